@@ -1 +1,5 @@
 # Include custom targets and environment variables here
+
+# Embed the git commit hash in the server binary, which is exposed by the info endpoint
+BUILD_HASH ?= $(shell git rev-parse HEAD 2> /dev/null)
+GO_BUILD_FLAGS += -ldflags '-X "github.com/kaakaa/mattermost-plugin-sharepost/server/plugin.buildHash=$(BUILD_HASH)"'
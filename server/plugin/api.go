@@ -1,11 +1,20 @@
 package plugin
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -14,29 +23,110 @@ import (
 )
 
 const (
-	toChannelKey      = "to_channel"
-	shareTypeKey      = "share_type"
-	additionalTextKey = "additional_text"
+	toChannelKey        = "to_channel"
+	shareTypeKey        = "share_type"
+	additionalTextKey   = "additional_text"
+	shareThreadKey      = "share_thread"
+	moveThreadKey       = "move_thread"
+	includeFilesKey     = "include_files"
+	toRootIDKey         = "to_root_id"
+	deleteSourceKey     = "delete_source"
+	renderModeKey       = "render_mode"
+	textPositionKey     = "additional_text_position"
+	postAsBotKey        = "post_as_bot"
+	detachRepliesKey    = "detach_replies"
+	confirmMentionKey   = "confirm_broadcast_mention"
+	includeReactionsKey = "include_reactions"
+	pinInDestinationKey = "pin_in_destination"
 
-	shareTypeShare = "share"
-	shareTypeMove  = "move"
+	shareTypeShare     = "share"
+	shareTypeMove      = "move"
+	shareTypeCopy      = "copy"
+	shareTypeDuplicate = "duplicate"
+	shareTypePermalink = "permalink"
 
-	postPropsKeyAdditionalText = "sharepost.additional_text"
+	// Render modes of shared posts
+	renderModePlain = "plain"
+	renderModeQuote = "quote"
+	renderModeCard  = "card"
+
+	// Positions of additional text relative to the shared/copied content
+	textPositionAbove = "above"
+	textPositionBelow = "below"
+
+	postPropsKeyAdditionalText   = "sharepost.additional_text"
+	postPropsKeyTextPosition     = "sharepost.additional_text_position"
+	postPropsKeyCopiedFrom       = "sharepost.copied_from"
+	postPropsKeyDuplicatedFrom   = "sharepost.duplicated_from"
+	postPropsKeyFilesHandled     = "sharepost.files_handled"
+	postPropsKeyMovedTo          = "sharepost.moved_to"
+	postPropsKeyOriginalCreateAt = "sharepost.original_create_at"
+	postPropsKeySharedFromPostID = "sharepost.shared_from_post_id"
+
+	// Provenance of moved posts
+	postPropsKeyMovedFromChannelID = "sharepost.moved_from_channel_id"
+	postPropsKeyMovedByUserID      = "sharepost.moved_by_user_id"
+	postPropsKeyMovedAt            = "sharepost.moved_at"
+	// postPropsKeyOriginalUserID is the author of the post moved as the bot, which is used to restore the author when undoing
+	postPropsKeyOriginalUserID = "sharepost.original_user_id"
+	// postPropsKeyContinuationOf is the moved post whose message is continued in the reply, because it's over the max post size
+	postPropsKeyContinuationOf = "sharepost.continuation_of"
+
+	// botAttributionPrefix starts the message of the post moved as the bot, followed by the username of the original author
+	botAttributionPrefix = "Originally by @"
+
+	// postPropsKeyChannelMentions is the prop where the server stores the channels mentioned by `~channel-name`
+	postPropsKeyChannelMentions = "channel_mentions"
+	// postPropsKeyDisableGroupHighlight is the prop disabling the highlight of group mentions.
+	// The server version the plugin is built with doesn't define it as model.POST_PROPS_GROUP_HIGHLIGHT_DISABLED yet.
+	postPropsKeyDisableGroupHighlight = "disable_group_highlight"
+
+	maxQuotedMessageLength = 500
+
+	// maxShareWorkers is the number of channels shared to concurrently when sharing to multiple channels
+	maxShareWorkers = 4
+
+	// createPostMaxAttempts is the number of attempts to create a post when the server fails transiently
+	createPostMaxAttempts = 3
 )
 
-var messageGenericError = toPtr("Something went wrong. Please try again later.")
+var errSiteURLNotSet = errors.New("siteURL is not set")
 
-type submitDialogHandler func(map[string]string, *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error)
+// createPostRetryBackoff is the wait before retrying to create a post, multiplied by the number of failed attempts
+var createPostRetryBackoff = 200 * time.Millisecond
+
+// shareTimeout is the deadline of sharing a post to all selected channels
+var shareTimeout = 30 * time.Second
+
+// submitDialogHandler handles the dialog submission. The posts created with the context are returned to API clients.
+type submitDialogHandler func(context.Context, map[string]string, *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error)
 
 // InitAPI initialize API of the plugin
 func (p *SharePostPlugin) InitAPI() *mux.Router {
+	p.shareRateLimiter = newRateLimiter(time.Minute)
+	p.metrics = newMetrics()
+	p.lookupCache = newLookupCache(lookupCacheTTL)
+
 	r := mux.NewRouter()
 	r.HandleFunc("/", p.handleInfo).Methods(http.MethodGet)
+	r.HandleFunc("/metrics", p.handleMetrics).Methods(http.MethodGet)
 
 	apiV1 := r.PathPrefix("/api/v1").Subrouter()
-	apiV1.Use(checkAuthenticity)
+	apiV1.Use(p.checkAuthenticity)
 	apiV1.HandleFunc("/share", p.handleSubmitDialogRequest(p.handleSharePost)).Methods(http.MethodPost)
-	// apiV1.HandleFunc("/move", p.handleSubmitDialogRequest(p.handleMovePost).Methods(http.MethodPost)
+	apiV1.HandleFunc("/move", p.handleSubmitDialogRequest(p.handleMovePost)).Methods(http.MethodPost)
+	apiV1.HandleFunc("/undo", p.handleUndoMove).Methods(http.MethodPost)
+	apiV1.HandleFunc("/view_original", p.handleViewOriginal).Methods(http.MethodPost)
+	apiV1.HandleFunc("/history", p.handleHistory).Methods(http.MethodGet)
+	apiV1.HandleFunc("/preview", p.handlePreview).Methods(http.MethodPost)
+	apiV1.HandleFunc("/channels", p.handleChannels).Methods(http.MethodGet)
+	apiV1.HandleFunc("/share_types", p.handleShareTypes).Methods(http.MethodGet)
+	apiV1.HandleFunc("/settings", p.handleSettings).Methods(http.MethodGet)
+	apiV1.HandleFunc("/healthz", p.handleHealth).Methods(http.MethodGet)
+	apiV1.HandleFunc("/config", p.handleConfig).Methods(http.MethodGet)
+	apiV1.HandleFunc("/collection", p.handleSetCollectionRoot).Methods(http.MethodPut)
+	apiV1.HandleFunc("/collection", p.handleClearCollectionRoot).Methods(http.MethodDelete)
+	apiV1.HandleFunc("/bulk-move", p.handleBulkMove).Methods(http.MethodPost)
 	return r
 }
 
@@ -46,17 +136,121 @@ func (p *SharePostPlugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r
 	p.router.ServeHTTP(w, r)
 }
 
-func (p *SharePostPlugin) handleInfo(w http.ResponseWriter, _ *http.Request) {
+// pluginInfo is the response of handleInfo for machine-readable requests
+type pluginInfo struct {
+	PluginID  string `json:"plugin_id"`
+	Version   string `json:"version"`
+	BuildHash string `json:"build_hash"`
+}
+
+func (p *SharePostPlugin) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(pluginInfo{
+			PluginID:  manifest.Id,
+			Version:   manifest.Version,
+			BuildHash: buildHash,
+		}); err != nil {
+			p.API.LogWarn("Failed to write plugin info", "error", err.Error())
+		}
+		return
+	}
 	_, _ = io.WriteString(w, fmt.Sprintf("Installed SharePostPlugin v%s", manifest.Version))
 }
 
-func checkAuthenticity(next http.Handler) http.Handler {
+// clientSettings is the part of the configuration the webapp needs to build the dialog
+type clientSettings struct {
+	EnableShare bool `json:"enable_share"`
+	EnableMove  bool `json:"enable_move"`
+	// HasDefaultChannel is true when the destination can be omitted in favor of the default one
+	HasDefaultChannel bool `json:"has_default_channel"`
+	// CSRFToken is sent as the state of dialogs, because dialog submissions don't have the X-Requested-With header
+	CSRFToken string `json:"csrf_token"`
+	// SkipMoveConfirmation is the "don't ask again" preference of the user, which is the default of the dialog element
+	SkipMoveConfirmation bool `json:"skip_move_confirmation"`
+}
+
+// handleSettings returns the settings for the webapp. With `post_id`, the overrides of the team of the post apply,
+// so that the dialog offers the same options as the submission accepts. The team is resolved on the server,
+// and `team_id` is used only for posts in DM/GM channels, which don't belong to any team.
+func (p *SharePostPlugin) handleSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	query := r.URL.Query()
+	config := p.getConfiguration()
+	if postID := resolvePostID(query.Get("post_id")); postID != "" {
+		post, appErr := p.API.GetPost(postID)
+		// Posts of channels the user can't read are reported as not found, not to reveal their existence
+		if appErr != nil || !p.canReadPost(userID, post) {
+			writeJSONError(w, http.StatusNotFound, errorCodeInvalidRequest, "post not found")
+			return
+		}
+		teamID, appErr := p.getPostTeamID(post, query.Get("team_id"))
+		if appErr != nil {
+			p.API.LogWarn("failed to get team of the post", "post_id", postID, "error", appErr.Error())
+			writeJSONError(w, http.StatusInternalServerError, errorCodeInternal, "failed to get settings")
+			return
+		}
+		config = config.forTeam(teamID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(clientSettings{
+		EnableShare:          config.EnableShare,
+		EnableMove:           config.EnableMove,
+		HasDefaultChannel:    config.DefaultShareChannel != "",
+		CSRFToken:            p.makeCSRFToken(userID),
+		SkipMoveConfirmation: p.skipsMoveConfirmation(userID),
+	}); err != nil {
+		p.API.LogWarn("Failed to write settings", "error", err.Error())
+	}
+}
+
+// Codes of error responses, which are stable for API clients unlike the messages
+const (
+	errorCodeUnauthorized    = "unauthorized"
+	errorCodeForbidden       = "forbidden"
+	errorCodeInvalidRequest  = "invalid_request"
+	errorCodeConflict        = "conflict"
+	errorCodeTooManyRequests = "too_many_requests"
+	errorCodeInternal        = "internal_error"
+)
+
+// errorResponse is the body of error responses of the API
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// writeJSONError writes the error response in JSON, so that API clients can handle errors by the code
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: message, Code: code})
+}
+
+// checkAuthenticity rejects requests not from logged-in users, and state-changing requests which might be forged by other sites.
+// See checkCSRF for how the requests are verified.
+func (p *SharePostPlugin) checkAuthenticity(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("Mattermost-User-ID") == "" {
-			http.Error(w, "not authorized", http.StatusUnauthorized)
+		userID := r.Header.Get("Mattermost-User-ID")
+		if userID == "" {
+			writeJSONError(w, http.StatusUnauthorized, errorCodeUnauthorized, "not authorized")
 			return
 		}
 
+		if r.Method != http.MethodGet && !csrfExemptPaths[r.URL.Path] {
+			// The token is in the body, so the body is restored for the handlers
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil || !p.checkCSRF(r, userID, readCSRFToken(body)) {
+				p.API.LogWarn("invalid CSRF token", "user_id", userID, "path", r.URL.Path)
+				p.metrics.observeError(metricErrorUnauthorized)
+				writeJSONError(w, http.StatusUnauthorized, errorCodeUnauthorized, "not authorized")
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -66,19 +260,53 @@ func (p *SharePostPlugin) handleSubmitDialogRequest(handler submitDialogHandler)
 		request := model.SubmitDialogRequestFromJson(r.Body)
 		if request == nil {
 			p.API.LogWarn("Failed to decode SubmitDialogRequest")
-			http.Error(w, "invalid request", http.StatusBadRequest)
+			p.metrics.observeError(metricErrorInvalidRequest)
+			writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid request")
 			return
 		}
 
 		if request.UserId != r.Header.Get("Mattermost-User-Id") {
 			p.API.LogWarn("invalid user")
-			http.Error(w, "not authorized", http.StatusUnauthorized)
+			p.metrics.observeError(metricErrorUnauthorized)
+			writeJSONError(w, http.StatusUnauthorized, errorCodeUnauthorized, "not authorized")
+			return
+		}
+
+		// Requests retried with the same idempotency key get the result of the first one without processing it again
+		idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			p.metrics.observeError(metricErrorInvalidRequest)
+			writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Idempotency-Key is too long")
+			return
+		}
+		if idempotencyKey != "" {
+			// The key is claimed before processing the request, so that a retry arriving in the meantime doesn't post again
+			if result := p.claimIdempotencyKey(request.UserId, r.URL.Path, idempotencyKey); result != nil {
+				if result.Pending {
+					writeJSONError(w, http.StatusConflict, errorCodeConflict, "the request with the same Idempotency-Key is in progress")
+					return
+				}
+				p.writeIdempotentResult(w, result)
+				return
+			}
+		}
+
+		// Replayed results don't create posts, so only the requests processed here are counted
+		if !p.allowShare(request.UserId) {
+			if idempotencyKey != "" {
+				p.releaseIdempotencyKey(request.UserId, r.URL.Path, idempotencyKey)
+			}
+			p.SendEphemeralPost(request.ChannelId, request.UserId, p.getLocalizer(request.UserId)("share.rate_limited"))
+			writeJSONError(w, http.StatusTooManyRequests, errorCodeTooManyRequests, "too many requests")
 			return
 		}
 
-		msg, response, err := handler(mux.Vars(r), request)
+		// The plugin waits for the operation even if the client disconnects, so the context isn't derived from the request
+		ctx, created := withCreatedPosts(context.Background())
+		msg, response, err := handler(ctx, mux.Vars(r), request)
 		if err != nil {
 			p.API.LogWarn("Failed to handle SubmitDialogRequest", "error", err.Error())
+			p.metrics.observeError(metricErrorSubmitDialog)
 		}
 
 		if msg != nil {
@@ -86,220 +314,2040 @@ func (p *SharePostPlugin) handleSubmitDialogRequest(handler submitDialogHandler)
 		}
 
 		if response != nil {
+			if idempotencyKey != "" {
+				p.releaseIdempotencyKey(request.UserId, r.URL.Path, idempotencyKey)
+			}
 			w.Header().Set("Content-Type", "application/json")
 			err = json.NewEncoder(w).Encode(response)
 			if err != nil {
 				p.API.LogWarn("Failed to write SubmitDialogRequest", "error", err.Error())
 				w.WriteHeader(http.StatusInternalServerError)
 			}
+			return
+		}
+
+		// The server ignores the fields unknown to dialog responses, so the created posts are only seen by API clients
+		var body bytes.Buffer
+		posts := created.list()
+		if len(posts) > 0 {
+			if err := json.NewEncoder(&body).Encode(submitDialogResult{Posts: posts}); err != nil {
+				p.API.LogWarn("Failed to encode created posts", "error", err.Error())
+			}
+		}
+		// Refusals with errors of the dialog elements aren't recorded, so that clients can correct them and retry with the same key.
+		// Failures aren't recorded either unless some posts were created, so that retrying a failed request processes it again.
+		if idempotencyKey != "" {
+			if (err == nil && msg == nil) || len(posts) > 0 {
+				p.saveIdempotentResult(request.UserId, r.URL.Path, idempotencyKey, body.Bytes())
+			} else {
+				p.releaseIdempotencyKey(request.UserId, r.URL.Path, idempotencyKey)
+			}
+		}
+		if body.Len() > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			if _, err := w.Write(body.Bytes()); err != nil {
+				p.API.LogWarn("Failed to write created posts", "error", err.Error())
+			}
 		}
 	}
 }
 
-func (p *SharePostPlugin) handleSharePost(vars map[string]string, request *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
-	toChannel, ok := request.Submission[toChannelKey].(string)
-	if !ok {
-		return messageGenericError, nil, errors.Errorf("failed to get toChannel key. Value is: %v", request.Submission[toChannelKey])
-	}
+func (p *SharePostPlugin) handleSharePost(ctx context.Context, vars map[string]string, request *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+	T := p.getLocalizer(request.UserId)
+	// Power users may pass the permalink instead of the post ID
+	request.CallbackId = resolvePostID(request.CallbackId)
+	// Share types are normalized, so that minor differences of clients like "Move" are accepted
 	shareType, ok := request.Submission[shareTypeKey].(string)
-	if !ok {
-		return messageGenericError, nil, errors.Errorf("failed to get shareType key. Value is: %v", request.Submission[shareTypeKey])
+	shareType = strings.ToLower(strings.TrimSpace(shareType))
+	if !ok || shareType == "" {
+		return nil, dialogFieldError(shareTypeKey, T("dialog.select_share_type")), nil
+	}
+	// The permalink is sent only to the user, so no destination channel is needed
+	if shareType == shareTypePermalink {
+		return p.sendPermalink(request)
+	}
+	// Missing values are shown as errors of the dialog elements, so that the user can correct them in the dialog
+	toChannels := parseChannelIDs(request.Submission[toChannelKey])
+	if len(toChannels) == 0 {
+		defaultChannel := p.getDefaultShareChannel()
+		if defaultChannel == "" {
+			return nil, dialogFieldError(toChannelKey, T("dialog.select_channel")), nil
+		}
+		toChannels = []string{defaultChannel}
+	}
+	for i, toChannel := range toChannels {
+		var response *model.SubmitDialogResponse
+		if toChannels[i], response = p.resolveChannelID(T, toChannel); response != nil {
+			return nil, response, nil
+		}
+	}
+	additionalText, response := p.parseAdditionalText(T, request.Submission)
+	if response != nil {
+		return nil, response, nil
+	}
+	// Shared posts can be required to have a note for accountability. Moving relocates the post rather than sharing it.
+	if additionalText == "" && shareType != shareTypeMove && p.getConfiguration().RequireAdditionalText {
+		return nil, dialogFieldError(additionalTextKey, T("dialog.additional_text_required")), nil
+	}
+	renderMode, response := parseRenderMode(T, request.Submission)
+	if response != nil {
+		return nil, response, nil
+	}
+	textPosition, response := parseTextPosition(T, request.Submission)
+	if response != nil {
+		return nil, response, nil
+	}
+	// Boolean options are optional, and they're false when the key is missing
+	shareThread, _ := request.Submission[shareThreadKey].(bool)
+	moveThread, _ := request.Submission[moveThreadKey].(bool)
+	includeFiles, _ := request.Submission[includeFilesKey].(bool)
+	includeReactions, _ := request.Submission[includeReactionsKey].(bool)
+	deleteSource, _ := request.Submission[deleteSourceKey].(bool)
+
+	if msg, err := p.checkSourceTeam(T, request.CallbackId, request.TeamId); msg != nil {
+		return msg, nil, err
+	}
+	// Destinations are checked before creating any post, so that sharing to multiple channels doesn't end halfway
+	for _, toChannel := range toChannels {
+		if msg, err := p.checkDestination(T, request.UserId, request.TeamId, toChannel); msg != nil {
+			return msg, nil, err
+		}
+	}
+
+	// Shared post can be a reply in an existing thread of the destination channel
+	toRootID := ""
+	if value, _ := request.Submission[toRootIDKey].(string); strings.TrimSpace(value) != "" {
+		if len(toChannels) > 1 {
+			return toPtr(T("share.root_single_channel")), nil, nil
+		}
+		var msg *string
+		var err error
+		if toRootID, msg, err = p.findRootPostInChannel(T, value, toChannels[0]); msg != nil {
+			return msg, nil, err
+		}
 	}
-	additionalText, ok := request.Submission[additionalTextKey].(string)
-	if ok {
-		additionalText = fmt.Sprintf("%s\n\n", additionalText)
+	// Without the selected thread, posts are shared as replies to the collection root of the user in each channel
+	rootIDFor := func(toChannel string) string {
+		if toRootID != "" {
+			return toRootID
+		}
+		return p.getCollectionRoot(request.UserId, toChannel)
 	}
 
+	ctx, cancel := p.newOperationContext(ctx)
+	defer cancel()
 	switch shareType {
 	case shareTypeShare:
-		return p.sharePost(request, toChannel, additionalText)
+		if msg := p.checkShareTypeEnabled(T, request.UserId, request.TeamId, shareTypeShare); msg != nil {
+			return msg, nil, nil
+		}
+		share := func(ctx context.Context, toChannel string) (*string, *model.SubmitDialogResponse, error) {
+			return p.sharePost(ctx, request, toChannel, rootIDFor(toChannel), additionalText, renderMode, textPosition, shareThread, includeFiles, includeReactions)
+		}
+		if !deleteSource {
+			return p.summarizeShares(T, toChannels, p.shareToChannels(ctx, T, toChannels, share))
+		}
+		// The permissions are checked before sharing, so that the post isn't shared without being deleted
+		if msg, err := p.checkDeleteSource(T, request.UserId, request.CallbackId); msg != nil {
+			return msg, nil, err
+		}
+		results := p.shareToChannels(ctx, T, toChannels, share)
+		msg, response, err := p.summarizeShares(T, toChannels, results)
+		for _, result := range results {
+			if result.failed() {
+				p.API.LogWarn("the original post is not deleted because sharing failed.", "post_id", request.CallbackId)
+				return msg, response, err
+			}
+		}
+		if appErr := p.API.DeletePost(request.CallbackId); appErr != nil {
+			p.API.LogError("failed to delete the original post", "post_id", request.CallbackId, "error", appErr.Error())
+			return toPtr(T("share.delete_source_failed")), nil, fmt.Errorf("failed to delete post %w", appErr)
+		}
+		return msg, response, err
 	case shareTypeMove:
-		return p.movePost(request, toChannel, additionalText)
+		if msg := p.checkShareTypeEnabled(T, request.UserId, request.TeamId, shareTypeMove); msg != nil {
+			return msg, nil, nil
+		}
+		if len(toChannels) > 1 {
+			return toPtr(T("move.multiple_channels")), nil, nil
+		}
+		// Moving deletes the original post, so the user is asked to confirm it
+		if response := p.confirmMove(T, request); response != nil {
+			return nil, response, nil
+		}
+		postAsBot, _ := request.Submission[postAsBotKey].(bool)
+		detachReplies, _ := request.Submission[detachRepliesKey].(bool)
+		pinInDestination, _ := request.Submission[pinInDestinationKey].(bool)
+		return p.movePost(ctx, request, toChannels[0], toRootID, additionalText, moveThread, postAsBot, detachReplies, pinInDestination)
+	case shareTypeCopy:
+		results := p.shareToChannels(ctx, T, toChannels, func(ctx context.Context, toChannel string) (*string, *model.SubmitDialogResponse, error) {
+			return p.copyPost(ctx, request, toChannel, rootIDFor(toChannel), additionalText, textPosition)
+		})
+		return p.summarizeShares(T, toChannels, results)
+	case shareTypeDuplicate:
+		results := p.shareToChannels(ctx, T, toChannels, func(ctx context.Context, toChannel string) (*string, *model.SubmitDialogResponse, error) {
+			return p.duplicatePost(ctx, request, toChannel, rootIDFor(toChannel), additionalText)
+		})
+		return p.summarizeShares(T, toChannels, results)
 	default:
-		return messageGenericError, nil, fmt.Errorf("invalid share_type %s", shareType)
+		return toPtr(T("error.generic")), nil, fmt.Errorf("invalid share_type %s", shareType)
 	}
 }
 
-func (p *SharePostPlugin) sharePost(request *model.SubmitDialogRequest, toChannel, additionalText string) (*string, *model.SubmitDialogResponse, error) {
-	postID := request.CallbackId
-	userID := request.UserId
-	channelID := request.ChannelId
-	channel, appErr := p.API.GetChannel(channelID)
+// shareResult is the result of sharing a post to a channel
+type shareResult struct {
+	msg      *string
+	response *model.SubmitDialogResponse
+	err      error
+}
+
+// failed reports whether sharing failed. Successful shares don't return the message, because the result is sent
+// as an ephemeral post.
+func (r *shareResult) failed() bool {
+	return r.msg != nil || r.err != nil
+}
+
+// reason returns the message for the user explaining why sharing failed
+func (r *shareResult) reason(T localizer) string {
+	if r.msg != nil {
+		return *r.msg
+	}
+	return T("error.generic")
+}
+
+// shareToChannels calls share function for each channel with a bounded number of goroutines, and returns the results
+// in the order of channels. A failure in one channel doesn't abort sharing to the others.
+// Channels not shared until shareTimeout are reported as failed, so that a hung channel doesn't hang the whole request.
+func (p *SharePostPlugin) shareToChannels(ctx context.Context, T localizer, toChannels []string, share func(ctx context.Context, toChannel string) (*string, *model.SubmitDialogResponse, error)) []*shareResult {
+	ctx, cancel := context.WithTimeout(ctx, shareTimeout)
+	defer cancel()
+
+	type indexedResult struct {
+		index  int
+		result *shareResult
+	}
+	jobs := make(chan int)
+	// Buffered for all channels, so that workers finishing after the deadline don't block
+	done := make(chan indexedResult, len(toChannels))
+
+	go func() {
+		defer close(jobs)
+		for i := range toChannels {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	workers := maxShareWorkers
+	if len(toChannels) < workers {
+		workers = len(toChannels)
+	}
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				msg, response, err := share(ctx, toChannels[i])
+				done <- indexedResult{index: i, result: &shareResult{msg: msg, response: response, err: err}}
+			}
+		}()
+	}
+
+	results := make([]*shareResult, len(toChannels))
+	for received := 0; received < len(toChannels); received++ {
+		select {
+		case r := <-done:
+			results[r.index] = r.result
+		case <-ctx.Done():
+			for i, result := range results {
+				if result == nil {
+					results[i] = &shareResult{msg: toPtr(T("error.generic")), err: fmt.Errorf("timed out sharing to channel %s", toChannels[i])}
+				}
+			}
+			return results
+		}
+	}
+	return results
+}
+
+// summarizeShares returns the result of sharing to a channel as it is, and the summary of results when sharing to
+// multiple channels. The summary lists the channels where sharing failed with the reasons, grouping the channels
+// failed for the same reason.
+func (p *SharePostPlugin) summarizeShares(T localizer, toChannels []string, results []*shareResult) (*string, *model.SubmitDialogResponse, error) {
+	if len(results) == 1 {
+		return results[0].msg, results[0].response, results[0].err
+	}
+
+	failed := 0
+	reasons := []string{}
+	channelsByReason := map[string][]string{}
+	for i, result := range results {
+		if result.err != nil {
+			p.API.LogWarn("failed to share post", "channel_id", toChannels[i], "error", result.err.Error())
+		}
+		if !result.failed() {
+			continue
+		}
+		failed++
+		name := toChannels[i]
+		if channel, appErr := p.getChannel(toChannels[i]); appErr == nil {
+			name = "~" + channel.Name
+		}
+		reason := result.reason(T)
+		if _, ok := channelsByReason[reason]; !ok {
+			reasons = append(reasons, reason)
+		}
+		channelsByReason[reason] = append(channelsByReason[reason], name)
+	}
+
+	lines := []string{T("share.summary", len(toChannels)-failed, len(toChannels), failed)}
+	for _, reason := range reasons {
+		lines = append(lines, T("share.summary_failure", strings.Join(channelsByReason[reason], ", "), reason))
+	}
+	return toPtr(strings.Join(lines, "\n")), nil, nil
+}
+
+// parseChannelIDs accepts a channel ID, comma-separated channel IDs or JSON array of channel IDs
+func parseChannelIDs(value interface{}) []string {
+	var ids []string
+	switch v := value.(type) {
+	case string:
+		if err := json.Unmarshal([]byte(v), &ids); err != nil {
+			ids = strings.Split(v, ",")
+		}
+	case []interface{}:
+		for _, id := range v {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+	}
+
+	ret := []string{}
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			ret = append(ret, id)
+		}
+	}
+	return ret
+}
+
+// resolveChannelID resolves the destination given as `team-name:channel-name` to the channel ID, for scripted submissions.
+// Values that look like IDs are used as they are, and other values without the team name are treated as IDs too,
+// so that they're reported as missing channels.
+func (p *SharePostPlugin) resolveChannelID(T localizer, value string) (string, *model.SubmitDialogResponse) {
+	if model.IsValidId(value) || !strings.Contains(value, ":") {
+		return value, nil
+	}
+	parts := strings.SplitN(value, ":", 2)
+	teamName, channelName := strings.TrimSpace(parts[0]), strings.TrimPrefix(strings.TrimSpace(parts[1]), "~")
+	if teamName == "" || channelName == "" {
+		return "", dialogFieldError(toChannelKey, T("dialog.channel_name_not_found", value))
+	}
+	channel, appErr := p.API.GetChannelByNameForTeamName(teamName, channelName, false)
+	if appErr != nil {
+		p.API.LogWarn("failed to get channel by name", "team_name", teamName, "channel_name", channelName, "error", appErr.Error())
+		return "", dialogFieldError(toChannelKey, T("dialog.channel_name_not_found", value))
+	}
+	return channel.Id, nil
+}
+
+func (p *SharePostPlugin) handleMovePost(ctx context.Context, vars map[string]string, request *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+	T := p.getLocalizer(request.UserId)
+	request.CallbackId = resolvePostID(request.CallbackId)
+	if msg, err := p.checkSourceTeam(T, request.CallbackId, request.TeamId); msg != nil {
+		return msg, nil, err
+	}
+	if msg := p.checkShareTypeEnabled(T, request.UserId, request.TeamId, shareTypeMove); msg != nil {
+		return msg, nil, nil
+	}
+	toChannel, ok := request.Submission[toChannelKey].(string)
+	if !ok || toChannel == "" {
+		return nil, dialogFieldError(toChannelKey, T("dialog.select_channel")), nil
+	}
+	toChannel, response := p.resolveChannelID(T, toChannel)
+	if response != nil {
+		return nil, response, nil
+	}
+	additionalText, response := p.parseAdditionalText(T, request.Submission)
+	if response != nil {
+		return nil, response, nil
+	}
+	moveThread, _ := request.Submission[moveThreadKey].(bool)
+	postAsBot, _ := request.Submission[postAsBotKey].(bool)
+	detachReplies, _ := request.Submission[detachRepliesKey].(bool)
+	pinInDestination, _ := request.Submission[pinInDestinationKey].(bool)
+
+	if msg, err := p.checkDestination(T, request.UserId, request.TeamId, toChannel); msg != nil {
+		return msg, nil, err
+	}
+	toRootID := ""
+	if value, _ := request.Submission[toRootIDKey].(string); strings.TrimSpace(value) != "" {
+		var msg *string
+		var err error
+		if toRootID, msg, err = p.findRootPostInChannel(T, value, toChannel); msg != nil {
+			return msg, nil, err
+		}
+	}
+	if response := p.confirmMove(T, request); response != nil {
+		return nil, response, nil
+	}
+	ctx, cancel := p.newOperationContext(ctx)
+	defer cancel()
+	return p.movePost(ctx, request, toChannel, toRootID, additionalText, moveThread, postAsBot, detachReplies, pinInDestination)
+}
+
+// checkShareTypeEnabled returns the message for the user if the share type is disabled by the configuration.
+// Sharing and moving can be disabled independently for each team, and copying is always enabled.
+func (p *SharePostPlugin) checkShareTypeEnabled(T localizer, userID, teamID, shareType string) *string {
+	config := p.getConfiguration().forTeam(teamID)
+	switch {
+	case shareType == shareTypeShare && !config.EnableShare:
+		p.API.LogWarn("sharing posts is disabled.", "user_id", userID)
+		return toPtr(T("share.disabled"))
+	case shareType == shareTypeMove && !config.EnableMove:
+		p.API.LogWarn("moving posts is disabled.", "user_id", userID)
+		return toPtr(T("move.disabled"))
+	}
+	return nil
+}
+
+// findRootPostInChannel finds the root post of the thread to reply to from the post ID or the permalink.
+// It returns the message for the user if the post is not in the channel.
+func (p *SharePostPlugin) findRootPostInChannel(T localizer, value, channelID string) (string, *string, error) {
+	postID := resolvePostID(value)
+	post, appErr := p.API.GetPost(postID)
 	if appErr != nil {
-		p.API.LogError("failed to get channel", "channel_id", channelID, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get channel %w", appErr)
+		p.API.LogWarn("failed to get post to reply to", "post_id", postID, "error", appErr.Error())
+		return "", toPtr(T("share.root_not_in_channel")), nil
+	}
+	if post.ChannelId != channelID {
+		p.API.LogWarn("post to reply to is not in the destination channel.", "post_id", postID, "channel_id", channelID)
+		return "", toPtr(T("share.root_not_in_channel")), nil
 	}
-	newChannel, appErr := p.API.GetChannel(toChannel)
+	// Replying to a reply means replying to its thread
+	if post.RootId != "" {
+		return post.RootId, nil, nil
+	}
+	return post.Id, nil, nil
+}
+
+// getDestinationChannel gets the channel to share/move the post to.
+// A missing channel is reported apart from server faults, because it's caused by a stale or malformed channel ID.
+func (p *SharePostPlugin) getDestinationChannel(T localizer, toChannel string) (*model.Channel, *string, error) {
+	channel, appErr := p.getChannel(toChannel)
 	if appErr != nil {
+		if appErr.StatusCode == http.StatusNotFound || appErr.StatusCode == http.StatusBadRequest {
+			p.API.LogWarn("destination channel is not found.", "channel_id", toChannel, "error", appErr.Error())
+			return nil, toPtr(T("share.channel_not_found")), nil
+		}
 		p.API.LogError("failed to get channel", "channel_id", toChannel, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get channel %w", appErr)
+		return nil, toPtr(T("error.generic")), fmt.Errorf("failed to get channel %w", appErr)
 	}
+	// Creating posts in archived channels fails with a generic error, so they're refused before creating any post
+	if channel.DeleteAt != 0 {
+		p.API.LogWarn("destination channel is archived.", "channel_id", toChannel)
+		return nil, toPtr(T("share.channel_archived")), nil
+	}
+	return channel, nil, nil
+}
 
-	teamID := request.TeamId
-	team, appErr := p.API.GetTeam(teamID)
+// getAccessibleTeam gets the team, and returns the message for the user when the team is deleted or the user can't access it.
+// Unexpected errors return the generic message with the error.
+func (p *SharePostPlugin) getAccessibleTeam(T localizer, teamID, notAccessibleID string) (*model.Team, *string, error) {
+	team, appErr := p.getTeam(teamID)
 	if appErr != nil {
+		if appErr.StatusCode == http.StatusNotFound || appErr.StatusCode == http.StatusForbidden {
+			p.API.LogWarn("team is not accessible.", "team_id", teamID, "error", appErr.Error())
+			return nil, toPtr(T(notAccessibleID)), nil
+		}
 		p.API.LogError("failed to get team", "team_id", teamID, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get team %w", appErr)
+		return nil, toPtr(T("error.generic")), fmt.Errorf("failed to get team %w", appErr)
+	}
+	if team.DeleteAt != 0 {
+		p.API.LogWarn("team is archived.", "team_id", teamID)
+		return nil, toPtr(T(notAccessibleID)), nil
+	}
+	return team, nil, nil
+}
+
+// getSourceTeam returns the team of the channel of the post, whose name is used in the permalinks to the post.
+// Posts in DM/GM channels don't belong to any team, so the team of the request is used for them.
+func (p *SharePostPlugin) getSourceTeam(T localizer, channel *model.Channel, currentTeamID string) (*model.Team, *string, error) {
+	teamID := channel.TeamId
+	if teamID == "" {
+		teamID = currentTeamID
+	}
+	return p.getAccessibleTeam(T, teamID, "share.team_not_accessible")
+}
+
+// checkDestination checks whether the channel is permitted as the destination by the configuration.
+// It returns the message for the user if not permitted.
+func (p *SharePostPlugin) checkDestination(T localizer, userID, teamID, toChannel string) (*string, error) {
+	channel, msg, err := p.getDestinationChannel(T, toChannel)
+	if msg != nil {
+		return msg, err
+	}
+	if !p.getConfiguration().forTeam(teamID).isDestinationAllowed(channel, teamID) {
+		p.API.LogWarn("destination channel is not permitted by the configuration.", "user_id", userID, "channel_id", toChannel)
+		return toPtr(T("share.destination_not_allowed")), nil
+	}
+	return nil, nil
+}
+
+func (p *SharePostPlugin) sharePost(ctx context.Context, request *model.SubmitDialogRequest, toChannel, toRootID, additionalText, renderMode, textPosition string, shareThread, includeFiles, includeReactions bool) (msg *string, response *model.SubmitDialogResponse, err error) {
+	defer func() { p.metrics.observeAction(shareTypeShare, msg, err) }()
+
+	postID := request.CallbackId
+	userID := request.UserId
+	T := p.getLocalizer(userID)
+	if _, err := p.getSiteURL(); err != nil {
+		p.API.LogError("Site URL is not configured")
+		return toPtr(T("error.site_url_not_set")), nil, err
+	}
+	newChannel, msg, err := p.getDestinationChannel(T, toChannel)
+	if msg != nil {
+		return msg, nil, err
+	}
+	if msg := p.checkPostToChannel(T, userID, toChannel); msg != nil {
+		p.API.LogWarn("user doesn't have permission to post in the channel.", p.shareLogFields(request.ChannelId, toChannel, "user_id", userID)...)
+		return msg, nil, nil
 	}
 
+	if msg, err := checkTimeout(T, ctx); msg != nil {
+		return msg, nil, err
+	}
 	postList, appErr := p.API.GetPostThread(postID)
+	if msg := p.checkPostDeleted(T, postID, nil, appErr); msg != nil {
+		return msg, nil, nil
+	}
 	if appErr != nil {
 		p.API.LogError("failed to get post list", "post_id", postID, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get post list %w", appErr)
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get post list %w", appErr)
 	}
 	p.API.LogDebug("ROOT: ", "post_id", postID)
 	postList.UniqueOrder()
 
+	original, ok := postList.Posts[postID]
+	if !ok {
+		p.API.LogError("failed to find post in the thread", "post_id", postID)
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to find post %s in the thread", postID)
+	}
+	if msg := p.checkPostDeleted(T, postID, original, nil); msg != nil {
+		return msg, nil, nil
+	}
+	if !isShareablePost(original) {
+		p.API.LogWarn("system message cannot be shared.", "post_id", postID, "type", original.Type)
+		return toPtr(T("share.system_message")), nil, nil
+	}
+	if !p.canReadPost(userID, original) {
+		p.API.LogWarn("user doesn't have permission to read the post.", "user_id", userID, "post_id", postID)
+		return toPtr(T("share.no_read_permission")), nil, nil
+	}
+	// The dialog may be opened from another channel than the post, e.g. with the permalink, so the channel of the post is used
+	channel, appErr := p.getChannel(original.ChannelId)
+	if appErr != nil {
+		p.API.LogError("failed to get channel", "channel_id", original.ChannelId, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get channel %w", appErr)
+	}
+	// DM/GM channels don't belong to any team, so the permalinks are made without team name
+	var team *model.Team
+	teamName := ""
+	if !newChannel.IsGroupOrDirect() {
+		if team, msg, err = p.getSourceTeam(T, channel, request.TeamId); msg != nil {
+			return msg, nil, err
+		}
+		teamName = team.Name
+	}
+	message, additionalText := p.buildShareMessage(postList, original, channel, team, additionalText, renderMode, shareThread, includeReactions)
+
 	newPost := &model.Post{
 		Type:      model.POST_DEFAULT,
 		UserId:    request.UserId,
 		ChannelId: toChannel,
-		Message:   fmt.Sprintf("> Shared from ~%s. ([original post](%s))", channel.Name, p.makePostLink(team.Name, postID)),
+		RootId:    toRootID,
+		Message:   message,
+	}
+	if includeFiles && len(original.FileIds) > 0 {
+		newFileIds, appErr := p.API.CopyFileInfos(userID, original.FileIds)
+		if appErr != nil {
+			p.API.LogWarn("failed to copy file ids", "error", appErr.Error())
+			return toPtr(T("error.generic")), nil, fmt.Errorf("failed to copy file ids %w", appErr)
+		}
+		newPost.FileIds = newFileIds
+	}
+	newPost.SetProps(model.StringInterface{
+		postPropsKeyAdditionalText:   additionalText,
+		postPropsKeyTextPosition:     textPosition,
+		postPropsKeyFilesHandled:     true,
+		postPropsKeySharedFromPostID: postID,
+	})
+	if renderMode == renderModeCard {
+		model.ParseSlackAttachment(newPost, []*model.SlackAttachment{p.makeShareCard(original, channel, teamName)})
 	}
-	newPost.SetProps(model.StringInterface{postPropsKeyAdditionalText: additionalText})
 
-	newPost, err := p.API.CreatePost(newPost)
-	if err != nil {
-		p.API.LogWarn("failed to create post", "error", err.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to create post %w", err)
+	if msg, err := checkTimeout(T, ctx); msg != nil {
+		return msg, nil, err
+	}
+	if !p.reserveShare(userID, postID, toChannel) {
+		p.API.LogWarn("the post is already shared to the channel recently.", "post_id", postID, "channel_id", toChannel)
+		return toPtr(T("share.duplicated", channelMention(T, newChannel))), nil, nil
+	}
+	newPost, appErr = p.createPostWithRetry(ctx, newPost)
+	if appErr != nil {
+		p.releaseShare(userID, postID, toChannel)
+		p.API.LogWarn("failed to create post", p.shareLogFields(channel.Id, toChannel, "post_id", postID, "error", appErr.Error())...)
+		if msg, err := checkTimeout(T, ctx); msg != nil {
+			return msg, nil, err
+		}
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to create post %w", appErr)
 	}
-	p.SendEphemeralPost(channelID, userID, fmt.Sprintf("[This post](%s) is shared to ~%s. [New post](%s).", p.makePostLink(team.Name, postID), newChannel.Name, p.makePostLink(team.Name, newPost.Id)))
+	p.recordAudit(&auditEntry{
+		Action:               auditActionShare,
+		UserID:               userID,
+		PostID:               postID,
+		NewPostID:            newPost.Id,
+		SourceChannelID:      original.ChannelId,
+		DestinationChannelID: toChannel,
+	})
+	addCreatedPost(ctx, newPost, p.makePostLink(teamName, newPost.Id))
+	p.SendEphemeralPost(request.ChannelId, userID, T("share.done", p.makePostLink(teamName, postID), channelMention(T, newChannel), p.makePostLink(teamName, newPost.Id))+
+		visibilityWarning(T, channel, newChannel))
 	return nil, nil, nil
 }
 
-func (p *SharePostPlugin) movePost(request *model.SubmitDialogRequest, toChannel, additionalText string) (*string, *model.SubmitDialogResponse, error) {
+func (p *SharePostPlugin) copyPost(ctx context.Context, request *model.SubmitDialogRequest, toChannel, toRootID, additionalText, textPosition string) (msg *string, response *model.SubmitDialogResponse, err error) {
+	defer func() { p.metrics.observeAction(shareTypeCopy, msg, err) }()
+
 	postID := request.CallbackId
 	userID := request.UserId
-	teamID := request.TeamId
-
-	postList, appErr := p.API.GetPostThread(postID)
-	if appErr != nil {
-		p.API.LogError("failed to get post list", "post_id", postID, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get post list %w", appErr)
+	T := p.getLocalizer(userID)
+	if _, err := p.getSiteURL(); err != nil {
+		p.API.LogError("Site URL is not configured")
+		return toPtr(T("error.site_url_not_set")), nil, err
 	}
+	newChannel, msg, err := p.getDestinationChannel(T, toChannel)
+	if msg != nil {
+		return msg, nil, err
+	}
+	channelID := request.ChannelId
+
 	oldPost, appErr := p.API.GetPost(postID)
+	if msg := p.checkPostDeleted(T, postID, oldPost, appErr); msg != nil {
+		return msg, nil, nil
+	}
 	if appErr != nil {
 		p.API.LogError("failed to get post", "post_id", postID, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get post %w", appErr)
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get post %w", appErr)
 	}
-
-	// Cannot move any child posts in thread to other channel
-	if len(postList.Posts) > 1 && oldPost.RootId != "" {
-		p.API.LogWarn("the post that has parent posts cannot be moved to other channel.", "post_id", postID)
-		return toPtr("the post that has parent posts cannot be moved to other channel."), nil, nil
+	if !isShareablePost(oldPost) {
+		p.API.LogWarn("system message cannot be shared.", "post_id", postID, "type", oldPost.Type)
+		return toPtr(T("share.system_message")), nil, nil
 	}
-	// Cannot move the post to same channel
-	if oldPost.ChannelId == toChannel {
-		p.API.LogWarn("cannot move the post to same channel.")
-		return toPtr("cannot move the post to same channel."), nil, nil
+	if !p.canReadPost(userID, oldPost) {
+		p.API.LogWarn("user doesn't have permission to read the post.", "user_id", userID, "post_id", postID)
+		return toPtr(T("share.no_read_permission")), nil, nil
 	}
-
-	newChannel, appErr := p.API.GetChannel(toChannel)
+	channel, appErr := p.getChannel(oldPost.ChannelId)
 	if appErr != nil {
-		p.API.LogError("failed to get channel", "channel_id", toChannel, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get channel %w", appErr)
+		p.API.LogError("failed to get channel", "channel_id", oldPost.ChannelId, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get channel %w", appErr)
 	}
-	team, appErr := p.API.GetTeam(teamID)
-	if appErr != nil {
-		p.API.LogError("failed to get team", "team_id", teamID, "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to get team %w", appErr)
+	if msg := p.checkPostToChannel(T, userID, toChannel); msg != nil {
+		p.API.LogWarn("user doesn't have permission to post in the channel.", p.shareLogFields(channel.Id, toChannel, "user_id", userID)...)
+		return msg, nil, nil
 	}
 
-	// Create new post object
-	newPost, err := p.clonePost(oldPost, userID)
-	if err != nil {
-		return messageGenericError, nil, fmt.Errorf("failed to clone post %w", err)
+	team, msg, err := p.getSourceTeam(T, channel, request.TeamId)
+	if msg != nil {
+		return msg, nil, err
 	}
-	newPost.ChannelId = toChannel
-	newPost.SetProps(model.StringInterface{postPropsKeyAdditionalText: additionalText})
 
-	movedPost, appErr := p.API.CreatePost(newPost)
-	if appErr != nil {
-		p.API.LogWarn("failed to create post", "error", appErr.Error())
-		return messageGenericError, nil, fmt.Errorf("failed to create post %w", appErr)
+	// Post having only attached files has empty message, so attribution line is placed without separator
+	message := p.getServerLocalizer()("copy.attribution", channel.Name, p.makePostLink(team.Name, postID))
+	if oldPost.Message != "" {
+		message = fmt.Sprintf("%s\n\n%s", oldPost.Message, message)
 	}
-	p.API.LogDebug("success to create new post", "original_post_id", postID, "moved_post_id", movedPost.Id)
-
-	// Move children in thread
-	createdPostIds := []string{movedPost.Id}
-	willDeletePostIds := []string{}
-	if len(postList.Posts) > 1 {
-		postList.UniqueOrder()
-		postList.SortByCreateAt()
-		for _, id := range postList.Order {
-			if id == postID {
-				continue
-			}
-			p.API.LogDebug("start to move children in thread.", "post_id", id)
-			oldChildPost, appErr := p.API.GetPost(id)
-			if appErr != nil {
-				p.API.LogWarn("failed to get post.", "post_id", oldChildPost.Id)
-				if appErr = p.rollback(createdPostIds); appErr != nil {
-					p.API.LogWarn("failed to rollback post thread")
-					return messageGenericError, nil, fmt.Errorf("failed to create post thread: %s", appErr.Error())
-				}
-			}
-			newChildPost, err := p.clonePost(oldChildPost, userID)
-			if err != nil {
-				if appErr = p.rollback(createdPostIds); appErr != nil {
-					p.API.LogWarn("failed to rollback post thread")
-					return messageGenericError, nil, fmt.Errorf("failed to create post thread: %w", err)
-				}
-			}
-			newChildPost.ChannelId = toChannel
-			newChildPost.RootId = movedPost.Id
-			newChildPost.ParentId = movedPost.Id
-			newCreatedChildPost, appErr := p.API.CreatePost(newChildPost)
-			if appErr != nil {
-				p.API.LogWarn("failed to update post.", "post_id", newChildPost.Id, "error", appErr.Error())
-				if appErr = p.rollback(createdPostIds); appErr != nil {
-					p.API.LogWarn("failed to rollback post thread")
-					return messageGenericError, nil, fmt.Errorf("failed to create post thread and rollback: %s", appErr.Error())
-				}
-				return messageGenericError, nil, fmt.Errorf("failed to create post thread: %s", appErr.Error())
-			}
-			createdPostIds = append(createdPostIds, newCreatedChildPost.Id)
-			willDeletePostIds = append(willDeletePostIds, id)
+	newPost := &model.Post{
+		Type:      model.POST_DEFAULT,
+		UserId:    userID,
+		ChannelId: toChannel,
+		RootId:    toRootID,
+		Message:   message,
+	}
+	if len(oldPost.FileIds) > 0 {
+		newFileIds, appErr := p.API.CopyFileInfos(userID, oldPost.FileIds)
+		if appErr != nil {
+			p.API.LogWarn("failed to copy file ids", "error", appErr.Error())
+			return toPtr(T("error.generic")), nil, fmt.Errorf("failed to copy file ids %w", appErr)
 		}
-		p.API.LogDebug("done moving thread.", "original_post_id", postID)
+		newPost.FileIds = newFileIds
 	}
+	props := p.carryOverProps(oldPost)
+	props[postPropsKeyAdditionalText] = additionalText
+	props[postPropsKeyTextPosition] = textPosition
+	props[postPropsKeyCopiedFrom] = postID
+	newPost.SetProps(props)
 
-	oldPost.Type = model.POST_SYSTEM_GENERIC
-	oldPost.Message = fmt.Sprintf("Ttis post is moved to ~%s. [New post](%s)", newChannel.Name, p.makePostLink(team.Name, movedPost.Id))
-	oldPost.FileIds = model.StringArray{}
-	model.ParseSlackAttachment(oldPost, []*model.SlackAttachment{})
-	oldPost.Metadata = &model.PostMetadata{}
-
-	if _, appErr := p.API.UpdatePost(oldPost); appErr != nil {
-		p.API.LogWarn("failed to update moved post.", "post_id", oldPost.Id, "error", appErr.Error())
+	if msg, err := checkTimeout(T, ctx); msg != nil {
+		return msg, nil, err
 	}
-
-	for _, id := range willDeletePostIds {
-		if appErr := p.API.DeletePost(id); appErr != nil {
-			p.API.LogWarn("failed to delete post", "post_id", id)
+	if !p.reserveShare(userID, postID, toChannel) {
+		p.API.LogWarn("the post is already shared to the channel recently.", "post_id", postID, "channel_id", toChannel)
+		return toPtr(T("share.duplicated", channelMention(T, newChannel))), nil, nil
+	}
+	newPost, appErr = p.createPostWithRetry(ctx, newPost)
+	if appErr != nil {
+		p.releaseShare(userID, postID, toChannel)
+		p.API.LogWarn("failed to create post", p.shareLogFields(channel.Id, toChannel, "post_id", postID, "error", appErr.Error())...)
+		if msg, err := checkTimeout(T, ctx); msg != nil {
+			return msg, nil, err
 		}
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to create post %w", appErr)
 	}
+	p.recordAudit(&auditEntry{
+		Action:               auditActionCopy,
+		UserID:               userID,
+		PostID:               postID,
+		NewPostID:            newPost.Id,
+		SourceChannelID:      oldPost.ChannelId,
+		DestinationChannelID: toChannel,
+	})
+	addCreatedPost(ctx, newPost, p.makePostLink(team.Name, newPost.Id))
+	p.SendEphemeralPost(channelID, userID, T("copy.done", p.makePostLink(team.Name, postID), channelMention(T, newChannel), p.makePostLink(team.Name, newPost.Id))+
+		visibilityWarning(T, channel, newChannel))
 	return nil, nil, nil
 }
 
-func (p *SharePostPlugin) clonePost(old *model.Post, userID string) (*model.Post, error) {
-	// Create new post object
-	newPost := old.Clone()
-	newPost.Id = ""
-	newPost.UpdateAt = time.Now().UnixNano()
+// duplicatePost recreates the post with its message, files and props in the channel in the same way as moving,
+// but the original post is kept in place.
+func (p *SharePostPlugin) duplicatePost(ctx context.Context, request *model.SubmitDialogRequest, toChannel, toRootID, additionalText string) (msg *string, response *model.SubmitDialogResponse, err error) {
+	defer func() { p.metrics.observeAction(shareTypeDuplicate, msg, err) }()
 
-	// Create the reference to attached files
-	newFileIds, appErr := p.API.CopyFileInfos(userID, old.FileIds)
-	if appErr != nil {
-		p.API.LogWarn("failed to copy file ids", "error", appErr.Error())
-		return nil, fmt.Errorf("failed to copy fie ids %w", appErr)
-	}
+	postID := request.CallbackId
+	userID := request.UserId
+	T := p.getLocalizer(userID)
+	if _, err := p.getSiteURL(); err != nil {
+		p.API.LogError("Site URL is not configured")
+		return toPtr(T("error.site_url_not_set")), nil, err
+	}
+	newChannel, msg, err := p.getDestinationChannel(T, toChannel)
+	if msg != nil {
+		return msg, nil, err
+	}
+
+	oldPost, appErr := p.API.GetPost(postID)
+	if msg := p.checkPostDeleted(T, postID, oldPost, appErr); msg != nil {
+		return msg, nil, nil
+	}
+	if appErr != nil {
+		p.API.LogError("failed to get post", "post_id", postID, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get post %w", appErr)
+	}
+	if !isShareablePost(oldPost) {
+		p.API.LogWarn("system message cannot be shared.", "post_id", postID, "type", oldPost.Type)
+		return toPtr(T("share.system_message")), nil, nil
+	}
+	if !p.canReadPost(userID, oldPost) {
+		p.API.LogWarn("user doesn't have permission to read the post.", "user_id", userID, "post_id", postID)
+		return toPtr(T("share.no_read_permission")), nil, nil
+	}
+	channel, appErr := p.getChannel(oldPost.ChannelId)
+	if appErr != nil {
+		p.API.LogError("failed to get channel", "channel_id", oldPost.ChannelId, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get channel %w", appErr)
+	}
+	if msg := p.checkPostToChannel(T, userID, toChannel); msg != nil {
+		p.API.LogWarn("user doesn't have permission to post in the channel.", p.shareLogFields(channel.Id, toChannel, "user_id", userID)...)
+		return msg, nil, nil
+	}
+
+	team, msg, err := p.getSourceTeam(T, channel, request.TeamId)
+	if msg != nil {
+		return msg, nil, err
+	}
+
+	newPost, err := p.clonePost(oldPost, userID)
+	if err != nil {
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to clone post %w", err)
+	}
+	// The duplicate is posted by the user, and it's not a reply in the thread of the original post
+	newPost.UserId = userID
+	newPost.ChannelId = toChannel
+	newPost.RootId = toRootID
+	newPost.ParentId = toRootID
+	newPost.IsPinned = false
+	// Props making up the content are kept like moving, but those of other integrations are carried over only if allowed
+	props := p.carryOverProps(newPost, duplicatedPropKeys...)
+	props[postPropsKeyAdditionalText] = additionalText
+	props[postPropsKeyDuplicatedFrom] = postID
+	newPost.SetProps(props)
+
+	if msg, err := checkTimeout(T, ctx); msg != nil {
+		return msg, nil, err
+	}
+	if !p.reserveShare(userID, postID, toChannel) {
+		p.API.LogWarn("the post is already shared to the channel recently.", "post_id", postID, "channel_id", toChannel)
+		return toPtr(T("share.duplicated", channelMention(T, newChannel))), nil, nil
+	}
+	newPost, appErr = p.createPostWithRetry(ctx, newPost)
+	if appErr != nil {
+		p.releaseShare(userID, postID, toChannel)
+		p.API.LogWarn("failed to create post", p.shareLogFields(channel.Id, toChannel, "post_id", postID, "error", appErr.Error())...)
+		if msg, err := checkTimeout(T, ctx); msg != nil {
+			return msg, nil, err
+		}
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to create post %w", appErr)
+	}
+	p.API.LogDebug("success to create duplicate post", "original_post_id", postID, "duplicate_post_id", newPost.Id)
+	p.copyReactions(postID, newPost.Id)
+	p.recordAudit(&auditEntry{
+		Action:               auditActionDuplicate,
+		UserID:               userID,
+		PostID:               postID,
+		NewPostID:            newPost.Id,
+		SourceChannelID:      oldPost.ChannelId,
+		DestinationChannelID: toChannel,
+	})
+	addCreatedPost(ctx, newPost, p.makePostLink(team.Name, newPost.Id))
+	p.SendEphemeralPost(request.ChannelId, userID, T("duplicate.done", p.makePostLink(team.Name, postID), channelMention(T, newChannel), p.makePostLink(team.Name, newPost.Id))+
+		visibilityWarning(T, channel, newChannel))
+	return nil, nil, nil
+}
+
+// When postAsBot is true, the moved posts are created by the bot with the username of the original author in the message,
+// so that the content doesn't appear under the name of the author in the destination channel.
+// When detachReplies is true, the root post is moved alone and its replies are left under a note linking to the moved post.
+func (p *SharePostPlugin) movePost(ctx context.Context, request *model.SubmitDialogRequest, toChannel, toRootID, additionalText string, moveThread, postAsBot, detachReplies, pinInDestination bool) (msg *string, response *model.SubmitDialogResponse, err error) {
+	defer func() { p.metrics.observeAction(shareTypeMove, msg, err) }()
+
+	postID := request.CallbackId
+	userID := request.UserId
+	T := p.getLocalizer(userID)
+	if _, err := p.getSiteURL(); err != nil {
+		p.API.LogError("Site URL is not configured")
+		return toPtr(T("error.site_url_not_set")), nil, err
+	}
+	newChannel, msg, err := p.getDestinationChannel(T, toChannel)
+	if msg != nil {
+		return msg, nil, err
+	}
+
+	if msg, err := checkTimeout(T, ctx); msg != nil {
+		return msg, nil, err
+	}
+	postList, appErr := p.API.GetPostThread(postID)
+	if msg := p.checkPostDeleted(T, postID, nil, appErr); msg != nil {
+		return msg, nil, nil
+	}
+	if appErr != nil {
+		p.API.LogError("failed to get post list", "post_id", postID, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get post list %w", appErr)
+	}
+	oldPost, appErr := p.API.GetPost(postID)
+	if msg := p.checkPostDeleted(T, postID, oldPost, appErr); msg != nil {
+		return msg, nil, nil
+	}
+	if appErr != nil {
+		p.API.LogError("failed to get post", "post_id", postID, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get post %w", appErr)
+	}
+	if !isShareablePost(oldPost) {
+		p.API.LogWarn("system message cannot be shared.", "post_id", postID, "type", oldPost.Type)
+		return toPtr(T("share.system_message")), nil, nil
+	}
+
+	// Moving whole thread starts from the root post even if a reply is selected, so the permissions are checked on the root
+	if moveThread && oldPost.RootId != "" {
+		oldPost, appErr = p.API.GetPost(oldPost.RootId)
+		if appErr != nil {
+			p.API.LogError("failed to get root post", "post_id", postID, "error", appErr.Error())
+			return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get root post %w", appErr)
+		}
+		postID = oldPost.Id
+	}
+
+	// Moving the post requires being a member of the original channel and having permission to delete the post
+	if _, appErr = p.API.GetChannelMember(oldPost.ChannelId, userID); appErr != nil {
+		p.API.LogWarn("user is not a member of the channel.", "user_id", userID, "channel_id", oldPost.ChannelId)
+		return toPtr(T("move.not_member")), nil, nil
+	}
+	if !p.canDeletePost(userID, oldPost) {
+		p.API.LogWarn("user doesn't have permission to delete the post.", "user_id", userID, "post_id", postID)
+		return toPtr(T("move.no_permission")), nil, nil
+	}
+	// Moving whole thread deletes every reply in it from the original channel
+	if moveThread {
+		for _, post := range postList.Posts {
+			if !p.canDeletePost(userID, post) {
+				p.API.LogWarn("user doesn't have permission to delete the post in the thread.", "user_id", userID, "post_id", post.Id)
+				return toPtr(T("move.no_permission")), nil, nil
+			}
+		}
+	}
+	// The moved posts are created by the user, so the user must be able to post in the destination as when sharing
+	if msg := p.checkPostToChannel(T, userID, toChannel); msg != nil {
+		p.API.LogWarn("user doesn't have permission to post in the channel.", p.shareLogFields(oldPost.ChannelId, toChannel, "user_id", userID)...)
+		return msg, nil, nil
+	}
+
+	// Cannot move the root post having replies to other channel unless moving whole thread or detaching the replies from it.
+	// A reply is moved alone unless moving whole thread.
+	detach := detachReplies && !moveThread && oldPost.RootId == "" && isInThread(postList, oldPost)
+	moveReply := !moveThread && oldPost.RootId != ""
+	if !moveThread && !detach && !moveReply && isInThread(postList, oldPost) {
+		p.API.LogWarn("the post in a thread cannot be moved to other channel without moving whole thread.", "post_id", postID)
+		return toPtr(T("move.thread_not_movable")), nil, nil
+	}
+	// Threads can't be nested, so only a single post can be moved into the thread of the destination channel
+	if toRootID != "" && (moveThread || detach) {
+		p.API.LogWarn("the thread cannot be moved into another thread.", "post_id", postID)
+		return toPtr(T("move.root_single_post")), nil, nil
+	}
+	// Cannot move the post to same channel
+	if oldPost.ChannelId == toChannel {
+		p.API.LogWarn("cannot move the post to same channel.")
+		return toPtr(T("move.same_channel")), nil, nil
+	}
+	// Posts past the retention period are pending deletion by the data retention job, so they're not moved.
+	// The root post is the oldest in the thread, so checking it covers the whole thread.
+	if msg := p.checkRetention(T, oldPost); msg != nil {
+		p.API.LogWarn("the post is older than the message retention period.", "post_id", postID)
+		return msg, nil, nil
+	}
+	// Posts moved a moment ago are refused, so that a post isn't relocated again by mistake and its provenance isn't lost
+	if msg := p.checkMoveCooldown(T, oldPost); msg != nil {
+		p.API.LogWarn("the post was moved recently.", "post_id", postID)
+		return msg, nil, nil
+	}
+	// Moving a thread creates and deletes every post in it, so large threads are refused not to overload the server
+	if limit := p.getConfiguration().MaxThreadMoveSize; moveThread && limit > 0 && len(postList.Posts) > limit {
+		p.API.LogWarn("the thread is too large to move.", "post_id", postID, "posts", len(postList.Posts), "limit", limit)
+		return toPtr(T("move.thread_too_large", len(postList.Posts), limit)), nil, nil
+	}
+	// The server lets users who can read the channel pin posts in it. It's checked before moving,
+	// so that the post isn't moved without being pinned.
+	if pinInDestination && !p.API.HasPermissionToChannel(userID, toChannel, model.PERMISSION_READ_CHANNEL) {
+		p.API.LogWarn("user doesn't have permission to pin posts in the channel.", "user_id", userID, "channel_id", toChannel)
+		return toPtr(T("move.no_pin_permission")), nil, nil
+	}
+
+	// The destination channel may belong to another team, so the permalinks are made with the team of the channel.
+	// DM/GM channels don't belong to any team, so the permalinks are made without team name
+	teamName := ""
+	if teamID := newChannel.TeamId; teamID != "" {
+		if !p.isTeamMember(userID, teamID) {
+			p.API.LogWarn("user is not a member of the team.", "user_id", userID, "team_id", teamID)
+			return toPtr(T("move.not_team_member")), nil, nil
+		}
+		team, msg, err := p.getAccessibleTeam(T, teamID, "move.team_not_accessible")
+		if msg != nil {
+			return msg, nil, err
+		}
+		teamName = team.Name
+	}
+
+	// Authors are looked up in advance, so that failing to get them doesn't abort the move halfway
+	authorNames := map[string]string{}
+	if postAsBot {
+		if authorNames, err = p.lookupAuthorUsernames(postList); err != nil {
+			p.API.LogError("failed to get authors of the posts", "post_id", postID, "error", err.Error())
+			return toPtr(T("error.generic")), nil, err
+		}
+	}
+
+	// Posts are not changed until here, so the move can be aborted safely
+	if msg, err := checkTimeout(T, ctx); msg != nil {
+		return msg, nil, err
+	}
+
+	// Create new post object
+	newPost, err := p.clonePost(oldPost, userID)
+	if err != nil {
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to clone post %w", err)
+	}
+	newPost.ChannelId = toChannel
+	// The root of a reply is in the original channel, so the moved post becomes a new root unless a thread to reply to is selected
+	newPost.RootId = toRootID
+	newPost.ParentId = toRootID
+	// Only the root post has the footer, and undoing the move restores the original message without it
+	newPost.Message = appendFooter(newPost.Message, p.getConfiguration().MovedPostFooter)
+	newPost.AddProp(postPropsKeyAdditionalText, additionalText)
+	// Only the root post is pinned, like pinning the post by hand in the destination channel
+	if pinInDestination {
+		newPost.IsPinned = true
+	}
+	movedAt := model.GetMillis()
+	stampMoveProvenance := func(post *model.Post) {
+		post.AddProp(postPropsKeyMovedFromChannelID, oldPost.ChannelId)
+		post.AddProp(postPropsKeyMovedByUserID, userID)
+		post.AddProp(postPropsKeyMovedAt, movedAt)
+		if postAsBot {
+			p.attributeToBot(post, authorNames[post.UserId])
+		}
+	}
+	stampMoveProvenance(newPost)
+	// The footer and the attribution may make the message longer than the server accepts, so the rest is posted in replies
+	// not to lose any content. Additional text is placed above the first part by MessageWillBePosted, so room is left for it.
+	reserved := utf8.RuneCountInString(placeAdditionalText(newPost.Message, additionalText, textPositionAbove)) - utf8.RuneCountInString(newPost.Message)
+	messages := splitMessageReserving(newPost.Message, p.getConfiguration().getMaxPostSize(), reserved)
+	newPost.Message = messages[0]
+
+	movedPost, appErr := p.createPostWithRetry(ctx, newPost)
+	if appErr != nil {
+		p.API.LogWarn("failed to create post", p.shareLogFields(oldPost.ChannelId, toChannel, "post_id", postID, "error", appErr.Error())...)
+		if msg, err := checkTimeout(T, ctx); msg != nil {
+			return msg, nil, err
+		}
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to create post %w", appErr)
+	}
+	p.API.LogDebug("success to create new post", "original_post_id", postID, "moved_post_id", movedPost.Id)
+	p.copyReactions(postID, movedPost.Id)
+
+	// Move children in thread, or leave them in the original channel under the note linking to the moved post
+	continuationIds, err := p.postContinuations(ctx, movedPost, messages[1:])
+	createdPostIds := append([]string{movedPost.Id}, continuationIds...)
+	if err != nil {
+		return p.rollbackThread(T, createdPostIds, err)
+	}
+	var willDeletePostIds, createdChildIds []string
+	// The thread of a reply moved alone is left in the original channel
+	if detach {
+		willDeletePostIds, createdChildIds, err = p.detachReplies(ctx, postList, oldPost, userID, teamName, movedPost.Id)
+	} else if !moveReply {
+		willDeletePostIds, createdChildIds, err = p.moveChildren(ctx, postList, postID, movedPost, userID, stampMoveProvenance)
+	}
+	createdPostIds = append(createdPostIds, createdChildIds...)
+	if err != nil {
+		return p.rollbackThread(T, createdPostIds, err)
+	}
+
+	// Delete the root post at last, because deleting root post also deletes the posts in the thread
+	deletedChildren := 0
+	for _, id := range willDeletePostIds {
+		if appErr := p.API.DeletePost(id); appErr != nil {
+			p.API.LogWarn("failed to delete post", "post_id", id, "error", appErr.Error())
+			continue
+		}
+		deletedChildren++
+	}
+	if appErr := p.API.DeletePost(postID); appErr != nil {
+		p.API.LogError("failed to delete original post", p.shareLogFields(oldPost.ChannelId, toChannel, "post_id", postID, "moved_post_id", movedPost.Id, "error", appErr.Error())...)
+		// Replies that have already been deleted would be lost by the rollback, so the moved thread is kept in that case
+		if deletedChildren > 0 {
+			return toPtr(T("error.generic")), nil, fmt.Errorf("failed to delete original post after deleting its replies %w", appErr)
+		}
+		// Moved posts are deleted so that the post doesn't remain in both channels
+		return p.rollbackThread(T, createdPostIds, fmt.Errorf("failed to delete original post %w", appErr))
+	}
+	p.API.LogDebug("success to delete original post", "post_id", postID)
+
+	// The note of detached replies already links to the moved post
+	redirectNoteID := ""
+	if p.getConfiguration().EnableRedirectNote && !detach {
+		note := &model.Post{
+			UserId:    p.botUserID,
+			ChannelId: oldPost.ChannelId,
+			Message:   p.getServerLocalizer()("move.redirect_note", newChannel.Name, p.makePostLink(teamName, movedPost.Id)),
+		}
+		note.AddProp(postPropsKeyMovedTo, movedPost.Id)
+		if createdNote, appErr := p.API.CreatePost(note); appErr != nil {
+			p.API.LogWarn("failed to create redirect note.", p.shareLogFields(oldPost.ChannelId, toChannel, "error", appErr.Error())...)
+		} else {
+			redirectNoteID = createdNote.Id
+		}
+	}
+	p.recordAudit(&auditEntry{
+		Action:               auditActionMove,
+		UserID:               userID,
+		PostID:               postID,
+		NewPostID:            movedPost.Id,
+		SourceChannelID:      oldPost.ChannelId,
+		DestinationChannelID: toChannel,
+	})
+	addCreatedPost(ctx, movedPost, p.makePostLink(teamName, movedPost.Id))
+	if p.getConfiguration().EnableMoveNotification && oldPost.UserId != userID {
+		p.notifyMovedPostAuthor(oldPost.UserId, userID, p.makePostLink(teamName, movedPost.Id))
+	}
+
+	// Undoing deletes the moved post only, so the replies detached from it can't be brought back to the thread
+	undoable := !detach && p.saveUndoRecord(&undoRecord{
+		UserID:            userID,
+		OriginalChannelID: oldPost.ChannelId,
+		OriginalRootID:    oldPost.RootId,
+		OriginalMessage:   oldPost.Message,
+		UnpinOnUndo:       pinInDestination && !oldPost.IsPinned,
+		MovedPostID:       movedPost.Id,
+		RedirectNoteID:    redirectNoteID,
+	})
+	p.sendMoveConfirmation(oldPost.ChannelId, userID, T("move.done", newChannel.Name, p.makePostLink(teamName, movedPost.Id)), movedPost.Id, undoable)
+	return nil, nil, nil
+}
+
+// postContinuations posts the rest of the message of the moved post as its replies, placed right after it in the thread.
+// It returns the IDs of the created posts.
+func (p *SharePostPlugin) postContinuations(ctx context.Context, movedPost *model.Post, messages []string) ([]string, error) {
+	createdIds := []string{}
+	// The moved post may be a reply in the thread of the destination channel
+	rootID := movedPost.RootId
+	if rootID == "" {
+		rootID = movedPost.Id
+	}
+	for i, message := range messages {
+		post := &model.Post{
+			UserId:    movedPost.UserId,
+			ChannelId: movedPost.ChannelId,
+			RootId:    rootID,
+			ParentId:  rootID,
+			Message:   message,
+			CreateAt:  movedPost.CreateAt + int64(i+1),
+		}
+		for _, key := range []string{postPropsKeyMovedFromChannelID, postPropsKeyMovedByUserID, postPropsKeyMovedAt} {
+			post.AddProp(key, movedPost.GetProp(key))
+		}
+		post.AddProp(postPropsKeyContinuationOf, movedPost.Id)
+		created, appErr := p.createPostWithRetry(ctx, post)
+		if appErr != nil {
+			p.API.LogWarn("failed to create continuation of moved post", "post_id", movedPost.Id, "error", appErr.Error())
+			return createdIds, fmt.Errorf("failed to create continuation of moved post %w", appErr)
+		}
+		createdIds = append(createdIds, created.Id)
+	}
+	return createdIds, nil
+}
+
+// splitMessage splits the message into parts of at most limit characters, preferring line breaks and then spaces
+// as the boundaries so that words are kept. Joining the parts gives back the message.
+func splitMessage(message string, limit int) []string {
+	runes := []rune(message)
+	parts := []string{}
+	for len(runes) > limit {
+		cut := limit
+		// Boundaries in the first half are ignored, so that the parts don't get too short
+		if i := lastIndexOfRune(runes[limit/2:limit], '\n'); i >= 0 {
+			cut = limit/2 + i + 1
+		} else if i := lastIndexOfRune(runes[limit/2:limit], ' '); i >= 0 {
+			cut = limit/2 + i + 1
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return append(parts, string(runes))
+}
+
+// splitMessageReserving splits the message like splitMessage, leaving room for reserved characters in the first part.
+// At least one character is kept in the first part even if the reserved characters exceed the limit.
+func splitMessageReserving(message string, limit, reserved int) []string {
+	first := limit - reserved
+	if first < 1 {
+		first = 1
+	}
+	parts := splitMessage(message, first)
+	if len(parts) == 1 {
+		return parts
+	}
+	return append(parts[:1], splitMessage(strings.Join(parts[1:], ""), limit)...)
+}
+
+func lastIndexOfRune(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveChildren recreates the replies in the thread under the new root post in the channel of the new root post.
+// prepare is called with each reply before creating it.
+// It returns the IDs of the original replies, which should be deleted after moving, and the IDs of the created posts.
+func (p *SharePostPlugin) moveChildren(ctx context.Context, postList *model.PostList, rootID string, newRoot *model.Post, userID string, prepare func(*model.Post)) ([]string, []string, error) {
+	movedIds := []string{}
+	createdIds := []string{}
+	if len(postList.Posts) <= 1 {
+		return movedIds, createdIds, nil
+	}
+
+	postList.UniqueOrder()
+	postList.SortByCreateAt()
+	for _, id := range postList.Order {
+		if id == rootID {
+			continue
+		}
+		// Posts created so far are rolled back by the caller
+		if err := ctx.Err(); err != nil {
+			return movedIds, createdIds, fmt.Errorf("moving thread is interrupted: %w", err)
+		}
+		p.API.LogDebug("start to move children in thread.", "post_id", id)
+		oldChildPost, appErr := p.API.GetPost(id)
+		if appErr != nil {
+			p.API.LogWarn("failed to get post.", "post_id", id, "error", appErr.Error())
+			return movedIds, createdIds, fmt.Errorf("failed to get post in thread: %w", appErr)
+		}
+		newChildPost, err := p.clonePost(oldChildPost, userID)
+		if err != nil {
+			return movedIds, createdIds, fmt.Errorf("failed to clone post in thread: %w", err)
+		}
+		newChildPost.ChannelId = newRoot.ChannelId
+		newChildPost.RootId = newRoot.Id
+		newChildPost.ParentId = newRoot.Id
+		prepare(newChildPost)
+		newCreatedChildPost, appErr := p.createPostWithRetry(ctx, newChildPost)
+		if appErr != nil {
+			p.API.LogWarn("failed to create post.", "post_id", id, "error", appErr.Error())
+			return movedIds, createdIds, fmt.Errorf("failed to create post thread: %w", appErr)
+		}
+		p.copyReactions(id, newCreatedChildPost.Id)
+		createdIds = append(createdIds, newCreatedChildPost.Id)
+		movedIds = append(movedIds, id)
+	}
+	p.API.LogDebug("done moving thread.", "original_post_id", rootID)
+	return movedIds, createdIds, nil
+}
+
+// detachReplies reparents the replies of the root post to a note posted by the bot in the original channel, which links to the
+// moved root post. Replies can't be moved to another root by updating them, so they're recreated under the note.
+// It returns the IDs of the original replies, which should be deleted after moving, and the IDs of the created posts.
+func (p *SharePostPlugin) detachReplies(ctx context.Context, postList *model.PostList, root *model.Post, userID, teamName, movedPostID string) ([]string, []string, error) {
+	note := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: root.ChannelId,
+		Message:   p.getServerLocalizer()("move.parent_moved", p.makePostLink(teamName, movedPostID)),
+		// The note takes the place of the root post in the channel
+		CreateAt: root.CreateAt,
+	}
+	note.AddProp(postPropsKeyMovedTo, movedPostID)
+	createdNote, appErr := p.createPostWithRetry(ctx, note)
+	if appErr != nil {
+		p.API.LogWarn("failed to create note for detached replies.", "post_id", root.Id, "error", appErr.Error())
+		return []string{}, []string{}, fmt.Errorf("failed to create note for detached replies: %w", appErr)
+	}
+	movedIds, createdIds, err := p.moveChildren(ctx, postList, root.Id, createdNote, userID, func(*model.Post) {})
+	return movedIds, append([]string{createdNote.Id}, createdIds...), err
+}
+
+// lookupAuthorUsernames returns the usernames of the authors of the posts in the list by their user IDs
+func (p *SharePostPlugin) lookupAuthorUsernames(postList *model.PostList) (map[string]string, error) {
+	names := map[string]string{}
+	for _, post := range postList.Posts {
+		if _, ok := names[post.UserId]; ok {
+			continue
+		}
+		user, appErr := p.API.GetUser(post.UserId)
+		if appErr != nil {
+			return nil, fmt.Errorf("failed to get author of the post %w", appErr)
+		}
+		names[post.UserId] = user.Username
+	}
+	return names, nil
+}
+
+// attributeToBot makes the post created by the bot, and prefixes the message with the username of the original author.
+// The original author is kept in the props to restore it when undoing the move.
+func (p *SharePostPlugin) attributeToBot(post *model.Post, authorName string) {
+	post.AddProp(postPropsKeyOriginalUserID, post.UserId)
+	post.UserId = p.botUserID
+	post.Message = strings.TrimSuffix(botAttributionPrefix+authorName+"\n\n"+post.Message, "\n\n")
+}
+
+// removeBotAttribution restores the author and the message of the post moved as the bot
+func removeBotAttribution(post *model.Post) {
+	originalUserID, ok := post.GetProp(postPropsKeyOriginalUserID).(string)
+	if !ok {
+		return
+	}
+	post.UserId = originalUserID
+	post.DelProp(postPropsKeyOriginalUserID)
+	if !strings.HasPrefix(post.Message, botAttributionPrefix) {
+		return
+	}
+	if i := strings.Index(post.Message, "\n\n"); i >= 0 {
+		post.Message = post.Message[i+2:]
+	} else {
+		post.Message = ""
+	}
+}
+
+// notifyMovedPostAuthor sends a direct message from the bot to the author of the moved post.
+// Failures are only logged, because the post has already been moved.
+func (p *SharePostPlugin) notifyMovedPostAuthor(authorID, moverID, link string) {
+	mover, appErr := p.API.GetUser(moverID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get user who moved the post.", "user_id", moverID, "error", appErr.Error())
+		return
+	}
+	channel, appErr := p.API.GetDirectChannel(p.botUserID, authorID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get direct channel with the author.", "user_id", authorID, "error", appErr.Error())
+		return
+	}
+	// The message is sent to the author, so it's in the locale of the author rather than the user who moved the post
+	dm := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channel.Id,
+		Message:   p.getLocalizer(authorID)("move.author_notification", link, mover.Username),
+	}
+	if _, appErr := p.API.CreatePost(dm); appErr != nil {
+		p.API.LogWarn("failed to notify the author of the moved post.", "user_id", authorID, "error", appErr.Error())
+	}
+}
+
+// getReactionSummary returns the summary of the reactions on the post, or an empty string if it has no reactions.
+// Failures only omit the summary, because it's an optional enrichment of the shared post.
+func (p *SharePostPlugin) getReactionSummary(postID string) string {
+	reactions, appErr := p.API.GetReactions(postID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get reactions", "post_id", postID, "error", appErr.Error())
+		return ""
+	}
+	return summarizeReactions(reactions)
+}
+
+// summarizeReactions aggregates the reactions by emoji like `:+1: 5 :heart: 3`.
+// Emojis are sorted by the count, and those with the same count keep the order in which they were first reacted.
+func summarizeReactions(reactions []*model.Reaction) string {
+	counts := map[string]int{}
+	emojis := []string{}
+	for _, reaction := range reactions {
+		if counts[reaction.EmojiName] == 0 {
+			emojis = append(emojis, reaction.EmojiName)
+		}
+		counts[reaction.EmojiName]++
+	}
+	sort.SliceStable(emojis, func(i, j int) bool { return counts[emojis[i]] > counts[emojis[j]] })
+
+	summary := make([]string, 0, len(emojis))
+	for _, emoji := range emojis {
+		summary = append(summary, fmt.Sprintf(":%s: %d", emoji, counts[emoji]))
+	}
+	return strings.Join(summary, " ")
+}
+
+// copyReactions adds the reactions on the original post to the moved post
+func (p *SharePostPlugin) copyReactions(fromPostID, toPostID string) {
+	reactions, appErr := p.API.GetReactions(fromPostID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get reactions", "post_id", fromPostID, "error", appErr.Error())
+		return
+	}
+	for _, reaction := range reactions {
+		newReaction := &model.Reaction{
+			UserId:    reaction.UserId,
+			PostId:    toPostID,
+			EmojiName: reaction.EmojiName,
+		}
+		if _, appErr := p.API.AddReaction(newReaction); appErr != nil {
+			p.API.LogWarn("failed to add reaction", "post_id", toPostID, "emoji_name", reaction.EmojiName, "error", appErr.Error())
+		}
+	}
+}
+
+// checkPostToChannel returns the message for the user if the user isn't a member of the channel or can't create posts in it.
+// HasPermissionToChannel respects the channel moderation, so members lacking the permission are told that posting
+// is restricted in the channel.
+func (p *SharePostPlugin) checkPostToChannel(T localizer, userID, channelID string) *string {
+	if _, appErr := p.API.GetChannelMember(channelID, userID); appErr != nil {
+		if msg := p.joinPublicChannel(T, userID, channelID); msg != nil {
+			return msg
+		}
+	}
+	if !p.API.HasPermissionToChannel(userID, channelID, model.PERMISSION_CREATE_POST) {
+		return toPtr(T("share.posting_restricted"))
+	}
+	return nil
+}
+
+// joinPublicChannel adds the user to the public channel when joining public channels on sharing is enabled.
+// It returns the message for the user if the channel isn't public or the user isn't allowed to join it.
+// Users outside the team of the channel join the team first when joining open teams is also enabled.
+func (p *SharePostPlugin) joinPublicChannel(T localizer, userID, channelID string) *string {
+	config := p.getConfiguration()
+	if !config.AutoJoinPublicChannels {
+		return toPtr(T("share.no_permission"))
+	}
+	channel, appErr := p.getChannel(channelID)
+	if appErr != nil || channel.Type != model.CHANNEL_OPEN {
+		return toPtr(T("share.no_permission"))
+	}
+	if !p.API.HasPermissionToTeam(userID, channel.TeamId, model.PERMISSION_JOIN_PUBLIC_CHANNELS) {
+		// Users have no permissions in teams they're not in, so they may be allowed after joining the team
+		if !config.AutoJoinOpenTeams || p.isTeamMember(userID, channel.TeamId) {
+			p.API.LogWarn("user doesn't have permission to join the channel.", "user_id", userID, "channel_id", channelID)
+			return toPtr(T("share.no_permission"))
+		}
+		if msg := p.joinOpenTeam(T, userID, channel.TeamId); msg != nil {
+			return msg
+		}
+		if !p.API.HasPermissionToTeam(userID, channel.TeamId, model.PERMISSION_JOIN_PUBLIC_CHANNELS) {
+			p.API.LogWarn("user doesn't have permission to join the channel.", "user_id", userID, "channel_id", channelID)
+			return toPtr(T("share.no_permission"))
+		}
+	}
+	if _, appErr := p.API.AddChannelMember(channelID, userID); appErr != nil {
+		p.API.LogWarn("failed to add user to the channel.", "user_id", userID, "channel_id", channelID, "error", appErr.Error())
+		return toPtr(T("share.no_permission"))
+	}
+	return nil
+}
+
+// joinOpenTeam adds the user to the team if the team is open and the user is allowed to join open teams.
+// It returns the message for the user otherwise. Invite-only teams are told apart, because only team admins can add the user.
+func (p *SharePostPlugin) joinOpenTeam(T localizer, userID, teamID string) *string {
+	team, appErr := p.getTeam(teamID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get team to join.", "team_id", teamID, "error", appErr.Error())
+		return toPtr(T("share.no_permission"))
+	}
+	if !team.AllowOpenInvite {
+		p.API.LogWarn("team of the channel is invite-only.", "user_id", userID, "team_id", teamID)
+		return toPtr(T("share.team_invite_only", team.DisplayName))
+	}
+	if !p.API.HasPermissionTo(userID, model.PERMISSION_JOIN_PUBLIC_TEAMS) {
+		p.API.LogWarn("user doesn't have permission to join open teams.", "user_id", userID, "team_id", teamID)
+		return toPtr(T("share.no_permission"))
+	}
+	if _, appErr := p.API.CreateTeamMember(teamID, userID); appErr != nil {
+		p.API.LogWarn("failed to add user to the team.", "user_id", userID, "team_id", teamID, "error", appErr.Error())
+		return toPtr(T("share.no_permission"))
+	}
+	return nil
+}
+
+// isTeamMember checks whether the user is in the team. Members who left the team remain with DeleteAt set.
+func (p *SharePostPlugin) isTeamMember(userID, teamID string) bool {
+	member, appErr := p.API.GetTeamMember(teamID, userID)
+	return appErr == nil && member.DeleteAt == 0
+}
+
+// isInThread checks whether the post is a reply or the root post having replies.
+// The post list may contain posts which are not in the thread of the post, so the relationships are checked by RootId.
+func isInThread(postList *model.PostList, post *model.Post) bool {
+	if post.RootId != "" {
+		return true
+	}
+	for _, p := range postList.Posts {
+		if p.RootId == post.Id {
+			return true
+		}
+	}
+	return false
+}
+
+// sendPermalink sends the permalink of the post to the user as an ephemeral post, without posting it anywhere.
+// The permalink is made with the team of the channel of the post, and without team name for DM/GM channels.
+func (p *SharePostPlugin) sendPermalink(request *model.SubmitDialogRequest) (msg *string, response *model.SubmitDialogResponse, err error) {
+	defer func() { p.metrics.observeAction(shareTypePermalink, msg, err) }()
+
+	postID := request.CallbackId
+	userID := request.UserId
+	T := p.getLocalizer(userID)
+	if _, err := p.getSiteURL(); err != nil {
+		p.API.LogError("Site URL is not configured")
+		return toPtr(T("error.site_url_not_set")), nil, err
+	}
+	post, appErr := p.API.GetPost(postID)
+	if msg := p.checkPostDeleted(T, postID, post, appErr); msg != nil {
+		return msg, nil, nil
+	}
+	if appErr != nil {
+		p.API.LogError("failed to get post", "post_id", postID, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get post %w", appErr)
+	}
+	if !p.canReadPost(userID, post) {
+		p.API.LogWarn("user doesn't have permission to read the post.", "user_id", userID, "post_id", postID)
+		return toPtr(T("share.no_read_permission")), nil, nil
+	}
+	channel, appErr := p.getChannel(post.ChannelId)
+	if appErr != nil {
+		p.API.LogError("failed to get channel", "channel_id", post.ChannelId, "error", appErr.Error())
+		return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get channel %w", appErr)
+	}
+	teamName := ""
+	if channel.TeamId != "" {
+		team, appErr := p.getTeam(channel.TeamId)
+		if appErr != nil {
+			p.API.LogError("failed to get team", "team_id", channel.TeamId, "error", appErr.Error())
+			return toPtr(T("error.generic")), nil, fmt.Errorf("failed to get team %w", appErr)
+		}
+		teamName = team.Name
+	}
+
+	permalink := p.makePostLink(teamName, postID)
+	p.SendEphemeralPost(request.ChannelId, userID, T("permalink.done", permalink, permalink))
+	return nil, nil, nil
+}
+
+// isShareablePost checks whether the post is a normal post. System messages (including ephemeral posts,
+// whose type is `system_ephemeral`) are generated by the server, so sharing/moving them makes no sense.
+func isShareablePost(post *model.Post) bool {
+	return !post.IsSystemMessage() && post.Type != model.POST_EPHEMERAL
+}
+
+// checkPostDeleted returns the message for the user if the post has been deleted, e.g. after the dialog was opened.
+// The post is deleted if getting it fails with 404 or it's soft-deleted. Other errors are left to the caller.
+func (p *SharePostPlugin) checkPostDeleted(T localizer, postID string, post *model.Post, appErr *model.AppError) *string {
+	if (appErr != nil && appErr.StatusCode == http.StatusNotFound) || (appErr == nil && post != nil && post.DeleteAt != 0) {
+		p.API.LogWarn("the post has already been deleted.", "post_id", postID)
+		return toPtr(T("share.post_deleted"))
+	}
+	return nil
+}
+
+// checkSourceTeam checks that the post is in the team of the request, because the overrides of the configuration
+// are applied by the team ID sent by the client. Otherwise, changing the team ID would apply the overrides of another team.
+// Posts in DM/GM channels don't belong to any team, so they can be shared from any team.
+func (p *SharePostPlugin) checkSourceTeam(T localizer, postID, teamID string) (*string, error) {
+	post, appErr := p.API.GetPost(postID)
+	if msg := p.checkPostDeleted(T, postID, post, appErr); msg != nil {
+		return msg, nil
+	}
+	if appErr != nil {
+		p.API.LogError("failed to get post", "post_id", postID, "error", appErr.Error())
+		return toPtr(T("error.generic")), fmt.Errorf("failed to get post %w", appErr)
+	}
+	channel, appErr := p.getChannel(post.ChannelId)
+	if appErr != nil {
+		p.API.LogError("failed to get channel", "channel_id", post.ChannelId, "error", appErr.Error())
+		return toPtr(T("error.generic")), fmt.Errorf("failed to get channel %w", appErr)
+	}
+	if channel.TeamId != "" && channel.TeamId != teamID {
+		p.API.LogWarn("the post isn't in the team of the request.", "post_id", postID, "team_id", teamID)
+		return toPtr(T("share.team_mismatch")), nil
+	}
+	return nil, nil
+}
+
+// getPostTeamID returns the team of the channel of the post, whose overrides of the configuration apply to the post.
+// Posts in DM/GM channels don't belong to any team, so currentTeamID, the team where the user is, is returned for them
+// as checkSourceTeam accepts any team for them.
+func (p *SharePostPlugin) getPostTeamID(post *model.Post, currentTeamID string) (string, *model.AppError) {
+	channel, appErr := p.getChannel(post.ChannelId)
+	if appErr != nil {
+		return "", appErr
+	}
+	if channel.TeamId == "" {
+		return currentTeamID, nil
+	}
+	return channel.TeamId, nil
+}
+
+// checkDeleteSource checks whether the user can delete the original post after sharing it.
+// It requires the same permissions as moving, and posts in a thread are refused because deleting the root post deletes the whole thread.
+func (p *SharePostPlugin) checkDeleteSource(T localizer, userID, postID string) (*string, error) {
+	postList, appErr := p.API.GetPostThread(postID)
+	if appErr != nil {
+		p.API.LogError("failed to get post list", "post_id", postID, "error", appErr.Error())
+		return toPtr(T("error.generic")), fmt.Errorf("failed to get post list %w", appErr)
+	}
+	post, ok := postList.Posts[postID]
+	if !ok {
+		p.API.LogError("failed to find post in the thread", "post_id", postID)
+		return toPtr(T("error.generic")), fmt.Errorf("failed to find post %s in the thread", postID)
+	}
+	if _, appErr = p.API.GetChannelMember(post.ChannelId, userID); appErr != nil {
+		p.API.LogWarn("user is not a member of the channel.", "user_id", userID, "channel_id", post.ChannelId)
+		return toPtr(T("move.not_member")), nil
+	}
+	if !p.canDeletePost(userID, post) {
+		p.API.LogWarn("user doesn't have permission to delete the post.", "user_id", userID, "post_id", postID)
+		return toPtr(T("share.delete_source_no_permission")), nil
+	}
+	if isInThread(postList, post) {
+		p.API.LogWarn("the post in a thread cannot be deleted after sharing.", "post_id", postID)
+		return toPtr(T("share.delete_source_in_thread")), nil
+	}
+	return nil, nil
+}
+
+// getDefaultShareChannel returns the configured default destination channel.
+// The channel is also checked at use time in addition to when saving the configuration, because it can be archived later.
+// A missing or archived channel is ignored, so that the user is asked to select a channel instead.
+func (p *SharePostPlugin) getDefaultShareChannel() string {
+	channelID := p.getConfiguration().DefaultShareChannel
+	if channelID == "" {
+		return ""
+	}
+	channel, appErr := p.getChannel(channelID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get default share channel, ignoring it", "channel_id", channelID, "error", appErr.Error())
+		return ""
+	}
+	if channel.DeleteAt != 0 {
+		p.API.LogWarn("default share channel is archived, ignoring it", "channel_id", channelID)
+		return ""
+	}
+	return channelID
+}
+
+// canReadPost checks whether the user has permission to read the channel of the post.
+// Posts are got by the plugin without the permissions of the user, so sharing them requires this check not to leak them.
+func (p *SharePostPlugin) canReadPost(userID string, post *model.Post) bool {
+	return p.API.HasPermissionToChannel(userID, post.ChannelId, model.PERMISSION_READ_CHANNEL)
+}
+
+// canDeletePost checks whether the user has permission to delete the post
+func (p *SharePostPlugin) canDeletePost(userID string, post *model.Post) bool {
+	if post.UserId == userID {
+		return p.API.HasPermissionToChannel(userID, post.ChannelId, model.PERMISSION_DELETE_POST)
+	}
+	return p.API.HasPermissionToChannel(userID, post.ChannelId, model.PERMISSION_DELETE_OTHERS_POSTS)
+}
+
+// duplicatedPropKeys are the props kept in duplicated posts, which are part of the content of the post
+var duplicatedPropKeys = []string{
+	"attachments",
+	"priority",
+	model.POST_PROPS_MENTION_HIGHLIGHT_DISABLED,
+	postPropsKeyDisableGroupHighlight,
+	postPropsKeyOriginalCreateAt,
+}
+
+// carryOverProps returns the props of the post which are in keys or allowed by the configuration.
+// Other props, e.g. the ones of other integrations or internal ones like `from_webhook`, are dropped.
+func (p *SharePostPlugin) carryOverProps(post *model.Post, keys ...string) model.StringInterface {
+	props := model.StringInterface{}
+	for _, list := range [][]string{keys, p.getConfiguration().getCarriedOverPropKeys()} {
+		for _, key := range list {
+			if value := post.GetProp(key); value != nil {
+				props[key] = value
+			}
+		}
+	}
+	return props
+}
+
+func (p *SharePostPlugin) clonePost(old *model.Post, userID string) (*model.Post, error) {
+	// Create new post object
+	// CreateAt and EditAt are kept as they are, so the moved post is placed at the same time as the original post.
+	// Props including message attachments are kept too, so callers must add props instead of replacing them.
+	// Post priority (urgent/important) stored in props is kept as well. The server of the supported versions doesn't
+	// have the priority in post metadata, so nothing else is needed for servers without the priority.
+	newPost := old.Clone()
+	newPost.Id = ""
+	newPost.UpdateAt = model.GetMillis()
+	newPost.AddProp(postPropsKeyOriginalCreateAt, old.CreateAt)
+	// Pinned posts stay pinned after being moved
+	newPost.IsPinned = old.IsPinned
+	// Moved posts are old content, so mentions in them don't notify users again
+	p.suppressMentions(newPost)
+	// Hashtags are parsed from the message again, because those of the original may be stale when it's edited.
+	// Channel mentions are resolved in the team of the original channel, so they are dropped and the server
+	// resolves them again in the destination channel. Otherwise mentions of channels that don't exist there are linked.
+	newPost.Hashtags, _ = model.ParseHashtags(newPost.Message)
+	newPost.DelProp(postPropsKeyChannelMentions)
+
+	// Create the reference to attached files
+	newFileIds, appErr := p.API.CopyFileInfos(userID, old.FileIds)
+	if appErr != nil {
+		p.API.LogWarn("failed to copy file ids", "error", appErr.Error())
+		return nil, fmt.Errorf("failed to copy fie ids %w", appErr)
+	}
 	newPost.FileIds = newFileIds
 	return newPost, nil
 }
 
+// suppressMentions keeps mentions in the post from notifying users again, unless EnableMentionsOnMove is set.
+// The props only disable highlighting channel-wide and group mentions, so mentions of users are escaped in the message.
+func (p *SharePostPlugin) suppressMentions(post *model.Post) {
+	if p.getConfiguration().EnableMentionsOnMove {
+		return
+	}
+	post.AddProp(model.POST_PROPS_MENTION_HIGHLIGHT_DISABLED, true)
+	post.AddProp(postPropsKeyDisableGroupHighlight, true)
+	post.Message = escapeUserMentions(post.Message)
+}
+
+// userMentionPattern matches code, link targets and URLs, which are left as they are, and mentions like `@username`,
+// whose `@` is captured. Mentions start at the beginning of the message or after a space or a punctuation, so `@` in
+// email addresses and paths like `https://example.com/@user` isn't matched.
+var userMentionPattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`|\\]\\([^)]*\\)|\\b[a-zA-Z][a-zA-Z0-9+.-]*://\\S+|(?:^|[^\\w@./])(@)\\w")
+
+// mentionEscape is put after `@` of mentions. The server doesn't find mentions in `@\u200busername`,
+// while it looks the same as the mention for users.
+const mentionEscape = "\u200b"
+
+// escapeUserMentions escapes `@username` mentions in the message, so that they don't notify the users.
+// Escaped mentions aren't matched again, so escaping the message of a post moved before is safe.
+func escapeUserMentions(message string) string {
+	var escaped strings.Builder
+	last := 0
+	for _, match := range userMentionPattern.FindAllStringSubmatchIndex(message, -1) {
+		// Only mentions capture `@`
+		if match[2] < 0 {
+			continue
+		}
+		escaped.WriteString(message[last:match[3]])
+		escaped.WriteString(mentionEscape)
+		last = match[3]
+	}
+	escaped.WriteString(message[last:])
+	return escaped.String()
+}
+
+// createPostWithRetry creates the post, retrying when the server fails with a server-side error such as overload.
+// Other errors like permission or validation errors are returned immediately, because retrying doesn't help.
+// Retrying stops when the context is done, and the last error is returned.
+// The server may fail after saving the post, so a pending post id is set to let the server deduplicate the retries
+// instead of creating the post twice.
+func (p *SharePostPlugin) createPostWithRetry(ctx context.Context, post *model.Post) (*model.Post, *model.AppError) {
+	if post.PendingPostId == "" {
+		post.PendingPostId = model.NewId()
+	}
+	var appErr *model.AppError
+	for attempt := 1; ; attempt++ {
+		var created *model.Post
+		if created, appErr = p.API.CreatePost(post); appErr == nil {
+			return created, nil
+		}
+		if attempt >= createPostMaxAttempts || appErr.StatusCode < http.StatusInternalServerError {
+			return nil, appErr
+		}
+		p.API.LogWarn("failed to create post, retrying", "attempt", attempt, "error", appErr.Error())
+		if err := sleepContext(ctx, time.Duration(attempt)*createPostRetryBackoff); err != nil {
+			return nil, appErr
+		}
+	}
+}
+
+// makeThreadSummary composes a message quoting the root post of the thread and the selected reply.
+// Other posts in the thread are not included to avoid huge messages.
+func (p *SharePostPlugin) makeThreadSummary(channelName, teamName string, postList *model.PostList, selected *model.Post) string {
+	var posts []*model.Post
+	for _, id := range postList.Order {
+		if id == selected.RootId || id == selected.Id {
+			posts = append(posts, postList.Posts[id])
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].CreateAt < posts[j].CreateAt })
+
+	T := p.getServerLocalizer()
+	lines := []string{T("share.thread_summary", channelName)}
+	for _, post := range posts {
+		label := T("share.thread_reply")
+		if post.Id == selected.RootId {
+			label = T("share.thread_root")
+		}
+		firstLine := strings.SplitN(post.Message, "\n", 2)[0]
+		lines = append(lines, fmt.Sprintf("> * [%s](%s): %s", label, p.makePostLink(teamName, post.Id), firstLine))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// buildShareMessage builds the whole message of the shared post: the composed message, the reaction summary and the footer.
+// It's used by both sharing and previewing, so that the preview shows the same message as the shared post.
+func (p *SharePostPlugin) buildShareMessage(postList *model.PostList, original *model.Post, channel *model.Channel, team *model.Team, additionalText, renderMode string, shareThread, includeReactions bool) (string, string) {
+	message, additionalText := p.composeShareMessage(postList, original, channel, team, additionalText, renderMode, shareThread)
+	// The summary shows how the post was received at the time of sharing. Reactions added later aren't reflected.
+	if includeReactions {
+		if summary := p.getReactionSummary(original.Id); summary != "" {
+			if message != "" {
+				message += "\n\n"
+			}
+			message += summary
+		}
+	}
+	return appendFooter(message, p.getConfiguration().SharedPostFooter), additionalText
+}
+
+// composeShareMessage composes the message of the shared post from the original post in the post list.
+// It also returns the additional text to be prepended by MessageWillBePosted, which is empty when the template renders it.
+// In the card render mode, the content of the post is rendered in the attachment card, so the message is empty.
+func (p *SharePostPlugin) composeShareMessage(postList *model.PostList, original *model.Post, channel *model.Channel, team *model.Team, additionalText, renderMode string, shareThread bool) (string, string) {
+	teamName := ""
+	if team != nil {
+		teamName = team.Name
+	}
+	if shareThread && original.RootId != "" {
+		return p.makeThreadSummary(channel.Name, teamName, postList, original), additionalText
+	}
+	switch {
+	case renderMode == renderModeCard:
+		return "", strings.TrimSpace(additionalText)
+	case isFileOnlyPost(original):
+		// Quoting or templating the empty message makes an awkward post, so the files are described instead
+		T := p.getServerLocalizer()
+		link := fmt.Sprintf("[%s](%s)", p.makePermalinkLabel(T("share.original_post"), original, channel), p.makePostLink(teamName, original.Id))
+		return formatFileOnlyShare(T, original, channel.Name, p.getAuthorName(original), link), additionalText
+	case renderMode == renderModeQuote:
+		return p.formatQuotedShare(original, channel, team), additionalText
+	}
+	if tmpl := p.getConfiguration().shareMessageTemplate; tmpl != nil {
+		// Additional text is rendered only by the template, so it's not prepended by MessageWillBePosted
+		rendered, err := renderShareMessage(tmpl, shareMessageData{
+			Permalink:      p.makePostLink(teamName, original.Id),
+			PermalinkLabel: p.makePermalinkLabel(p.getServerLocalizer()("share.original_post"), original, channel),
+			AdditionalText: additionalText,
+			Author:         p.getAuthorName(original),
+			Channel:        channel.Name,
+			Message:        original.Message,
+		})
+		if err == nil {
+			return rendered, ""
+		}
+		p.API.LogWarn("failed to render share message template, falling back to the default format", "error", err.Error())
+	}
+	// Plain is only the link to the original post, which is quoted by the quote mode
+	T := p.getServerLocalizer()
+	link := fmt.Sprintf("[%s](%s)", p.makePermalinkLabel(T("share.original_post"), original, channel), p.makePostLink(teamName, original.Id))
+	return T("share.plain", channel.Name, link), additionalText
+}
+
+// formatQuotedShare renders the post as a markdown blockquote with the author and the time it was posted.
+// team is nil for posts shared to DM/GM channels.
+func (p *SharePostPlugin) formatQuotedShare(post *model.Post, channel *model.Channel, team *model.Team) string {
+	teamName := ""
+	if team != nil {
+		teamName = team.Name
+	}
+	link := p.makePostLink(teamName, post.Id)
+	authorName := p.getAuthorName(post)
+	createAt := time.Unix(post.CreateAt/1000, 0)
+	T := p.getServerLocalizer()
+
+	body, truncated := truncateQuotedMessage(post.Message)
+
+	lines := []string{
+		T("share.quote_header", authorName, channel.Name, createAt.Format(T("share.time_layout"))),
+		">",
+	}
+	for _, line := range strings.Split(body, "\n") {
+		lines = append(lines, "> "+line)
+	}
+	lines = append(lines, ">")
+	label := T("share.original_post")
+	if truncated {
+		label = T("share.view_full_post")
+	}
+	lines = append(lines, fmt.Sprintf("> ([%s](%s))", p.makePermalinkLabel(label, post, channel), link))
+	return strings.Join(lines, "\n")
+}
+
+// maxPermalinkLabelExcerptLength is the maximum number of characters of the message in the labels of links
+const maxPermalinkLabelExcerptLength = 40
+
+// makePermalinkLabel returns the label of the link to the original post in the shared post, as configured by the admin.
+// defaultLabel is used for the original format.
+func (p *SharePostPlugin) makePermalinkLabel(defaultLabel string, post *model.Post, channel *model.Channel) string {
+	return formatPermalinkLabel(p.getServerLocalizer(), p.getConfiguration().getPermalinkLabel(), defaultLabel, post, channel)
+}
+
+// formatPermalinkLabel formats the label of the link to the post in the style. The fixed label is localized by T.
+// The channel and the excerpt of the message are escaped, so that their markdown doesn't break the link.
+func formatPermalinkLabel(T localizer, style, defaultLabel string, post *model.Post, channel *model.Channel) string {
+	switch style {
+	case permalinkLabelSharedPost:
+		return T("share.shared_post_label")
+	case permalinkLabelChannelMessage:
+		channelName := channel.DisplayName
+		if channelName == "" {
+			channelName = channel.Name
+		}
+		label := escapeMarkdown(channelName)
+		if excerpt := makeExcerpt(post.Message, maxPermalinkLabelExcerptLength); excerpt != "" {
+			label += ": " + escapeMarkdown(excerpt)
+		}
+		return label
+	}
+	return defaultLabel
+}
+
+// makeExcerpt returns the first line of the message, truncated to the number of characters with an ellipsis
+func makeExcerpt(message string, length int) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+	if runes := []rune(line); len(runes) > length {
+		return strings.TrimSpace(string(runes[:length])) + "…"
+	}
+	return line
+}
+
+// markdownEscaper escapes the characters having a meaning in inline markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `(`, `\(`, `)`, `\)`,
+	`~`, `\~`, `<`, `\<`, `>`, `\>`, `|`, `\|`, `#`, `\#`,
+)
+
+// escapeMarkdown escapes the text so that it's rendered as it is in markdown
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// isFileOnlyPost reports whether the post has attached files without any message
+func isFileOnlyPost(post *model.Post) bool {
+	return strings.TrimSpace(post.Message) == "" && len(post.FileIds) > 0
+}
+
+// formatFileOnlyShare renders the post having only attached files by the number of the files and the markdown link to the post.
+func formatFileOnlyShare(T localizer, post *model.Post, channelName, authorName, link string) string {
+	return T("share.file_only", len(post.FileIds), authorName, channelName, link)
+}
+
+// appendFooter appends the footer configured by the admin to the message after a horizontal rule.
+// The message is returned as it is when the footer is empty.
+func appendFooter(message, footer string) string {
+	footer = strings.TrimSpace(footer)
+	switch {
+	case footer == "":
+		return message
+	case message == "":
+		return footer
+	default:
+		return message + "\n\n---\n" + footer
+	}
+}
+
+// truncateQuotedMessage truncates the message quoted in shared posts to maxQuotedMessageLength characters.
+// It also returns whether the message is truncated.
+func truncateQuotedMessage(message string) (string, bool) {
+	if runes := []rune(message); len(runes) > maxQuotedMessageLength {
+		return string(runes[:maxQuotedMessageLength]) + "…", true
+	}
+	return message, false
+}
+
+// getAuthorName returns the display name of the author of the post in the teammate name display setting of the server.
+// "a deleted user" is returned for deleted or deactivated authors, and "Someone" if the author can't be got.
+func (p *SharePostPlugin) getAuthorName(post *model.Post) string {
+	T := p.getServerLocalizer()
+	user, appErr := p.API.GetUser(post.UserId)
+	if appErr != nil {
+		p.API.LogWarn("failed to get author of the post", "user_id", post.UserId, "error", appErr.Error())
+		if appErr.StatusCode == http.StatusNotFound {
+			return T("share.deleted_author")
+		}
+		return T("share.unknown_author")
+	}
+	if user.DeleteAt != 0 {
+		return T("share.deleted_author")
+	}
+	return user.GetDisplayNameWithPrefix(p.getTeammateNameDisplay(), "@")
+}
+
+// getTeammateNameDisplay returns the format of user names configured on the server, defaulting to username
+func (p *SharePostPlugin) getTeammateNameDisplay() string {
+	if p.ServerConfig == nil || p.ServerConfig.TeamSettings.TeammateNameDisplay == nil || *p.ServerConfig.TeamSettings.TeammateNameDisplay == "" {
+		return model.SHOW_USERNAME
+	}
+	return *p.ServerConfig.TeamSettings.TeammateNameDisplay
+}
+
+// shareMessageData is the data passed to the share message template configured by the admin
+type shareMessageData struct {
+	Permalink      string
+	PermalinkLabel string
+	AdditionalText string
+	Author         string
+	Channel        string
+	Message        string
+}
+
+func renderShareMessage(tmpl *template.Template, data shareMessageData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// getSiteURL returns Site URL of the server. Site URL can be unset by changing the server configuration after activation.
+func (p *SharePostPlugin) getSiteURL() (string, error) {
+	if p.ServerConfig == nil || p.ServerConfig.ServiceSettings.SiteURL == nil || *p.ServerConfig.ServiceSettings.SiteURL == "" {
+		return "", errSiteURLNotSet
+	}
+	return *p.ServerConfig.ServiceSettings.SiteURL, nil
+}
+
+// makePostLink returns the permalink of the post. Callers have to check Site URL by getSiteURL beforehand,
+// otherwise the link is relative to the server root.
 func (p *SharePostPlugin) makePostLink(teamName, postID string) string {
-	return fmt.Sprintf("%s/%s/pl/%s", *p.ServerConfig.ServiceSettings.SiteURL, teamName, postID)
+	siteURL, err := p.getSiteURL()
+	if err != nil {
+		p.API.LogWarn("failed to make permalink", "post_id", postID, "error", err.Error())
+	}
+	// Permalink without team name is redirected to the team that the user belongs to
+	if teamName == "" {
+		return fmt.Sprintf("%s/_redirect/pl/%s", siteURL, postID)
+	}
+	return fmt.Sprintf("%s/%s/pl/%s", siteURL, teamName, postID)
+}
+
+// postIDPattern matches post IDs in permalinks
+var postIDPattern = regexp.MustCompile(`^\w+$`)
+
+// parsePostLink extracts the team name and the post ID from the permalink, which is the inverse of makePostLink.
+// Team name is empty for the permalink redirected to the team of the user (`/_redirect/pl/<post id>`).
+func parsePostLink(link string) (string, string, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse permalink %w", err)
+	}
+	// Site URL may have a subpath, so the last segments of the path are used
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	n := len(segments)
+	if n < 3 || segments[n-2] != "pl" || !postIDPattern.MatchString(segments[n-1]) || segments[n-3] == "" {
+		return "", "", fmt.Errorf("invalid permalink %s", link)
+	}
+	teamName := segments[n-3]
+	if teamName == "_redirect" {
+		teamName = ""
+	}
+	return teamName, segments[n-1], nil
+}
+
+// resolvePostID returns the post ID from the value, which is either the post ID or the permalink
+func resolvePostID(value string) string {
+	value = strings.TrimSpace(value)
+	if _, postID, err := parsePostLink(value); err == nil {
+		return postID
+	}
+	return value
+}
+
+// shareLogFields returns the key-value pairs for logs about sharing/moving a post from the source channel to the destination channel,
+// followed by keyValuePairs.
+func (p *SharePostPlugin) shareLogFields(sourceChannelID, destinationChannelID string, keyValuePairs ...interface{}) []interface{} {
+	fields := append(p.channelLogFields("source", sourceChannelID), p.channelLogFields("destination", destinationChannelID)...)
+	return append(fields, keyValuePairs...)
+}
+
+// channelLogFields returns the key-value pairs of the channel for logs. The display name of the channel and the name of its team
+// are added to the ID, so that admins can tell the channel from logs. They're looked up through the cache, and omitted on failure.
+func (p *SharePostPlugin) channelLogFields(prefix, channelID string) []interface{} {
+	fields := []interface{}{prefix + "_channel_id", channelID}
+	channel, appErr := p.getChannel(channelID)
+	if appErr != nil {
+		return fields
+	}
+	fields = append(fields, prefix+"_channel_name", channel.DisplayName)
+	if channel.TeamId == "" {
+		return fields
+	}
+	if team, appErr := p.getTeam(channel.TeamId); appErr == nil {
+		fields = append(fields, prefix+"_team_name", team.Name)
+	}
+	return fields
+}
+
+// channelMention returns the mention of the channel, which is rendered as the display name of the channel by clients.
+// DM/GM channels cannot be mentioned with `~`.
+func channelMention(T localizer, channel *model.Channel) string {
+	if channel.IsGroupOrDirect() {
+		return T("share.direct_message")
+	}
+	return "~" + channel.Name
+}
+
+// visibilityWarning returns the note for the user when the audience of the post changes by sharing, or empty string.
+// Private channels, DMs and GMs are treated as private because only their members can read the posts.
+func visibilityWarning(T localizer, from, to *model.Channel) string {
+	fromPublic := from.Type == model.CHANNEL_OPEN
+	toPublic := to.Type == model.CHANNEL_OPEN
+	switch {
+	case fromPublic && !toPublic:
+		return "\n" + T("share.public_to_private")
+	case !fromPublic && toPublic:
+		return "\n" + T("share.private_to_public")
+	}
+	return ""
 }
 
 func (p *SharePostPlugin) rollback(ids []string) *model.AppError {
@@ -312,6 +2360,160 @@ func (p *SharePostPlugin) rollback(ids []string) *model.AppError {
 	return nil
 }
 
+// rollbackThread deletes the posts created while moving a thread, and returns the error that caused the rollback
+func (p *SharePostPlugin) rollbackThread(T localizer, createdPostIds []string, cause error) (*string, *model.SubmitDialogResponse, error) {
+	if appErr := p.rollback(createdPostIds); appErr != nil {
+		p.API.LogWarn("failed to rollback post thread")
+		return toPtr(T("error.generic")), nil, fmt.Errorf("%v and failed to rollback: %w", cause, appErr)
+	}
+	if isTimeout(cause) {
+		return toPtr(T("error.timed_out")), nil, cause
+	}
+	return toPtr(T("error.generic")), nil, cause
+}
+
+// parseAdditionalText returns the trimmed additional text followed by a blank line, which separates it from the shared message.
+// The dialog error is returned when the text is too long.
+func (p *SharePostPlugin) parseAdditionalText(T localizer, submission map[string]interface{}) (string, *model.SubmitDialogResponse) {
+	text, _ := submission[additionalTextKey].(string)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", nil
+	}
+	if max := p.getConfiguration().getMaxAdditionalTextLength(); utf8.RuneCountInString(text) > max {
+		return "", dialogFieldError(additionalTextKey, T("dialog.additional_text_too_long", max))
+	}
+	if len(findBroadcastMentions(text)) > 0 {
+		switch p.getConfiguration().getBroadcastMentionPolicy() {
+		case broadcastMentionStrip:
+			text = stripBroadcastMentions(text)
+		case broadcastMentionConfirm:
+			if confirmed, _ := submission[confirmMentionKey].(bool); !confirmed {
+				return "", dialogFieldError(additionalTextKey, T("dialog.confirm_broadcast_mention"))
+			}
+		}
+	}
+	return text + "\n\n", nil
+}
+
+// broadcastMentionPattern matches the candidates of the mentions notifying everyone in the channel
+var broadcastMentionPattern = regexp.MustCompile(`(?i)@(all|channel|here)`)
+
+// findBroadcastMentions returns the positions of @all, @channel and @here in the text.
+// Candidates in email addresses or usernames like `@channel-admin` are excluded, but a trailing period is allowed
+// like the server does.
+func findBroadcastMentions(text string) [][]int {
+	isNameChar := func(c byte) bool {
+		return c == '_' || c == '-' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+	}
+	mentions := [][]int{}
+	for _, loc := range broadcastMentionPattern.FindAllStringIndex(text, -1) {
+		if loc[0] > 0 && (isNameChar(text[loc[0]-1]) || text[loc[0]-1] == '.' || text[loc[0]-1] == '@') {
+			continue
+		}
+		rest := text[loc[1]:]
+		if rest != "" && (isNameChar(rest[0]) || (rest[0] == '.' && len(rest) > 1 && isNameChar(rest[1]))) {
+			continue
+		}
+		mentions = append(mentions, loc)
+	}
+	return mentions
+}
+
+// stripBroadcastMentions removes `@` of the mentions notifying everyone in the channel, so that the words are kept
+func stripBroadcastMentions(text string) string {
+	mentions := findBroadcastMentions(text)
+	for i := len(mentions) - 1; i >= 0; i-- {
+		text = text[:mentions[i][0]] + text[mentions[i][0]+1:]
+	}
+	return text
+}
+
+// checkRetention returns the message for the user if the post is older than the message retention period of the server.
+// It's checked only when enforcing the retention on moves is enabled. Moved posts keep CreateAt of the original posts
+// regardless of this option, so they are deleted by the retention job at the same time as the original would be.
+func (p *SharePostPlugin) checkRetention(T localizer, post *model.Post) *string {
+	if !p.getConfiguration().EnforceRetentionOnMove {
+		return nil
+	}
+	retention := getMessageRetention(p.ServerConfig)
+	if retention == 0 {
+		return nil
+	}
+	if model.GetMillis()-post.CreateAt > int64(retention/time.Millisecond) {
+		return toPtr(T("move.retention_expired", int(retention/(24*time.Hour))))
+	}
+	return nil
+}
+
+// checkMoveCooldown returns the message for the user if the post was moved within the cooldown, which is found by the provenance props.
+func (p *SharePostPlugin) checkMoveCooldown(T localizer, post *model.Post) *string {
+	cooldown := time.Duration(p.getConfiguration().MoveCooldownMinutes) * time.Minute
+	if cooldown <= 0 {
+		return nil
+	}
+	// Props are decoded from JSON as float64 when the post is read from the server
+	var movedAt int64
+	switch value := post.GetProp(postPropsKeyMovedAt).(type) {
+	case int64:
+		movedAt = value
+	case float64:
+		movedAt = int64(value)
+	default:
+		return nil
+	}
+	if model.GetMillis()-movedAt < int64(cooldown/time.Millisecond) {
+		return toPtr(T("move.recently_moved", p.getConfiguration().MoveCooldownMinutes))
+	}
+	return nil
+}
+
+// parseRenderMode returns the render mode of the shared post in the submission, defaulting to plain.
+func parseRenderMode(T localizer, submission map[string]interface{}) (string, *model.SubmitDialogResponse) {
+	mode, _ := submission[renderModeKey].(string)
+	switch mode {
+	case "":
+		return renderModePlain, nil
+	case renderModePlain, renderModeQuote, renderModeCard:
+		return mode, nil
+	default:
+		return "", dialogFieldError(renderModeKey, T("dialog.invalid_render_mode"))
+	}
+}
+
+// parseTextPosition returns the position of additional text in the submission, defaulting to above the content.
+func parseTextPosition(T localizer, submission map[string]interface{}) (string, *model.SubmitDialogResponse) {
+	position, _ := submission[textPositionKey].(string)
+	switch position {
+	case "":
+		return textPositionAbove, nil
+	case textPositionAbove, textPositionBelow:
+		return position, nil
+	default:
+		return "", dialogFieldError(textPositionKey, T("dialog.invalid_text_position"))
+	}
+}
+
+// placeAdditionalText puts the additional text, which is followed by a blank line, above or below the message.
+// Posts having only attached files have empty messages, so the text is placed without separator.
+func placeAdditionalText(message, additionalText, position string) string {
+	if position != textPositionBelow {
+		return additionalText + message
+	}
+	text := strings.TrimSpace(additionalText)
+	if text == "" || message == "" {
+		return message + text
+	}
+	return message + "\n\n" + text
+}
+
+// dialogFieldError returns the dialog response showing the error on the element
+func dialogFieldError(key, message string) *model.SubmitDialogResponse {
+	return &model.SubmitDialogResponse{
+		Errors: map[string]string{key: message},
+	}
+}
+
 func toPtr(s string) *string {
 	return &s
 }
@@ -0,0 +1,4605 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupTestPlugin(api *plugintest.API) *SharePostPlugin {
+	p := &SharePostPlugin{}
+	p.SetAPI(api)
+	p.ServerConfig = &model.Config{}
+	p.ServerConfig.ServiceSettings.SiteURL = toPtr("http://localhost:8065")
+	p.setConfiguration(&configuration{EnableShare: true, EnableMove: true})
+	p.i18n = loadTestI18nBundle()
+	p.metrics = newMetrics()
+	p.lookupCache = newLookupCache(lookupCacheTTL)
+	p.csrfSecret = []byte("csrf_secret")
+	return p
+}
+
+// loadTestI18nBundle loads only English messages, so that tests don't need to look up the locale of users
+func loadTestI18nBundle() *i18nBundle {
+	bundle, err := loadI18nBundle(filepath.Join("..", "..", "assets", "i18n"))
+	if err != nil {
+		panic(err)
+	}
+	return &i18nBundle{messages: map[string]map[string]string{defaultLocale: bundle.messages[defaultLocale]}}
+}
+
+func TestSharePost(t *testing.T) {
+	t.Run("share to direct channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "dm_channel_id").Return(&model.Channel{Id: "dm_channel_id", Name: "user1__user2", Type: model.CHANNEL_DIRECT}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "dm_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "dm_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("dm_channel_id", post.ChannelId)
+			assert.Contains(post.Message, "> message\n")
+			assert.Contains(post.Message, "([original post](http://localhost:8065/_redirect/pl/post_id))")
+			assert.Equal("post_id", post.GetProp(postPropsKeySharedFromPostID))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{}).Run(func(args mock.Arguments) {
+			post := args.Get(1).(*model.Post)
+			assert.Contains(post.Message, "Note: You're sharing from a public channel into a private channel.")
+		})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "dm_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetTeam", mock.Anything)
+	})
+	t.Run("share as a reply", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "reply_id").Return(&model.Post{Id: "reply_id", ChannelId: "to_channel_id", RootId: "root_id"}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("to_channel_id", post.ChannelId)
+			assert.Equal("root_id", post.RootId)
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+				shareTypeKey: shareTypeShare,
+				toRootIDKey:  "reply_id",
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("confirmation links to the new post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", DisplayName: "Off-Topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{}).Run(func(args mock.Arguments) {
+			post := args.Get(1).(*model.Post)
+			assert.Equal("channel_id", post.ChannelId)
+			assert.Equal("[This post](http://localhost:8065/team/pl/post_id) is shared to ~off-topic. [New post](http://localhost:8065/team/pl/new_post_id).", post.Message)
+		})
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertCalled(t, "SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("share from another channel than the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.True(strings.HasPrefix(post.Message, "> **@author** posted in ~town-square"))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{}).Run(func(args mock.Arguments) {
+			// The confirmation is sent to the channel where the user is
+			assert.Equal("current_channel_id", args.Get(1).(*model.Post).ChannelId)
+		})
+
+		// The dialog is opened from the permalink in another channel
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "current_channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetChannel", "current_channel_id")
+	})
+	t.Run("share post in another team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "other_team_id").Return(&model.Team{Id: "other_team_id", Name: "other-team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Contains(post.Message, "([original post](http://localhost:8065/other-team/pl/post_id))")
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetTeam", "team_id")
+	})
+	t.Run("retry creating post on server error", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		defer func(backoff time.Duration) { createPostRetryBackoff = backoff }(createPostRetryBackoff)
+		createPostRetryBackoff = 0
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("LogWarn", "failed to create post, retrying", "attempt", 1, "error", mock.AnythingOfType("string")).Return()
+		var pendingPostIDs []string
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			pendingPostIDs = append(pendingPostIDs, post.PendingPostId)
+			return nil
+		}, &model.AppError{StatusCode: http.StatusServiceUnavailable}).Once()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			pendingPostIDs = append(pendingPostIDs, post.PendingPostId)
+			post.Id = "new_post_id"
+			return post
+		}, nil).Once()
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 2)
+		// The retry is sent with the same pending post id, so that the server doesn't create the post twice
+		assert.Len(pendingPostIDs, 2)
+		assert.NotEmpty(pendingPostIDs[0])
+		assert.Equal(pendingPostIDs[0], pendingPostIDs[1])
+	})
+	t.Run("give up creating post after retries", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		defer func(backoff time.Duration) { createPostRetryBackoff = backoff }(createPostRetryBackoff)
+		createPostRetryBackoff = 0
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("LogWarn", "failed to create post, retrying", "attempt", mock.AnythingOfType("int"), "error", mock.AnythingOfType("string")).Return()
+		api.On("LogWarn", GetMockArgumentsWithType("string", 13)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, &model.AppError{StatusCode: http.StatusInternalServerError})
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Equal("Something went wrong. Please try again later.", *msg)
+		assert.Nil(response)
+		assert.NotNil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", createPostMaxAttempts)
+	})
+	t.Run("don't retry creating post on client error", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("LogWarn", GetMockArgumentsWithType("string", 13)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, &model.AppError{StatusCode: http.StatusForbidden})
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Equal("Something went wrong. Please try again later.", *msg)
+		assert.Nil(response)
+		assert.NotNil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+	t.Run("share with message template", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		p.setConfiguration(&configuration{
+			shareMessageTemplate: template.Must(template.New("").Parse("{{.AdditionalText}} {{.Author}} in ~{{.Channel}}: {{.Message}} {{.Permalink}}")),
+		})
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("Hi @author in ~town-square: message http://localhost:8065/team/pl/post_id", post.Message)
+			assert.Equal("", post.GetProp(postPropsKeyAdditionalText))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "Hi", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("share with footer", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		p.setConfiguration(&configuration{
+			shareMessageTemplate: template.Must(template.New("").Parse("{{.Message}}")),
+			SharedPostFooter:     "Posted via SharePost",
+		})
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("message\n\n---\nPosted via SharePost", post.Message)
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("file-only post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", FileIds: model.StringArray{"file_id1", "file_id2"}})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("CopyFileInfos", "user_id", []string{"file_id1", "file_id2"}).Return([]string{"new_file_id1", "new_file_id2"}, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("> Shared 2 file(s) posted by **@author** in ~town-square. ([original post](http://localhost:8065/team/pl/post_id))", post.Message)
+			assert.Equal(model.StringArray{"new_file_id1", "new_file_id2"}, post.FileIds)
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, true, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("quote mode ignores message template", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		p.setConfiguration(&configuration{
+			shareMessageTemplate: template.Must(template.New("").Parse("{{.Author}}: {{.Message}}")),
+		})
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.True(strings.HasPrefix(post.Message, "> **@author** posted in ~town-square"))
+			assert.Equal("Hi\n\n", post.GetProp(postPropsKeyAdditionalText))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "Hi\n\n", renderModeQuote, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("additional text below", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		p.setConfiguration(&configuration{
+			shareMessageTemplate: template.Must(template.New("").Parse("{{.Author}}: {{.Message}}")),
+		})
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.True(strings.HasPrefix(post.Message, "> **@author** posted in ~town-square"))
+			assert.Equal("Hi\n\n", post.GetProp(postPropsKeyAdditionalText))
+			assert.Equal(textPositionBelow, post.GetProp(postPropsKeyTextPosition))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "Hi\n\n", renderModeQuote, textPositionBelow, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("include reactions", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{
+			{UserId: "user1", PostId: "post_id", EmojiName: "heart"},
+			{UserId: "user1", PostId: "post_id", EmojiName: "+1"},
+			{UserId: "user2", PostId: "post_id", EmojiName: "+1"},
+		}, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.True(strings.HasPrefix(post.Message, "> **@author** posted in ~town-square"))
+			assert.True(strings.HasSuffix(post.Message, "\n\n:+1: 2 :heart: 1"))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, true)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("card mode", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: 1234567890123})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("", post.Message)
+			assert.Equal("Hi", post.GetProp(postPropsKeyAdditionalText))
+			assert.Equal("post_id", post.GetProp(postPropsKeySharedFromPostID))
+			attachments := post.Attachments()
+			if assert.Len(attachments, 1) {
+				card := attachments[0]
+				assert.Equal("@author", card.Title)
+				assert.Equal("http://localhost:8065/team/pl/post_id", card.TitleLink)
+				assert.Equal("message", card.Text)
+				assert.Equal([]*model.SlackAttachmentField{
+					{Title: "Author", Value: "@author", Short: true},
+					{Title: "Channel", Value: "~town-square", Short: true},
+				}, card.Fields)
+				assert.Equal(int64(1234567890), card.Timestamp)
+				if assert.Len(card.Actions, 1) {
+					assert.Equal("View original", card.Actions[0].Name)
+					assert.Equal("http://localhost:8065/team/pl/post_id", card.Actions[0].Integration.Context[cardContextKeyPermalink])
+				}
+			}
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "Hi\n\n", renderModeCard, textPositionAbove, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("site url is not set", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.ServerConfig.ServiceSettings.SiteURL = nil
+		api.On("LogError", "Site URL is not configured").Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("Server Site URL is not configured; ask an admin to set it.", *msg)
+		assert.Nil(response)
+		assert.Equal(errSiteURLNotSet, err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("no permission to post in the channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 11)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("You don't have permission to post in the selected channel.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("original post is soft-deleted", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", DeleteAt: 1000})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("The original post no longer exists.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("posting is restricted by channel moderation", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "announcements", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		// Channel moderation removes create_post from the members, which is reflected in the permission check
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 11)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("Posting is restricted in the selected channel by its moderation settings.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("destination channel no longer exists", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "to_channel_id").Return(nil, model.NewAppError("GetChannel", "app.channel.get.existing.app_error", nil, "", http.StatusNotFound))
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("The selected channel no longer exists.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("failed to get source team", func(t *testing.T) {
+		for _, test := range []struct {
+			Name       string
+			StatusCode int
+			Expected   string
+			HasError   bool
+		}{
+			{Name: "team not found", StatusCode: http.StatusNotFound, Expected: "The source team is no longer accessible.", HasError: false},
+			{Name: "no permission", StatusCode: http.StatusForbidden, Expected: "The source team is no longer accessible.", HasError: false},
+			{Name: "server error", StatusCode: http.StatusInternalServerError, Expected: "Something went wrong. Please try again later.", HasError: true},
+		} {
+			t.Run(test.Name, func(t *testing.T) {
+				assert := assert.New(t)
+				api := &plugintest.API{}
+				defer api.AssertExpectations(t)
+				p := setupTestPlugin(api)
+
+				api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+				api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+				api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+				api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+				api.On("GetTeam", "team_id").Return(nil, &model.AppError{Message: "failed", StatusCode: test.StatusCode})
+				postList := model.NewPostList()
+				postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+				postList.AddOrder("post_id")
+				api.On("GetPostThread", "post_id").Return(postList, nil)
+				api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+				api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+				if test.HasError {
+					api.On("LogError", GetMockArgumentsWithType("string", 5)...).Return()
+				} else {
+					api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+				}
+
+				request := &model.SubmitDialogRequest{
+					CallbackId: "post_id",
+					UserId:     "user_id",
+					ChannelId:  "channel_id",
+					TeamId:     "team_id",
+				}
+				msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+				assert.Equal(test.Expected, *msg)
+				assert.Nil(response)
+				assert.Equal(test.HasError, err != nil)
+				api.AssertNotCalled(t, "CreatePost", mock.Anything)
+			})
+		}
+	})
+	t.Run("destination channel is archived", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN, DeleteAt: 1000}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("The selected channel is archived and can't receive posts.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("failed to get destination channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "to_channel_id").Return(nil, model.NewAppError("GetChannel", "app.channel.get.existing.app_error", nil, "", http.StatusInternalServerError))
+		api.On("LogError", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("Something went wrong. Please try again later.", *msg)
+		assert.Nil(response)
+		assert.NotNil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("system message", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Type: model.POST_JOIN_CHANNEL, Message: "author joined the channel."})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+		}
+		msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("System messages can't be shared.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("share to multiple channels", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		mockNoCollectionRoot(api)
+
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		for _, id := range []string{"channel1_id", "channel2_id", "channel3_id"} {
+			api.On("GetChannel", id).Return(&model.Channel{Id: id, Name: strings.TrimSuffix(id, "_id"), TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+			api.On("GetChannelMember", id, "user_id").Return(&model.ChannelMember{}, nil)
+			api.On("HasPermissionToChannel", "user_id", id, model.PERMISSION_CREATE_POST).Return(id != "channel2_id")
+		}
+		api.On("LogWarn", GetMockArgumentsWithType("string", 15)...).Return()
+		created := []string{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			created = append(created, post.ChannelId)
+			post.Id = model.NewId()
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "channel1_id, channel2_id,channel3_id",
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Shared to 2 of 3 channels (1 failed).\n* ~channel2: Posting is restricted in the selected channel by its moderation settings.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		assert.ElementsMatch([]string{"channel1_id", "channel3_id"}, created)
+	})
+}
+
+func TestComposeShareMessageRenderModes(t *testing.T) {
+	assert := assert.New(t)
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+
+	post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+	postList := model.NewPostList()
+	postList.AddPost(post)
+	postList.AddOrder("post_id")
+	channel := &model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}
+	team := &model.Team{Id: "team_id", Name: "team"}
+
+	plain, _ := p.composeShareMessage(postList, post, channel, team, "", renderModePlain, false)
+	quote, _ := p.composeShareMessage(postList, post, channel, team, "", renderModeQuote, false)
+
+	assert.Equal("> Shared from ~town-square. ([original post](http://localhost:8065/team/pl/post_id))", plain)
+	assert.Equal("> **@author** posted in ~town-square on Thu 1 Jan 1970 at 00:00:00 UTC\n>\n> message\n>\n> ([original post](http://localhost:8065/team/pl/post_id))", quote)
+	api.AssertNumberOfCalls(t, "GetUser", 1)
+}
+
+func TestSharePostThread(t *testing.T) {
+	for _, test := range []struct {
+		Name        string
+		ShareThread bool
+		Expected    string
+	}{
+		{
+			Name:        "share a reply with its root",
+			ShareThread: true,
+			Expected: "> Shared thread from ~town-square.\n" +
+				"> * [root post](http://localhost:8065/team/pl/root_id): question\n" +
+				"> * [reply](http://localhost:8065/team/pl/reply_id): answer",
+		},
+		{
+			Name:        "share only the reply by default",
+			ShareThread: false,
+			Expected:    "> **@author** posted in ~town-square on Thu 1 Jan 1970 at 00:00:00 UTC\n>\n> answer\n>\n> ([original post](http://localhost:8065/team/pl/reply_id))",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			defer api.AssertExpectations(t)
+			p := setupTestPlugin(api)
+
+			postList := model.NewPostList()
+			postList.AddPost(&model.Post{Id: "root_id", UserId: "author_id", ChannelId: "channel_id", Message: "question\ndetails", CreateAt: 1})
+			postList.AddPost(&model.Post{Id: "other_reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "root_id", Message: "other", CreateAt: 2})
+			postList.AddPost(&model.Post{Id: "reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "root_id", Message: "answer", CreateAt: 3})
+			postList.AddOrder("reply_id")
+			postList.AddOrder("other_reply_id")
+			postList.AddOrder("root_id")
+			api.On("GetPostThread", "reply_id").Return(postList, nil)
+			api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+			api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+			api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+			api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+			api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil).Maybe()
+			api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+			api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+			api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				assert.Equal(test.Expected, post.Message)
+				post.Id = "new_post_id"
+				return post
+			}, nil)
+			api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+			api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+			mockAuditIndex(api)
+			api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+			request := &model.SubmitDialogRequest{
+				CallbackId: "reply_id",
+				UserId:     "user_id",
+				ChannelId:  "channel_id",
+				TeamId:     "team_id",
+			}
+			msg, response, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, test.ShareThread, false, false)
+
+			assert.Nil(msg)
+			assert.Nil(response)
+			assert.Nil(err)
+			api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+		})
+	}
+}
+
+func TestSharePostIncludeFiles(t *testing.T) {
+	for _, test := range []struct {
+		Name         string
+		IncludeFiles bool
+		FileIds      []string
+		CopyErr      *model.AppError
+		ExpectedMsg  string
+		Expected     []string
+	}{
+		{
+			Name:         "include files",
+			IncludeFiles: true,
+			FileIds:      []string{"file1_id", "file2_id"},
+			Expected:     []string{"new_file1_id", "new_file2_id"},
+		},
+		{
+			Name:         "files are not included by default",
+			IncludeFiles: false,
+			FileIds:      []string{"file1_id"},
+		},
+		{
+			Name:         "post without files",
+			IncludeFiles: true,
+		},
+		{
+			Name:         "failed to copy files",
+			IncludeFiles: true,
+			FileIds:      []string{"file1_id"},
+			CopyErr:      model.NewAppError("CopyFileInfos", "app.file_info.save.app_error", nil, "", http.StatusInternalServerError),
+			ExpectedMsg:  "Something went wrong. Please try again later.",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			p := setupTestPlugin(api)
+
+			postList := model.NewPostList()
+			postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", FileIds: test.FileIds})
+			postList.AddOrder("post_id")
+			api.On("GetPostThread", "post_id").Return(postList, nil)
+			api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+			api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+			api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+			api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+			api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+			api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+			api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+			api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+			api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return().Maybe()
+			copied := make([]string, len(test.FileIds))
+			for i, id := range test.FileIds {
+				copied[i] = "new_" + id
+			}
+			api.On("CopyFileInfos", "user_id", test.FileIds).Return(copied, test.CopyErr).Maybe()
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				assert.Equal(test.Expected, []string(post.FileIds))
+				assert.Equal(true, post.GetProp(postPropsKeyFilesHandled))
+				post.Id = "new_post_id"
+				return post
+			}, nil).Maybe()
+			api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{}).Maybe()
+			api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Maybe()
+			mockAuditIndex(api)
+			api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return().Maybe()
+
+			request := &model.SubmitDialogRequest{
+				CallbackId: "post_id",
+				UserId:     "user_id",
+				ChannelId:  "channel_id",
+				TeamId:     "team_id",
+			}
+			msg, _, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, test.IncludeFiles, false)
+
+			if test.ExpectedMsg != "" {
+				assert.Equal(test.ExpectedMsg, *msg)
+				assert.NotNil(err)
+				api.AssertNotCalled(t, "CreatePost", mock.Anything)
+				return
+			}
+			assert.Nil(msg)
+			assert.Nil(err)
+			api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+			if !test.IncludeFiles || len(test.FileIds) == 0 {
+				api.AssertNotCalled(t, "CopyFileInfos", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandleSharePost(t *testing.T) {
+	t.Run("channel is not selected", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Equal(map[string]string{toChannelKey: "Please select a channel."}, response.Errors)
+		assert.Nil(err)
+	})
+	t.Run("channel is not selected with default channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		mockNoCollectionRoot(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		p.setConfiguration(&configuration{EnableShare: true, DefaultShareChannel: "default_channel_id"})
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "default_channel_id").Return(&model.Channel{Id: "default_channel_id", Name: "highlights", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetChannelMember", "default_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "default_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("default_channel_id", post.ChannelId)
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+	t.Run("team of the request is not the team of the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		// Moving is disabled in the team of the post, and enabled in the team sent by the client
+		disabled := false
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: true, teamOverrides: map[string]*teamOverride{
+			"team_id": {EnableMove: &disabled},
+		}})
+		mockSourcePost(api)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "other_team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:   "to_channel_id",
+				shareTypeKey:   shareTypeMove,
+				confirmMoveKey: true,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("This post isn't in the current team. Please share it from the team of the post.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("channel is not selected with stale default channel", func(t *testing.T) {
+		for name, mockChannel := range map[string]func(api *plugintest.API){
+			"archived": func(api *plugintest.API) {
+				api.On("GetChannel", "default_channel_id").Return(&model.Channel{Id: "default_channel_id", Name: "highlights", TeamId: "team_id", Type: model.CHANNEL_OPEN, DeleteAt: 1000}, nil)
+				api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+			},
+			"deleted": func(api *plugintest.API) {
+				api.On("GetChannel", "default_channel_id").Return(nil, &model.AppError{Message: "not found"})
+				api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				assert := assert.New(t)
+				api := &plugintest.API{}
+				defer api.AssertExpectations(t)
+				p := setupTestPlugin(api)
+				p.setConfiguration(&configuration{EnableShare: true, DefaultShareChannel: "default_channel_id"})
+				mockChannel(api)
+
+				request := &model.SubmitDialogRequest{
+					CallbackId: "post_id",
+					UserId:     "user_id",
+					ChannelId:  "channel_id",
+					TeamId:     "team_id",
+					Submission: map[string]interface{}{
+						shareTypeKey: shareTypeShare,
+					},
+				}
+				msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+				assert.Nil(msg)
+				assert.Equal(map[string]string{toChannelKey: "Please select a channel."}, response.Errors)
+				assert.Nil(err)
+				api.AssertNotCalled(t, "CreatePost", mock.Anything)
+			})
+		}
+	})
+	t.Run("additional text is required", func(t *testing.T) {
+		for _, shareType := range []string{shareTypeShare, shareTypeCopy, shareTypeDuplicate} {
+			t.Run(shareType, func(t *testing.T) {
+				assert := assert.New(t)
+				api := &plugintest.API{}
+				defer api.AssertExpectations(t)
+				p := setupTestPlugin(api)
+				p.setConfiguration(&configuration{EnableShare: true, RequireAdditionalText: true})
+
+				request := &model.SubmitDialogRequest{
+					CallbackId: "post_id",
+					UserId:     "user_id",
+					ChannelId:  "channel_id",
+					TeamId:     "team_id",
+					Submission: map[string]interface{}{
+						toChannelKey:      "to_channel_id",
+						shareTypeKey:      shareType,
+						additionalTextKey: "  ",
+					},
+				}
+				msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+				assert.Nil(msg)
+				assert.Equal(map[string]string{additionalTextKey: "Please add a note explaining why you're sharing this."}, response.Errors)
+				assert.Nil(err)
+				api.AssertNotCalled(t, "CreatePost", mock.Anything)
+			})
+		}
+	})
+	t.Run("additional text is required and written", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		mockNoCollectionRoot(api)
+		p.setConfiguration(&configuration{EnableShare: true, RequireAdditionalText: true})
+
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("For the record\n\n", post.GetProp(postPropsKeyAdditionalText))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:      "to_channel_id",
+				shareTypeKey:      shareTypeShare,
+				additionalTextKey: "For the record",
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+	t.Run("additional text is not required for move", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		p.setConfiguration(&configuration{EnableMove: true, RequireAdditionalText: true})
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("KVGet", makeSkipConfirmationKey("user_id")).Return(nil, nil)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+				shareTypeKey: shareTypeMove,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		// The move is asked for the confirmation, not for the note
+		assert.Nil(msg)
+		assert.Contains(response.Errors, confirmMoveKey)
+		assert.NotContains(response.Errors, additionalTextKey)
+		assert.Nil(err)
+	})
+	t.Run("share is disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		p.setConfiguration(&configuration{EnableShare: false, EnableMove: true})
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Sharing posts is disabled on this server.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("destination by team and channel name", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		p.setConfiguration(&configuration{EnableShare: false, EnableMove: true})
+		api.On("GetChannelByNameForTeamName", "team", "off-topic", false).Return(&model.Channel{Id: "off_topic_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "off_topic_id").Return(&model.Channel{Id: "off_topic_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "team:~off-topic",
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		// The destination is checked by the resolved ID before the share type
+		assert.Equal("Sharing posts is disabled on this server.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("destination by channel ID", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		p.setConfiguration(&configuration{EnableShare: false, EnableMove: true})
+		channelID := model.NewId()
+		api.On("GetChannel", channelID).Return(&model.Channel{Id: channelID, TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: channelID,
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Sharing posts is disabled on this server.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetChannelByNameForTeamName", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("destination name is not found", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByNameForTeamName", "team", "unknown", false).Return(nil, model.NewAppError("GetChannelByNameForTeamName", "app.channel.get_by_name.missing.app_error", nil, "", http.StatusNotFound))
+		api.On("LogWarn", GetMockArgumentsWithType("string", 7)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "team:unknown",
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Equal(map[string]string{toChannelKey: "Channel \"team:unknown\" is not found. Specify the channel by its ID or as team-name:channel-name."}, response.Errors)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetChannel", mock.Anything)
+	})
+	t.Run("move is disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: false})
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:   "to_channel_id",
+				shareTypeKey:   shareTypeMove,
+				confirmMoveKey: true,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Moving posts is disabled on this server.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetPostThread", mock.Anything)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("move is disabled for the team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		disabled := false
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: true, teamOverrides: map[string]*teamOverride{
+			"team_id": {EnableMove: &disabled},
+		}})
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:   "to_channel_id",
+				shareTypeKey:   shareTypeMove,
+				confirmMoveKey: true,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Moving posts is disabled on this server.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetPostThread", mock.Anything)
+	})
+	t.Run("destination not allowed for the team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		denied := "to_channel_id"
+		p.setConfiguration(&configuration{EnableShare: true, teamOverrides: map[string]*teamOverride{
+			"team_id": {DeniedShareDestinations: &denied},
+		}})
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Posts can't be shared or moved to the selected channel.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("share type is not selected", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Equal(map[string]string{shareTypeKey: "Please select a share type."}, response.Errors)
+		assert.Nil(err)
+	})
+	t.Run("share type in mixed case", func(t *testing.T) {
+		for _, test := range []struct {
+			ShareType string
+			Expected  string
+		}{
+			{ShareType: "Share", Expected: "Sharing posts is disabled on this server."},
+			{ShareType: " SHARE ", Expected: "Sharing posts is disabled on this server."},
+			{ShareType: "Move", Expected: "Moving posts is disabled on this server."},
+			{ShareType: "MOVE", Expected: "Moving posts is disabled on this server."},
+		} {
+			t.Run(test.ShareType, func(t *testing.T) {
+				assert := assert.New(t)
+				api := &plugintest.API{}
+				defer api.AssertExpectations(t)
+				p := setupTestPlugin(api)
+				mockSourcePost(api)
+				p.setConfiguration(&configuration{EnableShare: false, EnableMove: false})
+				api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+				api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+				request := &model.SubmitDialogRequest{
+					CallbackId: "post_id",
+					UserId:     "user_id",
+					ChannelId:  "channel_id",
+					TeamId:     "team_id",
+					Submission: map[string]interface{}{
+						toChannelKey: "to_channel_id",
+						shareTypeKey: test.ShareType,
+					},
+				}
+				msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+				assert.Equal(test.Expected, *msg)
+				assert.Nil(response)
+				assert.Nil(err)
+			})
+		}
+	})
+	t.Run("unknown share type", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+				shareTypeKey: "Forward",
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Something went wrong. Please try again later.", *msg)
+		assert.Nil(response)
+		assert.EqualError(err, "invalid share_type forward")
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("destination not allowed", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		p.setConfiguration(&configuration{DeniedShareDestinations: "denied_channel_id"})
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "denied_channel_id").Return(&model.Channel{Id: "denied_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id,denied_channel_id",
+				shareTypeKey: shareTypeShare,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Posts can't be shared or moved to the selected channel.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("thread to reply to is not in the destination channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "root_id").Return(&model.Post{Id: "root_id", ChannelId: "other_channel_id"}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+				shareTypeKey: shareTypeShare,
+				toRootIDKey:  "http://localhost:8065/team/pl/root_id",
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("The thread to reply to was not found in the selected channel.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("thread to reply to with multiple channels", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "other_channel_id").Return(&model.Channel{Id: "other_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id,other_channel_id",
+				shareTypeKey: shareTypeShare,
+				toRootIDKey:  "root_id",
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("Replying to a thread is available only when sharing, copying or moving to a single channel.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetPost", "root_id")
+	})
+	t.Run("delete source after sharing", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		mockNoCollectionRoot(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "archive", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_OTHERS_POSTS).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("DeletePost", "post_id").Return(nil)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:    "to_channel_id",
+				shareTypeKey:    shareTypeShare,
+				deleteSourceKey: true,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertCalled(t, "DeletePost", "post_id")
+	})
+	t.Run("don't delete source when sharing failed", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		mockNoCollectionRoot(api)
+
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "archive", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_OTHERS_POSTS).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 13)...).Return()
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:    "to_channel_id",
+				shareTypeKey:    shareTypeShare,
+				deleteSourceKey: true,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("You don't have permission to post in the selected channel.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("no permission to delete source", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "archive", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_OTHERS_POSTS).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:    "to_channel_id",
+				shareTypeKey:    shareTypeShare,
+				deleteSourceKey: true,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("You don't have permission to delete this post.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+}
+
+func TestHandleMovePost(t *testing.T) {
+	t.Run("move the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("to_channel_id", post.ChannelId)
+			assert.Equal("note\n\n", post.GetProp(postPropsKeyAdditionalText))
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:      "to_channel_id",
+				additionalTextKey: "note",
+				confirmMoveKey:    true,
+			},
+		}
+		msg, response, err := p.handleMovePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertCalled(t, "DeletePost", "post_id")
+	})
+	t.Run("team of the request is not the team of the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "other_team_id",
+			Submission: map[string]interface{}{
+				toChannelKey:   "to_channel_id",
+				confirmMoveKey: true,
+			},
+		}
+		msg, response, err := p.handleMovePost(context.Background(), map[string]string{}, request)
+
+		assert.Equal("This post isn't in the current team. Please share it from the team of the post.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("channel is not selected", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{},
+		}
+		msg, response, err := p.handleMovePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Equal(map[string]string{toChannelKey: "Please select a channel."}, response.Errors)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestParseChannelIDs(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Value    interface{}
+		Expected []string
+	}{
+		{Name: "single channel", Value: "channel1", Expected: []string{"channel1"}},
+		{Name: "comma-separated", Value: "channel1, channel2,,channel3 ", Expected: []string{"channel1", "channel2", "channel3"}},
+		{Name: "JSON array", Value: `["channel1", "channel2"]`, Expected: []string{"channel1", "channel2"}},
+		{Name: "array", Value: []interface{}{"channel1", 2, " channel2"}, Expected: []string{"channel1", "channel2"}},
+		{Name: "empty", Value: "", Expected: []string{}},
+		{Name: "missing", Value: nil, Expected: []string{}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, parseChannelIDs(test.Value))
+		})
+	}
+}
+
+func TestShareToChannels(t *testing.T) {
+	t.Run("results in the order of channels", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		toChannels := []string{"channel1", "channel2", "channel3", "channel4", "channel5", "channel6"}
+
+		results := p.shareToChannels(context.Background(), p.getLocalizer("user_id"), toChannels, func(_ context.Context, toChannel string) (*string, *model.SubmitDialogResponse, error) {
+			if toChannel == "channel3" {
+				return toPtr("failed"), nil, nil
+			}
+			return nil, nil, nil
+		})
+
+		assert.Len(results, len(toChannels))
+		for i, result := range results {
+			assert.Equal(toChannels[i] == "channel3", result.failed(), toChannels[i])
+		}
+	})
+	t.Run("hung channel is reported as failed", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		defer func(timeout time.Duration) { shareTimeout = timeout }(shareTimeout)
+		shareTimeout = 50 * time.Millisecond
+		hung := make(chan struct{})
+		defer close(hung)
+
+		results := p.shareToChannels(context.Background(), p.getLocalizer("user_id"), []string{"channel1", "hung_channel"}, func(_ context.Context, toChannel string) (*string, *model.SubmitDialogResponse, error) {
+			if toChannel == "hung_channel" {
+				<-hung
+			}
+			return nil, nil, nil
+		})
+
+		assert.False(results[0].failed())
+		assert.True(results[1].failed())
+		assert.NotNil(results[1].err)
+	})
+}
+
+func TestSummarizeShares(t *testing.T) {
+	t.Run("single channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		msg, response, err := p.summarizeShares(p.getLocalizer("user_id"), []string{"channel1"}, []*shareResult{{msg: toPtr("failed")}})
+
+		assert.Equal("failed", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("multiple channels with failures", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "channel2").Return(&model.Channel{Id: "channel2", Name: "off-topic"}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, response, err := p.summarizeShares(p.getLocalizer("user_id"), []string{"channel1", "channel2", "channel3"}, []*shareResult{
+			{},
+			{msg: toPtr("Something went wrong."), err: errors.New("failed")},
+			{},
+		})
+
+		assert.Equal("Shared to 2 of 3 channels (1 failed).\n* ~off-topic: Something went wrong.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+	t.Run("group failures by reason", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "secret_id").Return(&model.Channel{Id: "secret_id", Name: "secret"}, nil)
+		api.On("GetChannel", "private_id").Return(&model.Channel{Id: "private_id", Name: "private"}, nil)
+		api.On("GetChannel", "archived_id").Return(&model.Channel{Id: "archived_id", Name: "archived"}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, response, err := p.summarizeShares(p.getLocalizer("user_id"), []string{"secret_id", "town_square_id", "archived_id", "private_id"}, []*shareResult{
+			{msg: toPtr("You don't have permission.")},
+			{},
+			{err: errors.New("failed to create post")},
+			{msg: toPtr("You don't have permission.")},
+		})
+
+		assert.Equal("Shared to 1 of 4 channels (3 failed).\n"+
+			"* ~secret, ~private: You don't have permission.\n"+
+			"* ~archived: Something went wrong. Please try again later.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+	})
+}
+
+func TestSummarizeReactions(t *testing.T) {
+	for _, test := range []struct {
+		Name      string
+		Reactions []*model.Reaction
+		Expected  string
+	}{
+		{
+			Name:      "no reactions",
+			Reactions: []*model.Reaction{},
+			Expected:  "",
+		},
+		{
+			Name: "aggregated by emoji",
+			Reactions: []*model.Reaction{
+				{UserId: "user1", EmojiName: "heart"},
+				{UserId: "user1", EmojiName: "+1"},
+				{UserId: "user2", EmojiName: "+1"},
+				{UserId: "user3", EmojiName: "+1"},
+				{UserId: "user2", EmojiName: "heart"},
+				{UserId: "user3", EmojiName: "tada"},
+			},
+			Expected: ":+1: 3 :heart: 2 :tada: 1",
+		},
+		{
+			Name: "same counts keep the order of first reactions",
+			Reactions: []*model.Reaction{
+				{UserId: "user1", EmojiName: "tada"},
+				{UserId: "user1", EmojiName: "eyes"},
+				{UserId: "user1", EmojiName: "+1"},
+			},
+			Expected: ":tada: 1 :eyes: 1 :+1: 1",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, summarizeReactions(test.Reactions))
+		})
+	}
+}
+
+func TestGetReactionSummary(t *testing.T) {
+	t.Run("failed to get reactions", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetReactions", "post_id").Return(nil, &model.AppError{Message: "failed"})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		assert.Equal(t, "", p.getReactionSummary("post_id"))
+	})
+}
+
+func TestCheckPostToChannel(t *testing.T) {
+	T := func(id string, args ...interface{}) string { return id }
+	publicChannel := &model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}
+	privateChannel := &model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_PRIVATE}
+
+	t.Run("member", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+
+		assert.Nil(t, p.checkPostToChannel(T, "user_id", "to_channel_id"))
+		api.AssertNotCalled(t, "AddChannelMember", mock.Anything, mock.Anything)
+	})
+	t.Run("not a member without auto-join", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+
+		assert.Equal(t, "share.no_permission", *p.checkPostToChannel(T, "user_id", "to_channel_id"))
+		api.AssertNotCalled(t, "AddChannelMember", mock.Anything, mock.Anything)
+	})
+	t.Run("join public channel", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(publicChannel, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(true)
+		api.On("AddChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+
+		assert.Nil(t, p.checkPostToChannel(T, "user_id", "to_channel_id"))
+	})
+	t.Run("private channel is not joined", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(privateChannel, nil)
+
+		assert.Equal(t, "share.no_permission", *p.checkPostToChannel(T, "user_id", "to_channel_id"))
+		api.AssertNotCalled(t, "AddChannelMember", mock.Anything, mock.Anything)
+	})
+	t.Run("no permission to join", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(publicChannel, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		assert.Equal(t, "share.no_permission", *p.checkPostToChannel(T, "user_id", "to_channel_id"))
+		api.AssertNotCalled(t, "AddChannelMember", mock.Anything, mock.Anything)
+	})
+	t.Run("failed to join", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(publicChannel, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(true)
+		api.On("AddChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{Message: "failed"})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 7)...).Return()
+
+		assert.Equal(t, "share.no_permission", *p.checkPostToChannel(T, "user_id", "to_channel_id"))
+	})
+	t.Run("join open team", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true, AutoJoinOpenTeams: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(publicChannel, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(false).Once()
+		api.On("GetTeamMember", "team_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team", AllowOpenInvite: true}, nil)
+		api.On("HasPermissionTo", "user_id", model.PERMISSION_JOIN_PUBLIC_TEAMS).Return(true)
+		api.On("CreateTeamMember", "team_id", "user_id").Return(&model.TeamMember{}, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(true).Once()
+		api.On("AddChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+
+		assert.Nil(t, p.checkPostToChannel(T, "user_id", "to_channel_id"))
+	})
+	t.Run("invite-only team is not joined", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true, AutoJoinOpenTeams: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(publicChannel, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(false)
+		api.On("GetTeamMember", "team_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team", AllowOpenInvite: false}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		assert.Equal(t, "share.team_invite_only", *p.checkPostToChannel(T, "user_id", "to_channel_id"))
+		api.AssertNotCalled(t, "CreateTeamMember", mock.Anything, mock.Anything)
+		api.AssertNotCalled(t, "AddChannelMember", mock.Anything, mock.Anything)
+	})
+	t.Run("team member without permission to join", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true, AutoJoinOpenTeams: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(publicChannel, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(false)
+		api.On("GetTeamMember", "team_id", "user_id").Return(&model.TeamMember{}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		assert.Equal(t, "share.no_permission", *p.checkPostToChannel(T, "user_id", "to_channel_id"))
+		api.AssertNotCalled(t, "CreateTeamMember", mock.Anything, mock.Anything)
+	})
+	t.Run("no permission to join open teams", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{AutoJoinPublicChannels: true, AutoJoinOpenTeams: true})
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("GetChannel", "to_channel_id").Return(publicChannel, nil)
+		api.On("HasPermissionToTeam", "user_id", "team_id", model.PERMISSION_JOIN_PUBLIC_CHANNELS).Return(false)
+		api.On("GetTeamMember", "team_id", "user_id").Return(&model.TeamMember{DeleteAt: 1000}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team", AllowOpenInvite: true}, nil)
+		api.On("HasPermissionTo", "user_id", model.PERMISSION_JOIN_PUBLIC_TEAMS).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		assert.Equal(t, "share.no_permission", *p.checkPostToChannel(T, "user_id", "to_channel_id"))
+		api.AssertNotCalled(t, "CreateTeamMember", mock.Anything, mock.Anything)
+	})
+}
+
+func TestVisibilityWarning(t *testing.T) {
+	T := setupTestPlugin(&plugintest.API{}).getLocalizer("user_id")
+	public := &model.Channel{Type: model.CHANNEL_OPEN}
+	private := &model.Channel{Type: model.CHANNEL_PRIVATE}
+	direct := &model.Channel{Type: model.CHANNEL_DIRECT}
+
+	t.Run("same visibility", func(t *testing.T) {
+		assert.Equal(t, "", visibilityWarning(T, public, public))
+		assert.Equal(t, "", visibilityWarning(T, private, direct))
+	})
+	t.Run("public to private", func(t *testing.T) {
+		assert.Equal(t, "\nNote: You're sharing from a public channel into a private channel.", visibilityWarning(T, public, private))
+		assert.Equal(t, "\nNote: You're sharing from a public channel into a private channel.", visibilityWarning(T, public, direct))
+	})
+	t.Run("private to public", func(t *testing.T) {
+		assert.Equal(t, "\nNote: You're sharing from a private channel into a public channel, where more people can read it.", visibilityWarning(T, private, public))
+	})
+}
+
+func TestParseAdditionalText(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "  note\n"})
+		assert.Equal(t, "note\n\n", text)
+		assert.Nil(t, response)
+	})
+	t.Run("empty", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{})
+		assert.Equal(t, "", text)
+		assert.Nil(t, response)
+	})
+	t.Run("whitespace only", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: " \n\t "})
+		assert.Equal(t, "", text)
+		assert.Nil(t, response)
+	})
+	t.Run("over the limit", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		p.setConfiguration(&configuration{MaxAdditionalTextLength: 5})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "あいうえおか"})
+		assert.Equal(t, "", text)
+		assert.Equal(t, map[string]string{additionalTextKey: "Additional text must be 5 characters or less."}, response.Errors)
+	})
+	t.Run("at the limit", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		p.setConfiguration(&configuration{MaxAdditionalTextLength: 5})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "あいうえお"})
+		assert.Equal(t, "あいうえお\n\n", text)
+		assert.Nil(t, response)
+	})
+}
+
+func TestParseAdditionalTextBroadcastMention(t *testing.T) {
+	const warning = "The additional text notifies everyone in the channel with @all, @channel or @here. Check \"Notify everyone\" to share it anyway, or remove the mention."
+
+	t.Run("confirm by default", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "FYI @channel"})
+		assert.Equal(t, "", text)
+		assert.Equal(t, map[string]string{additionalTextKey: warning}, response.Errors)
+	})
+	t.Run("confirmed", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		p.setConfiguration(&configuration{BroadcastMentionPolicy: broadcastMentionConfirm})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "FYI @channel", confirmMentionKey: true})
+		assert.Equal(t, "FYI @channel\n\n", text)
+		assert.Nil(t, response)
+	})
+	t.Run("strip", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		p.setConfiguration(&configuration{BroadcastMentionPolicy: broadcastMentionStrip})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "@channel @ALL and @here. Not @channels or me@here.com"})
+		assert.Equal(t, "channel ALL and here. Not @channels or me@here.com\n\n", text)
+		assert.Nil(t, response)
+	})
+	t.Run("allow", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		p.setConfiguration(&configuration{BroadcastMentionPolicy: broadcastMentionAllow})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "FYI @channel"})
+		assert.Equal(t, "FYI @channel\n\n", text)
+		assert.Nil(t, response)
+	})
+	t.Run("no broadcast mention", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		text, response := p.parseAdditionalText(p.getLocalizer("user_id"), map[string]interface{}{additionalTextKey: "FYI @channel-admin, @here.team and @alice"})
+		assert.Equal(t, "FYI @channel-admin, @here.team and @alice\n\n", text)
+		assert.Nil(t, response)
+	})
+}
+
+func TestParseRenderMode(t *testing.T) {
+	T := setupTestPlugin(&plugintest.API{}).getLocalizer("user_id")
+	for _, test := range []struct {
+		Name     string
+		Value    interface{}
+		Expected string
+		Valid    bool
+	}{
+		{Name: "default", Value: nil, Expected: renderModePlain, Valid: true},
+		{Name: "empty", Value: "", Expected: renderModePlain, Valid: true},
+		{Name: "quote", Value: "quote", Expected: renderModeQuote, Valid: true},
+		{Name: "card", Value: "card", Expected: renderModeCard, Valid: true},
+		{Name: "unknown", Value: "fancy", Expected: "", Valid: false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			submission := map[string]interface{}{}
+			if test.Value != nil {
+				submission[renderModeKey] = test.Value
+			}
+			mode, response := parseRenderMode(T, submission)
+			assert.Equal(t, test.Expected, mode)
+			assert.Equal(t, test.Valid, response == nil)
+		})
+	}
+}
+
+func TestParseTextPosition(t *testing.T) {
+	T := setupTestPlugin(&plugintest.API{}).getLocalizer("user_id")
+	for _, test := range []struct {
+		Name     string
+		Value    interface{}
+		Expected string
+		Valid    bool
+	}{
+		{Name: "default", Value: nil, Expected: textPositionAbove, Valid: true},
+		{Name: "above", Value: "above", Expected: textPositionAbove, Valid: true},
+		{Name: "below", Value: "below", Expected: textPositionBelow, Valid: true},
+		{Name: "unknown", Value: "middle", Expected: "", Valid: false},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			submission := map[string]interface{}{}
+			if test.Value != nil {
+				submission[textPositionKey] = test.Value
+			}
+			position, response := parseTextPosition(T, submission)
+			assert.Equal(t, test.Expected, position)
+			assert.Equal(t, test.Valid, response == nil)
+		})
+	}
+}
+
+func TestPlaceAdditionalText(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Message  string
+		Text     string
+		Position string
+		Expected string
+	}{
+		{Name: "above", Message: "message", Text: "Hi\n\n", Position: textPositionAbove, Expected: "Hi\n\nmessage"},
+		{Name: "below", Message: "message", Text: "Hi\n\n", Position: textPositionBelow, Expected: "message\n\nHi"},
+		{Name: "below empty message", Message: "", Text: "Hi\n\n", Position: textPositionBelow, Expected: "Hi"},
+		{Name: "below without text", Message: "message", Text: "", Position: textPositionBelow, Expected: "message"},
+		{Name: "unknown position", Message: "message", Text: "Hi\n\n", Position: "", Expected: "Hi\n\nmessage"},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, placeAdditionalText(test.Message, test.Text, test.Position))
+		})
+	}
+}
+
+func TestAppendFooter(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Message  string
+		Footer   string
+		Expected string
+	}{
+		{Name: "configured", Message: "message", Footer: "Posted via SharePost", Expected: "message\n\n---\nPosted via SharePost"},
+		{Name: "empty", Message: "message", Footer: "", Expected: "message"},
+		{Name: "whitespace only", Message: "message", Footer: " \n", Expected: "message"},
+		{Name: "empty message", Message: "", Footer: "Posted via SharePost", Expected: "Posted via SharePost"},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, appendFooter(test.Message, test.Footer))
+		})
+	}
+}
+
+func TestShareLogFields(t *testing.T) {
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", DisplayName: "Town Square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil).Once()
+	api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil).Once()
+	api.On("GetChannel", "dm_channel_id").Return(&model.Channel{Id: "dm_channel_id", DisplayName: "", Type: model.CHANNEL_DIRECT}, nil).Once()
+	api.On("GetChannel", "deleted_channel_id").Return(nil, &model.AppError{Message: "not found", StatusCode: http.StatusNotFound})
+
+	assert.Equal(t, []interface{}{
+		"source_channel_id", "channel_id", "source_channel_name", "Town Square", "source_team_name", "team",
+		"destination_channel_id", "dm_channel_id", "destination_channel_name", "",
+		"post_id", "post_id",
+	}, p.shareLogFields("channel_id", "dm_channel_id", "post_id", "post_id"))
+	// Names are looked up once and cached
+	assert.Equal(t, []interface{}{
+		"source_channel_id", "channel_id", "source_channel_name", "Town Square", "source_team_name", "team",
+		"destination_channel_id", "deleted_channel_id",
+	}, p.shareLogFields("channel_id", "deleted_channel_id"))
+}
+
+func TestSplitMessage(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Message  string
+		Limit    int
+		Expected []string
+	}{
+		{Name: "within limit", Message: "short message", Limit: 20, Expected: []string{"short message"}},
+		{Name: "split at line break", Message: "first line\nsecond line", Limit: 15, Expected: []string{"first line\n", "second line"}},
+		{Name: "split at space", Message: "some words here", Limit: 12, Expected: []string{"some words ", "here"}},
+		{Name: "no boundary", Message: "abcdefghij", Limit: 4, Expected: []string{"abcd", "efgh", "ij"}},
+		{Name: "multibyte", Message: "あいうえおかきくけこ", Limit: 6, Expected: []string{"あいうえおか", "きくけこ"}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, splitMessage(test.Message, test.Limit))
+		})
+	}
+}
+
+func TestSplitMessageReserving(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Message  string
+		Limit    int
+		Reserved int
+		Expected []string
+	}{
+		{Name: "within limit", Message: "short", Limit: 10, Reserved: 5, Expected: []string{"short"}},
+		{Name: "over limit by reserved", Message: "abcdefgh", Limit: 10, Reserved: 5, Expected: []string{"abcde", "fgh"}},
+		{Name: "rest split by limit", Message: "abcdefghijklmnop", Limit: 6, Reserved: 3, Expected: []string{"abc", "defghi", "jklmno", "p"}},
+		{Name: "reserved exceeds limit", Message: "abc", Limit: 4, Reserved: 10, Expected: []string{"a", "bc"}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, splitMessageReserving(test.Message, test.Limit, test.Reserved))
+		})
+	}
+}
+
+func TestFormatPermalinkLabel(t *testing.T) {
+	channel := &model.Channel{Id: "channel_id", Name: "town-square", DisplayName: "Town Square", Type: model.CHANNEL_OPEN}
+	T := setupTestPlugin(&plugintest.API{}).getServerLocalizer()
+
+	for _, test := range []struct {
+		Name     string
+		Style    string
+		Message  string
+		Channel  *model.Channel
+		Expected string
+	}{
+		{
+			Name:     "original",
+			Style:    permalinkLabelOriginal,
+			Message:  "message",
+			Expected: "original post",
+		},
+		{
+			Name:     "shared post",
+			Style:    permalinkLabelSharedPost,
+			Message:  "message",
+			Expected: "Shared post",
+		},
+		{
+			Name:     "channel and message",
+			Style:    permalinkLabelChannelMessage,
+			Message:  "Release notes\nDetails follow",
+			Expected: "Town Square: Release notes",
+		},
+		{
+			Name:     "long message is truncated",
+			Style:    permalinkLabelChannelMessage,
+			Message:  strings.Repeat("a", 50),
+			Expected: "Town Square: " + strings.Repeat("a", maxPermalinkLabelExcerptLength) + "…",
+		},
+		{
+			Name:     "multibyte message is truncated by characters",
+			Style:    permalinkLabelChannelMessage,
+			Message:  strings.Repeat("あ", 50),
+			Expected: "Town Square: " + strings.Repeat("あ", maxPermalinkLabelExcerptLength) + "…",
+		},
+		{
+			Name:     "markdown characters are escaped",
+			Style:    permalinkLabelChannelMessage,
+			Message:  "**Bold** [link](http://example.com) `code` ~channel <b>_",
+			Expected: "Town Square: \\*\\*Bold\\*\\* \\[link\\]\\(http://example.com\\) \\`cod…",
+		},
+		{
+			Name:     "backslashes are escaped",
+			Style:    permalinkLabelChannelMessage,
+			Message:  `C:\path\]`,
+			Expected: `Town Square: C:\\path\\\]`,
+		},
+		{
+			Name:     "channel without display name",
+			Style:    permalinkLabelChannelMessage,
+			Message:  "message",
+			Channel:  &model.Channel{Id: "channel_id", Name: "my_channel"},
+			Expected: `my\_channel: message`,
+		},
+		{
+			Name:     "message without text",
+			Style:    permalinkLabelChannelMessage,
+			Message:  "  ",
+			Expected: "Town Square",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			c := channel
+			if test.Channel != nil {
+				c = test.Channel
+			}
+			post := &model.Post{Id: "post_id", Message: test.Message}
+			assert.Equal(t, test.Expected, formatPermalinkLabel(T, test.Style, "original post", post, c))
+		})
+	}
+}
+
+func TestFormatQuotedShare(t *testing.T) {
+	channel := &model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}
+	team := &model.Team{Id: "team_id", Name: "team"}
+	postedAt := time.Unix(0, 0).Format("on Mon 2 Jan 2006 at 15:04:05 MST")
+
+	t.Run("multi-line message", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "line1\nline2"}
+		assert.Equal(t, strings.Join([]string{
+			"> **@author** posted in ~town-square " + postedAt,
+			">",
+			"> line1",
+			"> line2",
+			">",
+			"> ([original post](http://localhost:8065/team/pl/post_id))",
+		}, "\n"), p.formatQuotedShare(post, channel, team))
+	})
+	t.Run("truncate long message", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: strings.Repeat("あ", maxQuotedMessageLength+1)}
+		assert.Equal(t, strings.Join([]string{
+			"> **@author** posted in ~town-square " + postedAt,
+			">",
+			"> " + strings.Repeat("あ", maxQuotedMessageLength) + "…",
+			">",
+			"> ([view full post](http://localhost:8065/_redirect/pl/post_id))",
+		}, "\n"), p.formatQuotedShare(post, channel, nil))
+	})
+	t.Run("labeled link", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{PermalinkLabel: permalinkLabelSharedPost})
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		assert.True(t, strings.HasSuffix(p.formatQuotedShare(post, channel, team), "\n> ([Shared post](http://localhost:8065/team/pl/post_id))"))
+	})
+	t.Run("default locale of the server", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.i18n, _ = loadI18nBundle(filepath.Join("..", "..", "assets", "i18n"))
+		p.ServerConfig.LocalizationSettings.DefaultServerLocale = model.NewString("ja")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		assert.Equal(t, strings.Join([]string{
+			"> **@author** が ~town-square に投稿 " + time.Unix(0, 0).Format("2006/01/02 15:04:05 MST"),
+			">",
+			"> message",
+			">",
+			"> ([元の投稿](http://localhost:8065/team/pl/post_id))",
+		}, "\n"), p.formatQuotedShare(post, channel, team))
+	})
+	t.Run("unknown author", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetUser", "author_id").Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		assert.Contains(t, p.formatQuotedShare(post, channel, team), "> **Someone** posted in ~town-square")
+	})
+	t.Run("deleted author", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetUser", "author_id").Return(nil, &model.AppError{StatusCode: http.StatusNotFound})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		assert.Contains(t, p.formatQuotedShare(post, channel, team), "> **a deleted user** posted in ~town-square")
+	})
+	t.Run("deactivated author", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author", DeleteAt: 1000}, nil)
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		assert.Contains(t, p.formatQuotedShare(post, channel, team), "> **a deleted user** posted in ~town-square")
+	})
+	t.Run("name display setting", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.ServerConfig.TeamSettings.TeammateNameDisplay = toPtr(model.SHOW_FULLNAME)
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author", FirstName: "Alice", LastName: "Smith"}, nil)
+
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		assert.Contains(t, p.formatQuotedShare(post, channel, team), "> **Alice Smith** posted in ~town-square")
+	})
+}
+
+func TestParsePostLink(t *testing.T) {
+	t.Run("permalink", func(t *testing.T) {
+		teamName, postID, err := parsePostLink("http://localhost:8065/team/pl/post_id")
+		assert.Nil(t, err)
+		assert.Equal(t, "team", teamName)
+		assert.Equal(t, "post_id", postID)
+	})
+	t.Run("trailing slash", func(t *testing.T) {
+		teamName, postID, err := parsePostLink("http://localhost:8065/team/pl/post_id/")
+		assert.Nil(t, err)
+		assert.Equal(t, "team", teamName)
+		assert.Equal(t, "post_id", postID)
+	})
+	t.Run("query string", func(t *testing.T) {
+		teamName, postID, err := parsePostLink("http://localhost:8065/team/pl/post_id?foo=bar#baz")
+		assert.Nil(t, err)
+		assert.Equal(t, "team", teamName)
+		assert.Equal(t, "post_id", postID)
+	})
+	t.Run("site url with subpath", func(t *testing.T) {
+		teamName, postID, err := parsePostLink("https://example.com/mattermost/team/pl/post_id")
+		assert.Nil(t, err)
+		assert.Equal(t, "team", teamName)
+		assert.Equal(t, "post_id", postID)
+	})
+	t.Run("DM-style link", func(t *testing.T) {
+		teamName, postID, err := parsePostLink("http://localhost:8065/_redirect/pl/post_id")
+		assert.Nil(t, err)
+		assert.Equal(t, "", teamName)
+		assert.Equal(t, "post_id", postID)
+	})
+	t.Run("inverse of makePostLink", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		teamName, postID, err := parsePostLink(p.makePostLink("team", "post_id"))
+		assert.Nil(t, err)
+		assert.Equal(t, "team", teamName)
+		assert.Equal(t, "post_id", postID)
+	})
+	t.Run("not a permalink", func(t *testing.T) {
+		for _, link := range []string{"post_id", "http://localhost:8065/team/channels/town-square", "http://localhost:8065/pl/post_id", "http://localhost:8065/team/pl/"} {
+			_, _, err := parsePostLink(link)
+			assert.NotNil(t, err, link)
+		}
+	})
+}
+
+func TestIsInThread(t *testing.T) {
+	root := &model.Post{Id: "root_id", ChannelId: "channel_id"}
+	reply := &model.Post{Id: "reply_id", ChannelId: "channel_id", RootId: "root_id"}
+
+	t.Run("standalone post", func(t *testing.T) {
+		postList := model.NewPostList()
+		postList.AddPost(root)
+
+		assert.False(t, isInThread(postList, root))
+	})
+	t.Run("root post with a reply", func(t *testing.T) {
+		postList := model.NewPostList()
+		postList.AddPost(root)
+		postList.AddPost(reply)
+
+		assert.True(t, isInThread(postList, root))
+	})
+	t.Run("reply", func(t *testing.T) {
+		postList := model.NewPostList()
+		postList.AddPost(root)
+		postList.AddPost(reply)
+
+		assert.True(t, isInThread(postList, reply))
+	})
+}
+
+// mockSourcePost mocks the post to share in the channel of the team of the request
+func mockSourcePost(api *plugintest.API) {
+	api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+	api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+}
+
+func mockMovePost(api *plugintest.API, oldPost *model.Post, replies ...*model.Post) {
+	postList := model.NewPostList()
+	postList.AddPost(oldPost)
+	postList.AddOrder(oldPost.Id)
+	for _, reply := range replies {
+		postList.AddPost(reply)
+		postList.AddOrder(reply.Id)
+		api.On("GetPost", reply.Id).Return(reply, nil)
+		api.On("DeletePost", reply.Id).Return(nil)
+	}
+	api.On("GetPostThread", oldPost.Id).Return(postList, nil)
+	api.On("GetPost", oldPost.Id).Return(oldPost, nil)
+	api.On("GetChannelMember", oldPost.ChannelId, "user_id").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionToChannel", "user_id", oldPost.ChannelId, model.PERMISSION_DELETE_OTHERS_POSTS).Return(true)
+	api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+	api.On("GetTeamMember", "team_id", "user_id").Return(&model.TeamMember{}, nil)
+	api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+	api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+	api.On("CopyFileInfos", "user_id", mock.Anything).Return([]string{}, nil)
+	api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+	api.On("LogDebug", GetMockArgumentsWithType("string", 5)...).Return()
+	api.On("DeletePost", oldPost.Id).Return(nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	mockAuditIndex(api)
+	api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+	api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+}
+
+func TestMovePost(t *testing.T) {
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+	}
+
+	t.Run("system message", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Type: model.POST_JOIN_CHANNEL})
+		postList.AddOrder("post_id")
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetPost", "post_id").Return(postList.Posts["post_id"], nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("System messages can't be shared.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("destination channel no longer exists", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(nil, model.NewAppError("GetChannel", "app.channel.get.existing.app_error", nil, "", http.StatusNotFound))
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("The selected channel no longer exists.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("destination channel is archived", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN, DeleteAt: 1000}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("The selected channel is archived and can't receive posts.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetPostThread", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("original post no longer exists", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPostThread", "post_id").Return(nil, model.NewAppError("GetPostThread", "app.post.get.app_error", nil, "", http.StatusNotFound))
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("The original post no longer exists.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("original post is soft-deleted", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		deleted := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", DeleteAt: 1000}
+		postList := model.NewPostList()
+		postList.AddPost(deleted)
+		postList.AddOrder("post_id")
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetPost", "post_id").Return(deleted, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("The original post no longer exists.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("root post with a reply", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id"})
+		postList.AddPost(&model.Post{Id: "reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "post_id"})
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetPost", "post_id").Return(postList.Posts["post_id"], nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_OTHERS_POSTS).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Contains(*msg, "thread")
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("preserve reactions", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		mockMovePost(api, &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{
+			{UserId: "user1", PostId: "post_id", EmojiName: "+1"},
+			{UserId: "user2", PostId: "post_id", EmojiName: "smile"},
+		}, nil)
+		var reactions []*model.Reaction
+		api.On("AddReaction", mock.AnythingOfType("*model.Reaction")).Return(func(reaction *model.Reaction) *model.Reaction {
+			reactions = append(reactions, reaction)
+			return reaction
+		}, nil)
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		assert.Equal([]*model.Reaction{
+			{UserId: "user1", PostId: "moved_post_id", EmojiName: "+1"},
+			{UserId: "user2", PostId: "moved_post_id", EmojiName: "smile"},
+		}, reactions)
+	})
+	t.Run("move to channel in the same team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "SendEphemeralPost", "user_id", mock.MatchedBy(func(post *model.Post) bool {
+			return strings.Contains(post.Message, "http://localhost:8065/team/pl/moved_post_id")
+		}))
+	})
+	t.Run("split oversized message", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMove: true, MovedPostFooter: "Moved by SharePost"})
+
+		message := strings.Repeat("long message ", model.POST_MESSAGE_MAX_RUNES_V2/len("long message ")+1)
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: message, CreateAt: 1000}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		var created []*model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			created = append(created, post.Clone())
+			post.Id = fmt.Sprintf("created_post_id_%d", len(created))
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		assert.Len(created, 2)
+		assert.True(utf8.RuneCountInString(created[0].Message) <= model.POST_MESSAGE_MAX_RUNES_V2)
+		assert.Equal(appendFooter(message, "Moved by SharePost"), created[0].Message+created[1].Message)
+		assert.Equal("created_post_id_1", created[1].RootId)
+		assert.Equal(int64(1001), created[1].CreateAt)
+		assert.Equal("created_post_id_1", created[1].GetProp(postPropsKeyContinuationOf))
+		assert.Equal("channel_id", created[1].GetProp(postPropsKeyMovedFromChannelID))
+		api.AssertCalled(t, "DeletePost", "post_id")
+	})
+	t.Run("split message oversized by additional text", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMove: true, MaxPostSize: 20})
+
+		// The message alone is within the limit, but not with the additional text placed by MessageWillBePosted
+		message := "first line\nsecond"
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: message, CreateAt: 1000}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		var created []*model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			addAdditionalText(post)
+			created = append(created, post.Clone())
+			post.Id = fmt.Sprintf("created_post_id_%d", len(created))
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "note\n\n", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		assert.Len(created, 2)
+		assert.Equal("note\n\nfirst line\n", created[0].Message)
+		assert.Equal("second", created[1].Message)
+		for _, post := range created {
+			assert.True(utf8.RuneCountInString(post.Message) <= 20)
+		}
+	})
+	t.Run("rollback when continuation of oversized message fails", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMove: true, MaxPostSize: 10})
+
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil).Once()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, &model.AppError{Message: "failed", StatusCode: http.StatusBadRequest})
+		mockMovePost(api, &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "a message over the limit"})
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+		api.On("DeletePost", "moved_post_id").Return(nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.NotNil(msg)
+		assert.NotNil(err)
+		api.AssertCalled(t, "DeletePost", "moved_post_id")
+		api.AssertNotCalled(t, "DeletePost", "post_id")
+	})
+	t.Run("moved recently", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMove: true, MoveCooldownMinutes: 10})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		// Props of posts read from the server are decoded from JSON
+		oldPost.AddProp(postPropsKeyMovedAt, float64(model.GetMillis()-int64(time.Minute/time.Millisecond)))
+		mockMovePost(api, oldPost)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("This post was moved recently. It can be moved again 10 minutes after the last move.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("moved before the cooldown", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMove: true, MoveCooldownMinutes: 10})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		oldPost.AddProp(postPropsKeyMovedAt, float64(model.GetMillis()-int64(time.Hour/time.Millisecond)))
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "DeletePost", "post_id")
+	})
+	t.Run("move to channel in other team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetChannel", "other_team_channel_id").Return(&model.Channel{Id: "other_team_channel_id", Name: "off-topic", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "other_team_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "other_team_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeamMember", "other_team_id", "user_id").Return(&model.TeamMember{}, nil)
+		api.On("GetTeam", "other_team_id").Return(&model.Team{Id: "other_team_id", Name: "other-team"}, nil)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("other_team_channel_id", post.ChannelId)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "other_team_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "SendEphemeralPost", "user_id", mock.MatchedBy(func(post *model.Post) bool {
+			return strings.Contains(post.Message, "http://localhost:8065/other-team/pl/moved_post_id")
+		}))
+		api.AssertNotCalled(t, "GetTeam", "team_id")
+	})
+	t.Run("failed to get destination team", func(t *testing.T) {
+		for _, test := range []struct {
+			Name       string
+			StatusCode int
+			Expected   string
+			HasError   bool
+		}{
+			{Name: "team not found", StatusCode: http.StatusNotFound, Expected: "The team of the selected channel is no longer accessible.", HasError: false},
+			{Name: "server error", StatusCode: http.StatusInternalServerError, Expected: "Something went wrong. Please try again later.", HasError: true},
+		} {
+			t.Run(test.Name, func(t *testing.T) {
+				assert := assert.New(t)
+				api := &plugintest.API{}
+				p := setupTestPlugin(api)
+
+				oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+				mockMovePost(api, oldPost)
+				api.On("GetChannel", "other_team_channel_id").Return(&model.Channel{Id: "other_team_channel_id", Name: "off-topic", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+				api.On("GetChannelMember", "other_team_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+				api.On("HasPermissionToChannel", "user_id", "other_team_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+				api.On("GetTeamMember", "other_team_id", "user_id").Return(&model.TeamMember{}, nil)
+				api.On("GetTeam", "other_team_id").Return(nil, &model.AppError{Message: "failed", StatusCode: test.StatusCode})
+				api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+				api.On("LogError", GetMockArgumentsWithType("string", 5)...).Return()
+
+				msg, _, err := p.movePost(context.Background(), request, "other_team_channel_id", "", "", false, false, false, false)
+
+				assert.Equal(test.Expected, *msg)
+				assert.Equal(test.HasError, err != nil)
+				api.AssertNotCalled(t, "CreatePost", mock.Anything)
+				api.AssertNotCalled(t, "DeletePost", mock.Anything)
+			})
+		}
+	})
+	t.Run("move to team the user is not in", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetChannel", "other_team_channel_id").Return(&model.Channel{Id: "other_team_channel_id", Name: "off-topic", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "other_team_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "other_team_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeamMember", "other_team_id", "user_id").Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "other_team_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("You can't move posts to a team you're not in.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("move to team the user left", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetChannel", "other_team_channel_id").Return(&model.Channel{Id: "other_team_channel_id", Name: "off-topic", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "other_team_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "other_team_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeamMember", "other_team_id", "user_id").Return(&model.TeamMember{DeleteAt: 1000}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "other_team_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("You can't move posts to a team you're not in.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("private channel the user is not a member of", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetChannel", "private_channel_id").Return(&model.Channel{Id: "private_channel_id", Name: "private", TeamId: "team_id", Type: model.CHANNEL_PRIVATE}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "private_channel_id", "user_id").Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 15)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "private_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("You don't have permission to post in the selected channel.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("moderated channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetChannel", "moderated_channel_id").Return(&model.Channel{Id: "moderated_channel_id", Name: "announcements", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "moderated_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "moderated_channel_id", model.PERMISSION_CREATE_POST).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 15)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "moderated_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("Posting is restricted in the selected channel by its moderation settings.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("post older than retention period", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnforceRetentionOnMove: true})
+		p.ServerConfig.DataRetentionSettings.EnableMessageDeletion = model.NewBool(true)
+		p.ServerConfig.DataRetentionSettings.MessageRetentionDays = model.NewInt(30)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: model.GetMillis() - 31*24*60*60*1000}
+		mockMovePost(api, oldPost)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("This post is older than the message retention period of the server (30 days), and it can't be moved because it's pending deletion.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("post within retention period", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnforceRetentionOnMove: true})
+		p.ServerConfig.DataRetentionSettings.EnableMessageDeletion = model.NewBool(true)
+		p.ServerConfig.DataRetentionSettings.MessageRetentionDays = model.NewInt(30)
+
+		createAt := model.GetMillis() - 29*24*60*60*1000
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: createAt}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal(createAt, post.CreateAt)
+			assert.Equal(createAt, post.GetProp(postPropsKeyOriginalCreateAt))
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("thread size limit", func(t *testing.T) {
+		rootPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: 1}
+		replies := []*model.Post{
+			{Id: "reply_id_1", UserId: "author_id", ChannelId: "channel_id", RootId: "post_id", Message: "reply", CreateAt: 2},
+			{Id: "reply_id_2", UserId: "author_id", ChannelId: "channel_id", RootId: "post_id", Message: "reply", CreateAt: 3},
+		}
+
+		t.Run("thread exceeding the limit", func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			p := setupTestPlugin(api)
+			p.setConfiguration(&configuration{MaxThreadMoveSize: 2})
+			mockMovePost(api, rootPost, replies...)
+			api.On("LogWarn", "the thread is too large to move.", "post_id", "post_id", "posts", 3, "limit", 2).Return()
+
+			msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", true, false, false, false)
+
+			assert.Equal("This thread is too large to move (3 posts, limit 2).", *msg)
+			assert.Nil(err)
+			api.AssertNotCalled(t, "CreatePost", mock.Anything)
+			api.AssertNotCalled(t, "DeletePost", mock.Anything)
+		})
+		t.Run("thread at the limit", func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			defer api.AssertExpectations(t)
+			p := setupTestPlugin(api)
+			p.setConfiguration(&configuration{MaxThreadMoveSize: 3})
+			mockMovePost(api, rootPost, replies...)
+			api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				post.Id = model.NewId()
+				return post
+			}, nil)
+
+			msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", true, false, false, false)
+
+			assert.Nil(msg)
+			assert.Nil(err)
+			api.AssertNumberOfCalls(t, "CreatePost", 3)
+		})
+	})
+	t.Run("roll back when the original post can't be deleted", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableRedirectNote: true, UndoMoveWindowMinutes: 5})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		// Registered before mockMovePost so that this expectation takes precedence
+		api.On("DeletePost", "post_id").Return(&model.AppError{Message: "failed"})
+		mockMovePost(api, oldPost)
+		api.On("DeletePost", "moved_post_id").Return(nil)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogError", GetMockArgumentsWithType("string", 17)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Equal("Something went wrong. Please try again later.", *msg)
+		assert.NotNil(err)
+		api.AssertCalled(t, "DeletePost", "moved_post_id")
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertNotCalled(t, "KVSetWithExpiry", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("preserve pinned status", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", IsPinned: true}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.True(post.IsPinned)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("pin in destination", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.True(post.IsPinned)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, true)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+	t.Run("no permission to pin in destination", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+		mockMovePost(api, oldPost)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, true)
+
+		assert.Equal("You don't have permission to pin posts in the selected channel, so the post is not moved.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("post as bot", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_id"
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		reply := &model.Post{Id: "reply_id", UserId: "replier_id", ChannelId: "channel_id", RootId: "post_id", ParentId: "post_id", Message: "reply", CreateAt: 1}
+		mockMovePost(api, oldPost, reply)
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetUser", "replier_id").Return(&model.User{Id: "replier_id", Username: "replier"}, nil)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+		created := []*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = model.NewId()
+			created = append(created, post)
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", true, true, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		if assert.Len(created, 2) {
+			assert.Equal("bot_id", created[0].UserId)
+			assert.Equal("Originally by @author\n\nmessage", created[0].Message)
+			assert.Equal("author_id", created[0].GetProp(postPropsKeyOriginalUserID))
+			assert.Equal("bot_id", created[1].UserId)
+			assert.Equal("Originally by @replier\n\nreply", created[1].Message)
+			assert.Equal("replier_id", created[1].GetProp(postPropsKeyOriginalUserID))
+		}
+	})
+	t.Run("keep original author by default", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_id"
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("author_id", post.UserId)
+			assert.Equal("message", post.Message)
+			assert.Nil(post.GetProp(postPropsKeyOriginalUserID))
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetUser", mock.Anything)
+	})
+	t.Run("failed to get author to post as bot", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetUser", "author_id").Return(nil, &model.AppError{Message: "failed"})
+		api.On("LogError", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, true, false, false)
+
+		assert.Equal("Something went wrong. Please try again later.", *msg)
+		assert.NotNil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("detach replies", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_id"
+		p.setConfiguration(&configuration{EnableMove: true, EnableRedirectNote: true, UndoMoveWindowMinutes: 5})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: 100}
+		reply1 := &model.Post{Id: "reply1_id", UserId: "replier_id", ChannelId: "channel_id", RootId: "post_id", ParentId: "post_id", Message: "reply1", CreateAt: 200}
+		reply2 := &model.Post{Id: "reply2_id", UserId: "replier_id", ChannelId: "channel_id", RootId: "post_id", ParentId: "reply1_id", Message: "reply2", CreateAt: 300}
+		mockMovePost(api, oldPost, reply1, reply2)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+		created := []*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = fmt.Sprintf("created%d_id", len(created))
+			created = append(created, post)
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, true, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		if assert.Len(created, 4) {
+			moved, note, newReply1, newReply2 := created[0], created[1], created[2], created[3]
+			assert.Equal("to_channel_id", moved.ChannelId)
+			assert.Equal("", moved.RootId)
+
+			assert.Equal("bot_id", note.UserId)
+			assert.Equal("channel_id", note.ChannelId)
+			assert.Equal("Parent moved to http://localhost:8065/team/pl/created0_id", note.Message)
+			assert.Equal(int64(100), note.CreateAt)
+			assert.Equal("created0_id", note.GetProp(postPropsKeyMovedTo))
+
+			for _, reply := range []*model.Post{newReply1, newReply2} {
+				assert.Equal("channel_id", reply.ChannelId)
+				assert.Equal("created1_id", reply.RootId)
+				assert.Equal("created1_id", reply.ParentId)
+				assert.Equal("replier_id", reply.UserId)
+				assert.Nil(reply.GetProp(postPropsKeyMovedFromChannelID))
+			}
+			assert.Equal(map[string]bool{"reply1": true, "reply2": true}, map[string]bool{newReply1.Message: true, newReply2.Message: true})
+		}
+		api.AssertCalled(t, "DeletePost", "reply1_id")
+		api.AssertCalled(t, "DeletePost", "reply2_id")
+		api.AssertCalled(t, "DeletePost", "post_id")
+		api.AssertNotCalled(t, "KVSetWithExpiry", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("move a reply as a new root", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		root := &model.Post{Id: "root_id", UserId: "author_id", ChannelId: "channel_id", Message: "root"}
+		reply := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", RootId: "root_id", ParentId: "root_id", Message: "reply"}
+		other := &model.Post{Id: "other_reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "root_id", ParentId: "root_id", Message: "other reply"}
+		postList := model.NewPostList()
+		for _, post := range []*model.Post{root, other} {
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+		postList.AddPost(reply)
+		postList.AddOrder(reply.Id)
+		// Registered before mockMovePost so that the whole thread is returned
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		// Detaching replies is only for root posts, so it's ignored for a reply
+		mockMovePost(api, reply)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("to_channel_id", post.ChannelId)
+			assert.Equal("", post.RootId)
+			assert.Equal("", post.ParentId)
+			assert.Equal("reply", post.Message)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, true, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertCalled(t, "DeletePost", "post_id")
+		api.AssertNotCalled(t, "DeletePost", "root_id")
+		api.AssertNotCalled(t, "DeletePost", "other_reply_id")
+	})
+	t.Run("own reply can't move the thread of other's root post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		root := &model.Post{Id: "root_id", UserId: "author_id", ChannelId: "channel_id", Message: "root"}
+		reply := &model.Post{Id: "post_id", UserId: "user_id", ChannelId: "channel_id", RootId: "root_id", ParentId: "root_id", Message: "reply"}
+		postList := model.NewPostList()
+		for _, post := range []*model.Post{root, reply} {
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetPost", "post_id").Return(reply, nil)
+		api.On("GetPost", "root_id").Return(root, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_OTHERS_POSTS).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", true, false, false, false)
+
+		assert.Equal("You don't have permission to move this post.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("move a reply into a thread of the destination channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		reply := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", RootId: "root_id", ParentId: "root_id", Message: "reply"}
+		mockMovePost(api, reply)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("to_channel_id", post.ChannelId)
+			assert.Equal("to_root_id", post.RootId)
+			assert.Equal("to_root_id", post.ParentId)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "to_root_id", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertCalled(t, "DeletePost", "post_id")
+	})
+	t.Run("thread can't be moved into a thread", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		mockMovePost(api, &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "root"},
+			&model.Post{Id: "reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "post_id", Message: "reply"})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "to_root_id", "", true, false, false, false)
+
+		assert.Equal("Only a single post can be moved into a thread. Unselect \"Move thread\" and \"Detach replies\", or leave \"Reply to thread\" empty.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("roll back detaching when a reply can't be recreated", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_id"
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		reply := &model.Post{Id: "reply_id", UserId: "replier_id", ChannelId: "channel_id", RootId: "post_id", ParentId: "post_id", Message: "reply", CreateAt: 1}
+		mockMovePost(api, oldPost, reply)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message != "reply" })).Return(func(post *model.Post) *model.Post {
+			post.Id = post.ChannelId + "_created_id"
+			return post
+		}, nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message == "reply" })).Return(nil, &model.AppError{Message: "failed"})
+		api.On("DeletePost", "to_channel_id_created_id").Return(nil)
+		api.On("DeletePost", "channel_id_created_id").Return(nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, true, false)
+
+		assert.NotNil(msg)
+		assert.NotNil(err)
+		api.AssertCalled(t, "DeletePost", "to_channel_id_created_id")
+		api.AssertCalled(t, "DeletePost", "channel_id_created_id")
+		api.AssertNotCalled(t, "DeletePost", "post_id")
+		api.AssertNotCalled(t, "DeletePost", "reply_id")
+	})
+	t.Run("preserve message attachments", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		attachments := []*model.SlackAttachment{{
+			Title: "Build #1",
+			Text:  "succeeded",
+			Fields: []*model.SlackAttachmentField{
+				{Title: "Branch", Value: "master", Short: true},
+			},
+		}}
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		oldPost.AddProp("attachments", attachments)
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal(attachments, post.Attachments())
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("preserve priority", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		priority := map[string]interface{}{"priority": "urgent", "requested_ack": true}
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		oldPost.AddProp("priority", priority)
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal(priority, post.GetProp("priority"))
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("suppress mentions", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "@here message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal(true, post.GetProp(model.POST_PROPS_MENTION_HIGHLIGHT_DISABLED))
+			assert.Equal(true, post.GetProp(postPropsKeyDisableGroupHighlight))
+			assert.Equal("@\u200bhere message", post.Message)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("suppress user mentions", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "@user1 please ask @user2"}
+		reply := &model.Post{Id: "reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "post_id", Message: "thanks @user1"}
+		mockMovePost(api, oldPost, reply)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+		messages := []string{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			messages = append(messages, post.Message)
+			post.Id = model.NewId()
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", true, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		assert.Equal([]string{"@\u200buser1 please ask @\u200buser2", "thanks @\u200buser1"}, messages)
+	})
+	t.Run("moved post footer", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{SharedPostFooter: "shared", MovedPostFooter: "moved"})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("message\n\n---\nmoved", post.Message)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("shared post footer doesn't apply to moved posts", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{SharedPostFooter: "shared"})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("message", post.Message)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("hashtags and channel mentions", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		// The message is edited after the hashtags were parsed, and the channel mention is resolved in the original team
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "#release is ready. see ~off-topic", Hashtags: "#draft"}
+		oldPost.AddProp(postPropsKeyChannelMentions, map[string]interface{}{"off-topic": map[string]interface{}{"display_name": "Off-Topic"}})
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("#release is ready. see ~off-topic", post.Message)
+			assert.Equal("#release", post.Hashtags)
+			assert.Nil(post.GetProp(postPropsKeyChannelMentions))
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("notify mentions when enabled", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMentionsOnMove: true})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "@here message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Nil(post.GetProp(model.POST_PROPS_MENTION_HIGHLIGHT_DISABLED))
+			assert.Nil(post.GetProp(postPropsKeyDisableGroupHighlight))
+			assert.Equal("@here message", post.Message)
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+	})
+	t.Run("stamp provenance", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		rootPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: 1}
+		replyPost := &model.Post{Id: "reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "post_id", Message: "reply", CreateAt: 2}
+		mockMovePost(api, rootPost, replyPost)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+		var created []*model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			created = append(created, post)
+			post.Id = model.NewId()
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", true, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		assert.Len(created, 2)
+		for _, post := range created {
+			assert.Equal("channel_id", post.GetProp(postPropsKeyMovedFromChannelID))
+			assert.Equal("user_id", post.GetProp(postPropsKeyMovedByUserID))
+			assert.NotNil(post.GetProp(postPropsKeyMovedAt))
+		}
+	})
+	t.Run("notify the author", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMoveNotification: true})
+		p.botUserID = "bot_id"
+
+		mockMovePost(api, &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("GetUser", "user_id").Return(&model.User{Id: "user_id", Username: "mover"}, nil)
+		api.On("GetDirectChannel", "bot_id", "author_id").Return(&model.Channel{Id: "dm_channel_id", Type: model.CHANNEL_DIRECT}, nil)
+		var dm *model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			if post.ChannelId == "dm_channel_id" {
+				dm = post
+			}
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		assert.NotNil(dm)
+		assert.Equal("bot_id", dm.UserId)
+		assert.Equal("Your post was moved to http://localhost:8065/team/pl/moved_post_id by @mover.", dm.Message)
+	})
+	t.Run("notify the author in the locale of the author", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.i18n, _ = loadI18nBundle(filepath.Join("..", "..", "assets", "i18n"))
+		p.setConfiguration(&configuration{EnableMoveNotification: true})
+		p.botUserID = "bot_id"
+
+		mockMovePost(api, &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("GetUser", "user_id").Return(&model.User{Id: "user_id", Username: "mover", Locale: "en"}, nil)
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author", Locale: "ja"}, nil)
+		api.On("GetDirectChannel", "bot_id", "author_id").Return(&model.Channel{Id: "dm_channel_id", Type: model.CHANNEL_DIRECT}, nil)
+		var dm *model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			if post.ChannelId == "dm_channel_id" {
+				dm = post
+			}
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		if assert.NotNil(dm) {
+			assert.Equal("あなたの投稿は @mover によって http://localhost:8065/team/pl/moved_post_id に移動されました。", dm.Message)
+		}
+	})
+	t.Run("failure to notify doesn't block the move", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMoveNotification: true})
+		p.botUserID = "bot_id"
+
+		mockMovePost(api, &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("GetUser", "user_id").Return(&model.User{Id: "user_id", Username: "mover"}, nil)
+		api.On("GetDirectChannel", "bot_id", "author_id").Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil).Once()
+
+		msg, response, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertCalled(t, "DeletePost", "post_id")
+	})
+	t.Run("don't notify when moving own post", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMoveNotification: true})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "user_id", ChannelId: "channel_id", Message: "message"}
+		postList := model.NewPostList()
+		postList.AddPost(oldPost)
+		postList.AddOrder(oldPost.Id)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetPost", "post_id").Return(oldPost, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_POST).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeamMember", "team_id", "user_id").Return(&model.TeamMember{}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		api.On("CopyFileInfos", "user_id", mock.Anything).Return([]string{}, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("LogDebug", GetMockArgumentsWithType("string", 5)...).Return()
+		api.On("DeletePost", "post_id").Return(nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		_, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", false, false, false, false)
+
+		assert.Nil(t, err)
+		api.AssertNotCalled(t, "GetDirectChannel", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMoveThread(t *testing.T) {
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+	}
+
+	for _, test := range []struct {
+		Name          string
+		FailOnMessage string
+		ExpectedMsg   string
+		Created       int
+		RolledBack    []string
+	}{
+		{
+			Name:    "move root and replies",
+			Created: 3,
+		},
+		{
+			Name:          "roll back when a reply can't be created",
+			FailOnMessage: "reply1",
+			ExpectedMsg:   "Something went wrong. Please try again later.",
+			Created:       2,
+			RolledBack:    []string{"created0_id", "created1_id"},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			p := setupTestPlugin(api)
+
+			oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: 100}
+			reply1 := &model.Post{Id: "reply1_id", UserId: "replier_id", ChannelId: "channel_id", RootId: "post_id", ParentId: "post_id", Message: "reply1", CreateAt: 200}
+			reply2 := &model.Post{Id: "reply2_id", UserId: "replier_id", ChannelId: "channel_id", RootId: "post_id", ParentId: "reply1_id", Message: "reply2", CreateAt: 300}
+			api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.Message == test.FailOnMessage })).
+				Return(nil, model.NewAppError("CreatePost", "app.post.save.app_error", nil, "", http.StatusBadRequest))
+			mockMovePost(api, oldPost, reply1, reply2)
+			api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+			api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+			api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+			created := []*model.Post{}
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				post.Id = fmt.Sprintf("created%d_id", len(created))
+				created = append(created, post)
+				return post
+			}, nil)
+
+			msg, _, err := p.movePost(context.Background(), request, "to_channel_id", "", "", true, false, false, false)
+
+			assert.Len(created, test.Created)
+			if test.RolledBack == nil {
+				assert.Nil(msg)
+				assert.Nil(err)
+				for _, reply := range created[1:] {
+					assert.Equal("to_channel_id", reply.ChannelId)
+					assert.Equal("created0_id", reply.RootId)
+					assert.Equal("created0_id", reply.ParentId)
+				}
+				for _, id := range []string{"post_id", "reply1_id", "reply2_id"} {
+					api.AssertCalled(t, "DeletePost", id)
+				}
+				return
+			}
+			assert.Equal(test.ExpectedMsg, *msg)
+			assert.NotNil(err)
+			for _, id := range test.RolledBack {
+				api.AssertCalled(t, "DeletePost", id)
+			}
+			for _, id := range []string{"post_id", "reply1_id", "reply2_id"} {
+				api.AssertNotCalled(t, "DeletePost", id)
+			}
+		})
+	}
+}
+
+func TestEscapeUserMentions(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Message  string
+		Expected string
+	}{
+		{
+			Name:     "user mentions",
+			Message:  "@user1 and @user2.",
+			Expected: "@\u200buser1 and @\u200buser2.",
+		},
+		{
+			Name:     "channel-wide mention",
+			Message:  "hi @here",
+			Expected: "hi @\u200bhere",
+		},
+		{
+			Name:     "mention after a newline or a parenthesis",
+			Message:  "line\n@user1 (@user2)",
+			Expected: "line\n@\u200buser1 (@\u200buser2)",
+		},
+		{
+			Name:     "email address",
+			Message:  "mail to user@example.com",
+			Expected: "mail to user@example.com",
+		},
+		{
+			Name:     "code",
+			Message:  "`@user1` and\n```\n@user2\n```\n@user3",
+			Expected: "`@user1` and\n```\n@user2\n```\n@\u200buser3",
+		},
+		{
+			Name:     "already escaped",
+			Message:  "@\u200buser1",
+			Expected: "@\u200buser1",
+		},
+		{
+			Name:     "URL containing @",
+			Message:  "see https://medium.com/@alice/post by @alice",
+			Expected: "see https://medium.com/@alice/post by @\u200balice",
+		},
+		{
+			Name:     "link target containing @",
+			Message:  "[@bob](https://example.com/@bob) and [profile](/@bob)",
+			Expected: "[@\u200bbob](https://example.com/@bob) and [profile](/@bob)",
+		},
+		{
+			Name:     "path without scheme",
+			Message:  "example.com/@user1",
+			Expected: "example.com/@user1",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, escapeUserMentions(test.Message))
+		})
+	}
+}
+
+func TestCopyPost(t *testing.T) {
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+	}
+
+	for _, test := range []struct {
+		Name     string
+		Post     *model.Post
+		Channel  *model.Channel
+		Expected string
+		Done     string
+	}{
+		{
+			Name:     "copy message with attribution",
+			Post:     &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"},
+			Channel:  &model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN},
+			Expected: "message\n\n> Copied from ~town-square. ([original post](http://localhost:8065/team/pl/post_id))",
+			Done:     "[This post](http://localhost:8065/team/pl/post_id) is copied to ~off-topic. [New post](http://localhost:8065/team/pl/new_post_id).",
+		},
+		{
+			Name:     "file-only post",
+			Post:     &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", FileIds: []string{"file_id"}},
+			Channel:  &model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN},
+			Expected: "> Copied from ~town-square. ([original post](http://localhost:8065/team/pl/post_id))",
+			Done:     "[This post](http://localhost:8065/team/pl/post_id) is copied to ~off-topic. [New post](http://localhost:8065/team/pl/new_post_id).",
+		},
+		{
+			Name:     "copy to direct message",
+			Post:     &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"},
+			Channel:  &model.Channel{Id: "to_channel_id", Name: "user1__user2", Type: model.CHANNEL_DIRECT},
+			Expected: "message\n\n> Copied from ~town-square. ([original post](http://localhost:8065/team/pl/post_id))",
+			Done:     "[This post](http://localhost:8065/team/pl/post_id) is copied to the direct message. [New post](http://localhost:8065/team/pl/new_post_id).",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			defer api.AssertExpectations(t)
+			p := setupTestPlugin(api)
+
+			api.On("GetChannel", "to_channel_id").Return(test.Channel, nil)
+			api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+			api.On("GetPost", "post_id").Return(test.Post, nil)
+			api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+			api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+			api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+			api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+			api.On("CopyFileInfos", "user_id", []string{"file_id"}).Return([]string{"new_file_id"}, nil).Maybe()
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				assert.Equal(test.Expected, post.Message)
+				assert.Equal("to_channel_id", post.ChannelId)
+				assert.Equal("user_id", post.UserId)
+				assert.Equal(len(test.Post.FileIds), len(post.FileIds))
+				post.Id = "new_post_id"
+				return post
+			}, nil)
+			api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+			mockAuditIndex(api)
+			api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+			api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+			msg, _, err := p.copyPost(context.Background(), request, "to_channel_id", "", "", textPositionAbove)
+
+			assert.Nil(msg)
+			assert.Nil(err)
+			api.AssertCalled(t, "SendEphemeralPost", "user_id", mock.MatchedBy(func(post *model.Post) bool {
+				return strings.HasPrefix(post.Message, test.Done)
+			}))
+			api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+			api.AssertNotCalled(t, "DeletePost", mock.Anything)
+		})
+	}
+	t.Run("copy post in another team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeam", "other_team_id").Return(&model.Team{Id: "other_team_id", Name: "other-team"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("message\n\n> Copied from ~town-square. ([original post](http://localhost:8065/other-team/pl/post_id))", post.Message)
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		msg, _, err := p.copyPost(context.Background(), request, "to_channel_id", "", "", textPositionAbove)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetTeam", "team_id")
+	})
+	t.Run("no permission to read the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "private_channel_id", Message: "secret"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "private_channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.copyPost(context.Background(), request, "to_channel_id", "", "", textPositionAbove)
+
+		assert.Equal("You don't have permission to read this post.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("copied to the channel within the dedup window", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{ShareDedupWindowSeconds: 30})
+		key := makeShareDedupKey("user_id", "post_id", "to_channel_id")
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).Return(false, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.copyPost(context.Background(), request, "to_channel_id", "", "", textPositionAbove)
+
+		assert.Equal("You already shared this post to ~off-topic a moment ago.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("carry over allowed props", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{CarriedOverPropKeys: "jira_issue_key, custom_app.state"})
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		oldPost.AddProp("jira_issue_key", "PROJ-1")
+		oldPost.AddProp("custom_app.state", map[string]interface{}{"step": 2})
+		oldPost.AddProp("other_plugin.internal", "volatile")
+		oldPost.AddProp("from_webhook", "true")
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(oldPost, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("PROJ-1", post.GetProp("jira_issue_key"))
+			assert.Equal(map[string]interface{}{"step": 2}, post.GetProp("custom_app.state"))
+			assert.Nil(post.GetProp("other_plugin.internal"))
+			assert.Nil(post.GetProp("from_webhook"))
+			assert.Equal("post_id", post.GetProp(postPropsKeyCopiedFrom))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		msg, _, err := p.copyPost(context.Background(), request, "to_channel_id", "", "", textPositionAbove)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertCalled(t, "CreatePost", mock.AnythingOfType("*model.Post"))
+	})
+	t.Run("drop props by default", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		oldPost.AddProp("jira_issue_key", "PROJ-1")
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(oldPost, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Nil(post.GetProp("jira_issue_key"))
+			assert.Equal("post_id", post.GetProp(postPropsKeyCopiedFrom))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		msg, _, err := p.copyPost(context.Background(), request, "to_channel_id", "", "", textPositionAbove)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+	})
+}
+
+func TestDuplicatePost(t *testing.T) {
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+	}
+
+	t.Run("duplicate post and keep the original", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", RootId: "root_id", Message: "message", FileIds: []string{"file_id"}}
+		oldPost.AddProp("attachments", []*model.SlackAttachment{{Text: "attachment"}})
+		oldPost.AddProp("jira_issue_key", "PROJ-1")
+		oldPost.AddProp("other_plugin.internal", "volatile")
+		p.setConfiguration(&configuration{CarriedOverPropKeys: "jira_issue_key"})
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(oldPost, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		api.On("CopyFileInfos", "user_id", []string{"file_id"}).Return([]string{"new_file_id"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("to_channel_id", post.ChannelId)
+			assert.Equal("user_id", post.UserId)
+			assert.Equal("", post.RootId)
+			assert.Equal("message", post.Message)
+			assert.Equal([]string{"new_file_id"}, []string(post.FileIds))
+			assert.Equal([]*model.SlackAttachment{{Text: "attachment"}}, post.Attachments())
+			assert.Equal("PROJ-1", post.GetProp("jira_issue_key"))
+			assert.Nil(post.GetProp("other_plugin.internal"))
+			assert.Equal("post_id", post.GetProp(postPropsKeyDuplicatedFrom))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 5)...).Return()
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{}).Run(func(args mock.Arguments) {
+			post := args.Get(1).(*model.Post)
+			assert.Equal("[This post](http://localhost:8065/team/pl/post_id) is duplicated to ~off-topic. [New post](http://localhost:8065/team/pl/new_post_id).", post.Message)
+		})
+
+		msg, response, err := p.duplicatePost(context.Background(), request, "to_channel_id", "", "")
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("duplicate post in another team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeam", "other_team_id").Return(&model.Team{Id: "other_team_id", Name: "other-team"}, nil)
+		api.On("CopyFileInfos", "user_id", mock.Anything).Return([]string{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 5)...).Return()
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{}).Run(func(args mock.Arguments) {
+			post := args.Get(1).(*model.Post)
+			assert.True(strings.HasPrefix(post.Message, "[This post](http://localhost:8065/other-team/pl/post_id) is duplicated to ~off-topic."))
+		})
+
+		msg, response, err := p.duplicatePost(context.Background(), request, "to_channel_id", "", "")
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetTeam", "team_id")
+	})
+	t.Run("system message", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id", Type: model.POST_JOIN_CHANNEL}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, response, err := p.duplicatePost(context.Background(), request, "to_channel_id", "", "")
+
+		assert.Equal("System messages can't be shared.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("no permission to read the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, response, err := p.duplicatePost(context.Background(), request, "to_channel_id", "", "")
+
+		assert.Equal("You don't have permission to read this post.", *msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestSendPermalink(t *testing.T) {
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+		Submission: map[string]interface{}{
+			shareTypeKey: shareTypePermalink,
+		},
+	}
+
+	t.Run("without destination channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel_id" && strings.Contains(post.Message, "http://localhost:8065/team/pl/post_id")
+		})).Return(&model.Post{})
+
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Nil(response)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("post in DM channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "dm_channel_id", Message: "message"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "dm_channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "dm_channel_id").Return(&model.Channel{Id: "dm_channel_id", Name: "user_id__author_id", Type: model.CHANNEL_DIRECT}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.MatchedBy(func(post *model.Post) bool {
+			return strings.Contains(post.Message, "http://localhost:8065/_redirect/pl/post_id")
+		})).Return(&model.Post{})
+
+		msg, _, err := p.sendPermalink(request)
+
+		assert.Nil(msg)
+		assert.Nil(err)
+	})
+	t.Run("no permission to read the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.sendPermalink(request)
+
+		assert.Equal("You don't have permission to read this post.", *msg)
+		assert.Nil(err)
+		api.AssertNotCalled(t, "SendEphemeralPost", mock.Anything, mock.Anything)
+	})
+}
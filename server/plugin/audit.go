@@ -0,0 +1,239 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// auditKeyPrefix is the prefix of KV store keys for audit entries.
+	// Keys are `audit_<13 digits of millis>_<random id>`, so sorting the keys orders the entries by time.
+	auditKeyPrefix = "audit_"
+
+	auditActionShare     = "share"
+	auditActionCopy      = "copy"
+	auditActionDuplicate = "duplicate"
+	auditActionMove      = "move"
+	auditActionUndo      = "undo"
+
+	// auditIndexKey is the key of the index of all audit entries, and auditIndexKey + "_<user id>" is the index of the entries of the user.
+	// Indexes are JSON arrays of entry keys from the oldest, so that the history can be paginated without listing all keys in the KV store.
+	auditIndexKey = "auditindex"
+	// maxAuditIndexLength is the number of entries kept in an index. Entries dropped from the index of all users are deleted.
+	maxAuditIndexLength = 1000
+	// maxAuditIndexRetries is the number of retries of updating an index when other requests update it at the same time
+	maxAuditIndexRetries = 5
+
+	defaultHistoryPerPage = 20
+	maxHistoryPerPage     = 100
+)
+
+// auditEntry is a record of sharing/moving a post
+type auditEntry struct {
+	Action               string `json:"action"`
+	UserID               string `json:"user_id"`
+	PostID               string `json:"post_id"`
+	NewPostID            string `json:"new_post_id"`
+	SourceChannelID      string `json:"source_channel_id"`
+	DestinationChannelID string `json:"destination_channel_id"`
+	Timestamp            int64  `json:"timestamp"`
+}
+
+func makeAuditKey(timestamp int64) string {
+	return fmt.Sprintf("%s%013d_%s", auditKeyPrefix, timestamp, model.NewId())
+}
+
+// recordAudit stores the audit entry in the KV store, sends it to the event webhook, and notifies the webapp of the user.
+// Recording is best-effort, so failures are only logged and don't fail the action.
+func (p *SharePostPlugin) recordAudit(entry *auditEntry) {
+	if entry.Timestamp == 0 {
+		entry.Timestamp = model.GetMillis()
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		p.API.LogWarn("failed to marshal audit entry", "post_id", entry.PostID, "error", err.Error())
+		return
+	}
+	if err := p.storeAuditEntry(makeAuditKey(entry.Timestamp), entry.UserID, b); err != nil {
+		p.API.LogWarn("failed to record audit entry", "post_id", entry.PostID, "error", err.Error())
+	}
+	p.notifyWebhook(b)
+	p.publishCompletedEvent(entry)
+}
+
+// publishCompletedEvent sends the WebSocket event `<action>_completed` (e.g. `share_completed`) to the user who did the action,
+// so that the webapp can react to it. The server prefixes the event name with `custom_<plugin id>_`.
+func (p *SharePostPlugin) publishCompletedEvent(entry *auditEntry) {
+	p.API.PublishWebSocketEvent(entry.Action+"_completed", map[string]interface{}{
+		"action":                 entry.Action,
+		"post_id":                entry.PostID,
+		"new_post_id":            entry.NewPostID,
+		"source_channel_id":      entry.SourceChannelID,
+		"destination_channel_id": entry.DestinationChannelID,
+	}, &model.WebsocketBroadcast{UserId: entry.UserID})
+}
+
+// storeAuditEntry stores the audit entry, and adds it to the index of all users and the index of the user.
+func (p *SharePostPlugin) storeAuditEntry(key, userID string, b []byte) error {
+	if appErr := p.API.KVSet(key, b); appErr != nil {
+		return fmt.Errorf("failed to store audit entry %w", appErr)
+	}
+	dropped, err := p.appendAuditIndex(auditIndexKey, key)
+	if err != nil {
+		return err
+	}
+	for _, droppedKey := range dropped {
+		if appErr := p.API.KVDelete(droppedKey); appErr != nil {
+			p.API.LogWarn("failed to delete old audit entry", "key", droppedKey, "error", appErr.Error())
+		}
+	}
+	// Entries dropped from the index of the user are still in the index of all users, so they're not deleted
+	_, err = p.appendAuditIndex(makeAuditIndexKey(userID), key)
+	return err
+}
+
+func makeAuditIndexKey(userID string) string {
+	return auditIndexKey + "_" + userID
+}
+
+// getAuditIndex returns the entry keys in the index from the oldest, and the stored value of the index for compare-and-set.
+func (p *SharePostPlugin) getAuditIndex(indexKey string) ([]string, []byte, error) {
+	b, appErr := p.API.KVGet(indexKey)
+	if appErr != nil {
+		return nil, nil, fmt.Errorf("failed to get audit index %w", appErr)
+	}
+	keys := []string{}
+	if b == nil {
+		return keys, nil, nil
+	}
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal audit index %w", err)
+	}
+	return keys, b, nil
+}
+
+// appendAuditIndex adds the entry key to the index, and returns the keys dropped from the index over maxAuditIndexLength.
+// The index is updated with compare-and-set, so that entries recorded at the same time on other servers are not lost.
+func (p *SharePostPlugin) appendAuditIndex(indexKey, key string) ([]string, error) {
+	for i := 0; i < maxAuditIndexRetries; i++ {
+		keys, old, err := p.getAuditIndex(indexKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		var dropped []string
+		if len(keys) > maxAuditIndexLength {
+			dropped = keys[:len(keys)-maxAuditIndexLength]
+			keys = keys[len(keys)-maxAuditIndexLength:]
+		}
+		b, err := json.Marshal(keys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal audit index %w", err)
+		}
+		ok, appErr := p.API.KVCompareAndSet(indexKey, old, b)
+		if appErr != nil {
+			return nil, fmt.Errorf("failed to update audit index %w", appErr)
+		}
+		if ok {
+			return dropped, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to update audit index %s due to conflicts", indexKey)
+}
+
+// listAuditEntries returns the page of audit entries from the newest. Entries of all users are returned if userID is empty.
+// Only the entries in the page are read, by paginating the index.
+func (p *SharePostPlugin) listAuditEntries(userID string, page, perPage int) ([]*auditEntry, error) {
+	indexKey := auditIndexKey
+	if userID != "" {
+		indexKey = makeAuditIndexKey(userID)
+	}
+	keys, _, err := p.getAuditIndex(indexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*auditEntry{}
+	// Pages past the end are empty. They're checked before multiplying, so that a huge page doesn't overflow.
+	if page > len(keys)/perPage {
+		return entries, nil
+	}
+	// The index is ordered from the oldest, so the page is taken from the end
+	end := len(keys) - page*perPage
+	for i := end - 1; i >= 0 && i >= end-perPage; i-- {
+		b, appErr := p.API.KVGet(keys[i])
+		if appErr != nil {
+			return nil, fmt.Errorf("failed to get audit entry %w", appErr)
+		}
+		if b == nil {
+			// Deleted after dropped from the index of all users
+			continue
+		}
+		var entry auditEntry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			p.API.LogWarn("failed to unmarshal audit entry", "key", keys[i], "error", err.Error())
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
+
+// handleHistory returns the share/move history of the user as JSON.
+// System admins can get the history of all users with `all=true`.
+func (p *SharePostPlugin) handleHistory(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	query := r.URL.Query()
+	page, err := parseQueryInt(query.Get("page"), 0)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid page")
+		return
+	}
+	perPage, err := parseQueryInt(query.Get("per_page"), defaultHistoryPerPage)
+	if err != nil || perPage == 0 {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid per_page")
+		return
+	}
+	if perPage > maxHistoryPerPage {
+		perPage = maxHistoryPerPage
+	}
+
+	filterUserID := userID
+	if query.Get("all") == "true" {
+		if !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
+			writeJSONError(w, http.StatusForbidden, errorCodeForbidden, "forbidden")
+			return
+		}
+		filterUserID = ""
+	}
+
+	entries, err := p.listAuditEntries(filterUserID, page, perPage)
+	if err != nil {
+		p.API.LogError("failed to list audit entries", "error", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, errorCodeInternal, "failed to get history")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		p.API.LogWarn("failed to write history", "error", err.Error())
+	}
+}
+
+// parseQueryInt parses the non-negative integer in the query parameter, or returns the default value if it's empty
+func parseQueryInt(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative value %d", n)
+	}
+	return n, nil
+}
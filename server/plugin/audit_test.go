@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRecordAudit(t *testing.T) {
+	t.Run("store entry", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		var key string
+		var stored auditEntry
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			key = args.Get(0).(string)
+			assert.Nil(json.Unmarshal(args.Get(1).([]byte), &stored))
+		})
+		indexes := map[string][]string{}
+		api.On("KVGet", auditIndexKey).Return(nil, nil)
+		api.On("KVGet", "auditindex_user_id").Return([]byte(`["audit_0000000000001_old"]`), nil)
+		api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("[]uint8")).Return(true, nil).Run(func(args mock.Arguments) {
+			var keys []string
+			assert.Nil(json.Unmarshal(args.Get(2).([]byte), &keys))
+			indexes[args.String(0)] = keys
+		})
+		api.On("PublishWebSocketEvent", "move_completed", map[string]interface{}{
+			"action":                 auditActionMove,
+			"post_id":                "post_id",
+			"new_post_id":            "new_post_id",
+			"source_channel_id":      "channel_id",
+			"destination_channel_id": "to_channel_id",
+		}, &model.WebsocketBroadcast{UserId: "user_id"}).Return()
+
+		p.recordAudit(&auditEntry{
+			Action:               auditActionMove,
+			UserID:               "user_id",
+			PostID:               "post_id",
+			NewPostID:            "new_post_id",
+			SourceChannelID:      "channel_id",
+			DestinationChannelID: "to_channel_id",
+			Timestamp:            1234567890123,
+		})
+
+		assert.True(strings.HasPrefix(key, "audit_1234567890123_"))
+		assert.True(len(key) <= model.KEY_VALUE_KEY_MAX_RUNES)
+		assert.Equal(auditEntry{
+			Action:               auditActionMove,
+			UserID:               "user_id",
+			PostID:               "post_id",
+			NewPostID:            "new_post_id",
+			SourceChannelID:      "channel_id",
+			DestinationChannelID: "to_channel_id",
+			Timestamp:            1234567890123,
+		}, stored)
+		assert.Equal(map[string][]string{
+			auditIndexKey:        {key},
+			"auditindex_user_id": {"audit_0000000000001_old", key},
+		}, indexes)
+	})
+	t.Run("drop the oldest entry from the full index", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		full := make([]string, maxAuditIndexLength)
+		for i := range full {
+			full[i] = makeAuditKey(int64(i))
+		}
+		b, _ := json.Marshal(full)
+		var updated []string
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		api.On("KVGet", auditIndexKey).Return(b, nil)
+		api.On("KVCompareAndSet", auditIndexKey, b, mock.AnythingOfType("[]uint8")).Return(true, nil).Run(func(args mock.Arguments) {
+			assert.Nil(json.Unmarshal(args.Get(2).([]byte), &updated))
+		})
+		api.On("KVDelete", full[0]).Return(nil)
+		api.On("KVGet", "auditindex_user_id").Return(nil, nil)
+		api.On("KVCompareAndSet", "auditindex_user_id", []byte(nil), mock.AnythingOfType("[]uint8")).Return(true, nil)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		p.recordAudit(&auditEntry{Action: auditActionShare, UserID: "user_id", PostID: "post_id", Timestamp: 1234567890123})
+
+		assert.Len(updated, maxAuditIndexLength)
+		assert.Equal(full[1], updated[0])
+		assert.True(strings.HasPrefix(updated[maxAuditIndexLength-1], "audit_1234567890123_"))
+	})
+	t.Run("retry updating the index changed by another request", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		api.On("KVGet", auditIndexKey).Return(nil, nil).Once()
+		api.On("KVCompareAndSet", auditIndexKey, []byte(nil), mock.AnythingOfType("[]uint8")).Return(false, nil).Once()
+		api.On("KVGet", auditIndexKey).Return([]byte(`["audit_0000000000001_other"]`), nil).Once()
+		api.On("KVCompareAndSet", auditIndexKey, []byte(`["audit_0000000000001_other"]`), mock.AnythingOfType("[]uint8")).Return(true, nil).Once()
+		api.On("KVGet", "auditindex_user_id").Return(nil, nil)
+		api.On("KVCompareAndSet", "auditindex_user_id", []byte(nil), mock.AnythingOfType("[]uint8")).Return(true, nil)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		p.recordAudit(&auditEntry{Action: auditActionShare, UserID: "user_id", PostID: "post_id"})
+	})
+	t.Run("failure is only logged", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(&model.AppError{})
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		p.recordAudit(&auditEntry{Action: auditActionShare, PostID: "post_id"})
+	})
+}
+
+// mockAuditIndex mocks the empty indexes of audit entries for the tests recording audit entries
+func mockAuditIndex(api *plugintest.API) {
+	isIndexKey := func(key string) bool { return strings.HasPrefix(key, auditIndexKey) }
+	api.On("KVGet", mock.MatchedBy(isIndexKey)).Return(nil, nil).Maybe()
+	api.On("KVCompareAndSet", mock.MatchedBy(isIndexKey), mock.Anything, mock.Anything).Return(true, nil).Maybe()
+}
+
+func mockAuditEntries(api *plugintest.API, entries ...auditEntry) {
+	all := []string{}
+	byUser := map[string][]string{}
+	for _, entry := range entries {
+		key := makeAuditKey(entry.Timestamp)
+		b, _ := json.Marshal(entry)
+		api.On("KVGet", key).Return(b, nil)
+		all = append(all, key)
+		byUser[entry.UserID] = append(byUser[entry.UserID], key)
+	}
+	b, _ := json.Marshal(all)
+	api.On("KVGet", auditIndexKey).Return(b, nil)
+	for userID, keys := range byUser {
+		b, _ := json.Marshal(keys)
+		api.On("KVGet", makeAuditIndexKey(userID)).Return(b, nil)
+	}
+}
+
+func TestHandleHistory(t *testing.T) {
+	entries := []auditEntry{
+		{Action: auditActionShare, UserID: "user_id", PostID: "post1", Timestamp: 1000},
+		{Action: auditActionMove, UserID: "other_id", PostID: "post2", Timestamp: 2000},
+		{Action: auditActionCopy, UserID: "user_id", PostID: "post3", Timestamp: 3000},
+		{Action: auditActionMove, UserID: "user_id", PostID: "post4", Timestamp: 4000},
+	}
+
+	// Older entries than the page are not read, so the expectations of the mocks are not asserted
+	t.Run("own history from the newest", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		mockAuditEntries(api, entries...)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/history?page=1&per_page=1", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleHistory(w, r)
+
+		var got []auditEntry
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal([]auditEntry{entries[2]}, got)
+	})
+	t.Run("page past the end", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		mockAuditEntries(api, entries...)
+
+		// The offset of the page overflows if it's multiplied by per_page
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/history?page=92233720368547759&per_page=100", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleHistory(w, r)
+
+		var got []auditEntry
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&got))
+		assert.Empty(got)
+	})
+	t.Run("all users' history by admin", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		mockAuditEntries(api, entries...)
+		api.On("HasPermissionTo", "admin_id", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/history?all=true&per_page=2", nil)
+		r.Header.Set("Mattermost-User-ID", "admin_id")
+		w := httptest.NewRecorder()
+		p.handleHistory(w, r)
+
+		var got []auditEntry
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal([]auditEntry{entries[3], entries[2]}, got)
+	})
+	t.Run("all users' history by non-admin", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionTo", "user_id", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/history?all=true", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleHistory(w, r)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+		api.AssertNotCalled(t, "KVGet", mock.Anything)
+	})
+	t.Run("invalid page", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/history?page=-1", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleHistory(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// maxBulkMovePosts is the maximum number of posts moved by a bulk-move request, not to hold the request too long
+const maxBulkMovePosts = 100
+
+// bulkMoveRequest is the request body of handleBulkMove.
+// TeamID is the team where the request is made, whose overrides of the configuration apply.
+type bulkMoveRequest struct {
+	PostIDs   []string `json:"post_ids"`
+	ChannelID string   `json:"channel_id"`
+	TeamID    string   `json:"team_id"`
+}
+
+// bulkMoveResult is the result of moving one of the posts. Error is the message for the user when the post isn't moved.
+type bulkMoveResult struct {
+	PostID string        `json:"post_id"`
+	Moved  bool          `json:"moved"`
+	Posts  []createdPost `json:"posts,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// bulkMoveResponse is the response of handleBulkMove
+type bulkMoveResponse struct {
+	Results []bulkMoveResult `json:"results"`
+	Moved   int              `json:"moved"`
+	Failed  int              `json:"failed"`
+}
+
+// handleBulkMove moves the posts to the channel one by one, in the order of the request.
+// Each post goes through the same checks as moving a single post, and a failure doesn't stop moving the rest.
+// Threads aren't moved in bulk, so root posts having replies fail as in the dialog without "move thread".
+func (p *SharePostPlugin) handleBulkMove(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	var request bulkMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.PostIDs) == 0 || request.ChannelID == "" {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "post_ids and channel_id are required")
+		return
+	}
+	if len(request.PostIDs) > maxBulkMovePosts {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("at most %d posts can be moved at once", maxBulkMovePosts))
+		return
+	}
+
+	T := p.getLocalizer(userID)
+	if msg := p.checkShareTypeEnabled(T, userID, request.TeamID, shareTypeMove); msg != nil {
+		writeJSONError(w, http.StatusForbidden, errorCodeForbidden, *msg)
+		return
+	}
+	if msg, err := p.checkDestination(T, userID, request.TeamID, request.ChannelID); msg != nil {
+		if err != nil {
+			p.API.LogWarn("failed to check the destination channel", "error", err.Error())
+		}
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, *msg)
+		return
+	}
+
+	response := bulkMoveResponse{Results: []bulkMoveResult{}}
+	for _, postID := range request.PostIDs {
+		result := p.bulkMovePost(userID, request.TeamID, resolvePostID(postID), request.ChannelID)
+		if result.Moved {
+			response.Moved++
+		} else {
+			response.Failed++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.API.LogWarn("failed to write bulk-move results", "error", err.Error())
+	}
+}
+
+// bulkMovePost moves a single post of the bulk-move request. Each post has its own operation timeout.
+func (p *SharePostPlugin) bulkMovePost(userID, teamID, postID, toChannel string) bulkMoveResult {
+	// The plugin waits for the operation even if the client disconnects, so the context isn't derived from the request
+	ctx, created := withCreatedPosts(context.Background())
+	ctx, cancel := p.newOperationContext(ctx)
+	defer cancel()
+
+	T := p.getLocalizer(userID)
+	// Each post is moved as a single move does, so it's counted in the rate limit
+	if !p.allowShare(userID) {
+		return bulkMoveResult{PostID: postID, Error: T("share.rate_limited")}
+	}
+	// The configuration of the team is checked once for all posts, so each post must be in the team
+	if msg, err := p.checkSourceTeam(T, postID, teamID); msg != nil {
+		if err != nil {
+			p.API.LogWarn("failed to move post in bulk", "post_id", postID, "error", err.Error())
+		}
+		return bulkMoveResult{PostID: postID, Error: *msg}
+	}
+
+	request := &model.SubmitDialogRequest{CallbackId: postID, UserId: userID, TeamId: teamID}
+	msg, _, err := p.movePost(ctx, request, toChannel, "", "", false, false, false, false)
+	if err != nil {
+		p.API.LogWarn("failed to move post in bulk", "post_id", postID, "error", err.Error())
+	}
+	if msg != nil {
+		return bulkMoveResult{PostID: postID, Error: *msg}
+	}
+	return bulkMoveResult{PostID: postID, Moved: true, Posts: created.list()}
+}
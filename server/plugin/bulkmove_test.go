@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleBulkMove(t *testing.T) {
+	serve := func(p *SharePostPlugin, body string) (int, bulkMoveResponse) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/bulk-move", strings.NewReader(body))
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		w := httptest.NewRecorder()
+		p.handleBulkMove(w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		var response bulkMoveResponse
+		_ = json.NewDecoder(result.Body).Decode(&response)
+		return result.StatusCode, response
+	}
+
+	t.Run("continue after a failure", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+
+		// The root post having a reply can't be moved without its thread
+		threadList := model.NewPostList()
+		threadList.AddPost(&model.Post{Id: "thread_id", UserId: "author_id", ChannelId: "channel_id"})
+		threadList.AddPost(&model.Post{Id: "reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "thread_id"})
+		api.On("GetPostThread", "thread_id").Return(threadList, nil)
+		api.On("GetPost", "thread_id").Return(threadList.Posts["thread_id"], nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+
+		code, response := serve(p, `{"post_ids":["thread_id","post_id"],"channel_id":"to_channel_id","team_id":"team_id"}`)
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal(1, response.Moved)
+		assert.Equal(1, response.Failed)
+		if assert.Len(response.Results, 2) {
+			assert.Equal("thread_id", response.Results[0].PostID)
+			assert.False(response.Results[0].Moved)
+			assert.Contains(response.Results[0].Error, "thread")
+			assert.Equal("post_id", response.Results[1].PostID)
+			assert.True(response.Results[1].Moved)
+			if assert.Len(response.Results[1].Posts, 1) {
+				assert.Equal("moved_post_id", response.Results[1].Posts[0].PostID)
+			}
+		}
+	})
+	t.Run("same channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		oldPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "to_channel_id", Message: "message"}
+		mockMovePost(api, oldPost)
+		api.On("LogWarn", "cannot move the post to same channel.").Return()
+
+		code, response := serve(p, `{"post_ids":["post_id"],"channel_id":"to_channel_id","team_id":"team_id"}`)
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal(0, response.Moved)
+		assert.Equal(1, response.Failed)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("post in another team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "other_team_id", Type: model.CHANNEL_OPEN}, nil)
+		mockSourcePost(api)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		code, response := serve(p, `{"post_ids":["post_id"],"channel_id":"to_channel_id","team_id":"other_team_id"}`)
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal(0, response.Moved)
+		assert.Equal(1, response.Failed)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("moving is disabled", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableShare: true})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		code, _ := serve(p, `{"post_ids":["post_id"],"channel_id":"to_channel_id","team_id":"team_id"}`)
+
+		assert.Equal(http.StatusForbidden, code)
+		api.AssertNotCalled(t, "GetPost", mock.Anything)
+	})
+	t.Run("no posts", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		code, _ := serve(p, `{"post_ids":[],"channel_id":"to_channel_id"}`)
+
+		assert.Equal(http.StatusBadRequest, code)
+	})
+	t.Run("too many posts", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		postIDs, _ := json.Marshal(make([]string, maxBulkMovePosts+1))
+
+		code, _ := serve(p, `{"post_ids":`+string(postIDs)+`,"channel_id":"to_channel_id"}`)
+
+		assert.Equal(http.StatusBadRequest, code)
+	})
+}
@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// lookupCacheTTL is how long teams and channels are cached.
+// Changes such as renaming or archiving are reflected after the entries expire.
+const lookupCacheTTL = 60 * time.Second
+
+// lookupCache caches teams and channels got from the server for a short time, to reduce API calls
+// when sharing to multiple channels or when the hook runs for every post.
+// Entries are not invalidated on changes, but just expire.
+type lookupCache struct {
+	ttl time.Duration
+
+	lock     sync.Mutex
+	teams    map[string]*cacheEntry
+	channels map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+func newLookupCache(ttl time.Duration) *lookupCache {
+	return &lookupCache{
+		ttl:      ttl,
+		teams:    map[string]*cacheEntry{},
+		channels: map[string]*cacheEntry{},
+	}
+}
+
+func (c *lookupCache) get(entries map[string]*cacheEntry, id string, now time.Time) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := entries[id]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(e.expireAt) {
+		delete(entries, id)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *lookupCache) set(entries map[string]*cacheEntry, id string, value interface{}, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// Expired entries are removed here so that the map doesn't keep growing with IDs never looked up again
+	for key, e := range entries {
+		if !now.Before(e.expireAt) {
+			delete(entries, key)
+		}
+	}
+	entries[id] = &cacheEntry{value: value, expireAt: now.Add(c.ttl)}
+}
+
+// getTeam gets the team from the cache, or from the server if not cached. Errors are not cached.
+func (p *SharePostPlugin) getTeam(teamID string) (*model.Team, *model.AppError) {
+	now := time.Now()
+	if v, ok := p.lookupCache.get(p.lookupCache.teams, teamID, now); ok {
+		return v.(*model.Team), nil
+	}
+	team, appErr := p.API.GetTeam(teamID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	p.lookupCache.set(p.lookupCache.teams, teamID, team, now)
+	return team, nil
+}
+
+// getChannel gets the channel from the cache, or from the server if not cached. Errors are not cached.
+func (p *SharePostPlugin) getChannel(channelID string) (*model.Channel, *model.AppError) {
+	now := time.Now()
+	if v, ok := p.lookupCache.get(p.lookupCache.channels, channelID, now); ok {
+		return v.(*model.Channel), nil
+	}
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	p.lookupCache.set(p.lookupCache.channels, channelID, channel, now)
+	return channel, nil
+}
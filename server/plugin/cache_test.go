@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLookupCache(t *testing.T) {
+	t.Run("cached until expired", func(t *testing.T) {
+		assert := assert.New(t)
+		c := newLookupCache(time.Minute)
+		now := time.Now()
+
+		c.set(c.teams, "team_id", "team", now)
+
+		v, ok := c.get(c.teams, "team_id", now.Add(59*time.Second))
+		assert.True(ok)
+		assert.Equal("team", v)
+		_, ok = c.get(c.teams, "team_id", now.Add(time.Minute))
+		assert.False(ok)
+		assert.Empty(c.teams)
+	})
+	t.Run("expired entries are removed on set", func(t *testing.T) {
+		c := newLookupCache(time.Minute)
+		now := time.Now()
+
+		c.set(c.channels, "old_id", "old", now)
+		c.set(c.channels, "new_id", "new", now.Add(time.Minute))
+
+		assert.Len(t, c.channels, 1)
+	})
+	t.Run("get channel from the server once", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id"}, nil).Once()
+
+		for i := 0; i < 3; i++ {
+			channel, appErr := p.getChannel("channel_id")
+			assert.Nil(appErr)
+			assert.Equal("channel_id", channel.Id)
+		}
+	})
+	t.Run("errors are not cached", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetTeam", "team_id").Return(nil, &model.AppError{}).Once()
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id"}, nil).Once()
+
+		_, appErr := p.getTeam("team_id")
+		assert.NotNil(appErr)
+		team, appErr := p.getTeam("team_id")
+		assert.Nil(appErr)
+		assert.Equal("team_id", team.Id)
+	})
+}
+
+// BenchmarkShareToMultipleChannels reports the number of GetTeam/GetChannel calls when sharing a post to 10 channels.
+// A cache with zero TTL never hits, so it shows the number of calls without the cache.
+func BenchmarkShareToMultipleChannels(b *testing.B) {
+	for _, bench := range []struct {
+		Name string
+		TTL  time.Duration
+	}{
+		{Name: "without cache", TTL: 0},
+		{Name: "with cache", TTL: lookupCacheTTL},
+	} {
+		b.Run(bench.Name, func(b *testing.B) {
+			api := &plugintest.API{}
+			p := setupTestPlugin(api)
+			p.lookupCache = newLookupCache(bench.TTL)
+
+			lookups := 0
+			countLookup := func(mock.Arguments) { lookups++ }
+			toChannels := []string{}
+			for i := 0; i < 10; i++ {
+				id := fmt.Sprintf("to_channel_id_%d", i)
+				toChannels = append(toChannels, id)
+				api.On("GetChannel", id).Return(&model.Channel{Id: id, Name: id, Type: model.CHANNEL_OPEN}, nil).Run(countLookup)
+				api.On("GetChannelMember", id, "user_id").Return(&model.ChannelMember{}, nil)
+				api.On("HasPermissionToChannel", "user_id", id, model.PERMISSION_CREATE_POST).Return(true)
+			}
+			api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil).Run(countLookup)
+			api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+			api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil).Run(countLookup)
+			postList := model.NewPostList()
+			postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+			postList.AddOrder("post_id")
+			api.On("GetPostThread", "post_id").Return(postList, nil)
+			api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+			api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{Id: "new_post_id"}, nil)
+			api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+			mockAuditIndex(api)
+			api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+			api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+			request := &model.SubmitDialogRequest{
+				CallbackId: "post_id",
+				UserId:     "user_id",
+				ChannelId:  "channel_id",
+				TeamId:     "team_id",
+			}
+			// Channels are shared one by one, so that the counter isn't updated concurrently
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, toChannel := range toChannels {
+					if _, _, err := p.sharePost(context.Background(), request, toChannel, "", "", renderModeQuote, textPositionAbove, false, false, false); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(lookups)/float64(b.N), "lookups/op")
+		})
+	}
+}
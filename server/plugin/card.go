@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const cardContextKeyPermalink = "permalink"
+
+// makeShareCard renders the post as a message attachment with the author as the title, and the button to view the original post.
+// The card is seen by all members of the destination channel, so it's rendered in the default locale of the server.
+func (p *SharePostPlugin) makeShareCard(post *model.Post, channel *model.Channel, teamName string) *model.SlackAttachment {
+	T := p.getServerLocalizer()
+	link := p.makePostLink(teamName, post.Id)
+	authorName := p.getAuthorName(post)
+	body, _ := truncateQuotedMessage(post.Message)
+	if isFileOnlyPost(post) {
+		body = T("card.file_count", len(post.FileIds))
+	}
+	return &model.SlackAttachment{
+		Fallback:  T("card.fallback", authorName, channel.Name, body),
+		Title:     authorName,
+		TitleLink: link,
+		Text:      body,
+		Fields: []*model.SlackAttachmentField{
+			{Title: T("card.author"), Value: authorName, Short: true},
+			{Title: T("card.channel"), Value: "~" + channel.Name, Short: true},
+		},
+		Timestamp: post.CreateAt / 1000,
+		Actions: []*model.PostAction{{
+			Name: T("card.view_original"),
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("/plugins/%s/api/v1/view_original", manifest.Id),
+				Context: map[string]interface{}{cardContextKeyPermalink: link},
+			},
+		}},
+	}
+}
+
+// handleViewOriginal replies the link to the original post of the card, because buttons of message attachments can't open links.
+// Permalinks are checked by the server when they are opened, so the link is returned to any user.
+func (p *SharePostPlugin) handleViewOriginal(w http.ResponseWriter, r *http.Request) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		p.API.LogWarn("Failed to decode PostActionIntegrationRequest")
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid request")
+		return
+	}
+	if request.UserId != r.Header.Get("Mattermost-User-Id") {
+		p.API.LogWarn("invalid user")
+		writeJSONError(w, http.StatusUnauthorized, errorCodeUnauthorized, "not authorized")
+		return
+	}
+	link, ok := request.Context[cardContextKeyPermalink].(string)
+	if !ok {
+		p.API.LogWarn("failed to get permalink from the context", "context", request.Context)
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid request")
+		return
+	}
+
+	T := p.getLocalizer(request.UserId)
+	response := &model.PostActionIntegrationResponse{EphemeralText: T("card.original_post", link)}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(response.ToJson()); err != nil {
+		p.API.LogWarn("failed to write PostActionIntegrationResponse", "error", err.Error())
+	}
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeShareCard(t *testing.T) {
+	assert := assert.New(t)
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	p.i18n, _ = loadI18nBundle(filepath.Join("..", "..", "assets", "i18n"))
+	p.ServerConfig.LocalizationSettings.DefaultServerLocale = model.NewString("ja")
+	api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+
+	// The card is seen by all members of the channel, so it's in the locale of the server rather than of the user
+	post := &model.Post{Id: "post_id", UserId: "author_id", FileIds: []string{"file_id"}}
+	card := p.makeShareCard(post, &model.Channel{Name: "town-square"}, "team")
+
+	assert.Equal("1 個のファイル", card.Text)
+	assert.Equal("@author が ~town-square に投稿: 1 個のファイル", card.Fallback)
+	assert.Equal("投稿者", card.Fields[0].Title)
+	assert.Equal("チャンネル", card.Fields[1].Title)
+	assert.Equal("元の投稿を表示", card.Actions[0].Name)
+}
+
+func TestHandleViewOriginal(t *testing.T) {
+	t.Run("reply the permalink", func(t *testing.T) {
+		assert := assert.New(t)
+		p := setupTestPlugin(&plugintest.API{})
+
+		request := &model.PostActionIntegrationRequest{
+			UserId:  "user_id",
+			Context: map[string]interface{}{cardContextKeyPermalink: "http://localhost:8065/team/pl/post_id"},
+		}
+		b, _ := json.Marshal(request)
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/view_original", bytes.NewReader(b))
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		w := httptest.NewRecorder()
+		p.handleViewOriginal(w, r)
+
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		var response model.PostActionIntegrationResponse
+		assert.Nil(json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal("[Original post](http://localhost:8065/team/pl/post_id)", response.EphemeralText)
+	})
+	t.Run("invalid user", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("LogWarn", "invalid user").Return()
+
+		request := &model.PostActionIntegrationRequest{
+			UserId:  "other_id",
+			Context: map[string]interface{}{cardContextKeyPermalink: "http://localhost:8065/team/pl/post_id"},
+		}
+		b, _ := json.Marshal(request)
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/view_original", bytes.NewReader(b))
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		w := httptest.NewRecorder()
+		p.handleViewOriginal(w, r)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	defaultDestinationsPerPage = 50
+	maxDestinationsPerPage     = 200
+
+	maxInt = int(^uint(0) >> 1)
+)
+
+// destinationChannel is a channel the user can share posts to, returned by handleChannels
+type destinationChannel struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	TeamName    string `json:"team_name"`
+	Type        string `json:"type"`
+}
+
+// listDestinationChannels returns the page of channels in all teams of the user, where the user can post and
+// the configuration permits sharing to. Channels are sorted by the team name and the display name.
+// The same-team restriction is applied only when the team of the original post is given as sourceTeamID.
+// Posts in DM/GM channels don't belong to any team, so sourceTeamID is empty for them.
+func (p *SharePostPlugin) listDestinationChannels(userID, sourceTeamID string, page, perPage int) ([]*destinationChannel, *model.AppError) {
+	ret := []*destinationChannel{}
+	// The number of channels is unknown until all teams are read, so only the pages whose offset overflows are empty at once
+	if page > maxInt/perPage {
+		return ret, nil
+	}
+	teams, appErr := p.API.GetTeamsForUser(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+
+	config := p.getConfiguration()
+	skip := page * perPage
+	for _, team := range teams {
+		channels, appErr := p.API.GetChannelsForTeamForUser(team.Id, userID, false)
+		if appErr != nil {
+			return nil, appErr
+		}
+		sort.Slice(channels, func(i, j int) bool {
+			return strings.ToLower(channels[i].DisplayName) < strings.ToLower(channels[j].DisplayName)
+		})
+		for _, c := range channels {
+			// DMs and GMs are returned for every team, and they don't have display names on the server
+			if c.DeleteAt != 0 || c.IsGroupOrDirect() || c.TeamId != team.Id {
+				continue
+			}
+			teamID := sourceTeamID
+			if teamID == "" {
+				teamID = c.TeamId
+			}
+			if !config.forTeam(teamID).isDestinationAllowed(c, teamID) {
+				continue
+			}
+			// Permissions are checked only for the channels up to the page, because it takes an API call for each channel
+			if !p.API.HasPermissionToChannel(userID, c.Id, model.PERMISSION_CREATE_POST) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			ret = append(ret, &destinationChannel{ID: c.Id, DisplayName: c.DisplayName, TeamName: team.Name, Type: c.Type})
+			if len(ret) >= perPage {
+				return ret, nil
+			}
+		}
+	}
+	return ret, nil
+}
+
+// handleChannels returns the page of channels in all teams of the user, where the user can share posts to, for custom pickers.
+// The share dialog doesn't use it, because dialogs of the supported servers offer only the built-in channel selector.
+func (p *SharePostPlugin) handleChannels(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	query := r.URL.Query()
+	page, err := parseQueryInt(query.Get("page"), 0)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid page")
+		return
+	}
+	perPage, err := parseQueryInt(query.Get("per_page"), defaultDestinationsPerPage)
+	if err != nil || perPage == 0 {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid per_page")
+		return
+	}
+	if perPage > maxDestinationsPerPage {
+		perPage = maxDestinationsPerPage
+	}
+
+	// The team of the original post is resolved on the server, so that the same-team restriction can't be bypassed by the client
+	sourceTeamID := ""
+	if postID := resolvePostID(query.Get("post_id")); postID != "" {
+		post, appErr := p.API.GetPost(postID)
+		if appErr != nil || !p.canReadPost(userID, post) {
+			writeJSONError(w, http.StatusNotFound, errorCodeInvalidRequest, "post not found")
+			return
+		}
+		if sourceTeamID, appErr = p.getPostTeamID(post, ""); appErr != nil {
+			p.API.LogWarn("failed to get team of the post", "post_id", postID, "error", appErr.Error())
+			writeJSONError(w, http.StatusInternalServerError, errorCodeInternal, "failed to get channels")
+			return
+		}
+	}
+
+	channels, appErr := p.listDestinationChannels(userID, sourceTeamID, page, perPage)
+	if appErr != nil {
+		p.API.LogWarn("failed to list destination channels", "user_id", userID, "error", appErr.Error())
+		writeJSONError(w, http.StatusInternalServerError, errorCodeInternal, "failed to get channels")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(channels); err != nil {
+		p.API.LogWarn("failed to write channels", "error", err.Error())
+	}
+}
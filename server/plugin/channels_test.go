@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleChannels(t *testing.T) {
+	teams := []*model.Team{
+		{Id: "team2_id", Name: "team2"},
+		{Id: "team1_id", Name: "team1"},
+	}
+	team1Channels := []*model.Channel{
+		{Id: "town_square_id", Name: "town-square", DisplayName: "Town Square", TeamId: "team1_id", Type: model.CHANNEL_OPEN},
+		{Id: "off_topic_id", Name: "off-topic", DisplayName: "Off-Topic", TeamId: "team1_id", Type: model.CHANNEL_OPEN},
+		{Id: "readonly_id", Name: "announcements", DisplayName: "Announcements", TeamId: "team1_id", Type: model.CHANNEL_OPEN},
+		{Id: "archived_id", Name: "archived", DisplayName: "Archived", TeamId: "team1_id", Type: model.CHANNEL_OPEN, DeleteAt: 1000},
+		{Id: "dm_id", Name: "user_id__other_id", Type: model.CHANNEL_DIRECT},
+	}
+	team2Channels := []*model.Channel{
+		{Id: "private_id", Name: "private", DisplayName: "Private", TeamId: "team2_id", Type: model.CHANNEL_PRIVATE},
+		{Id: "denied_id", Name: "denied", DisplayName: "Denied", TeamId: "team2_id", Type: model.CHANNEL_OPEN},
+	}
+	mockChannels := func(api *plugintest.API) {
+		api.On("GetTeamsForUser", "user_id").Return(teams, nil)
+		api.On("GetChannelsForTeamForUser", "team1_id", "user_id", false).Return(team1Channels, nil)
+		api.On("GetChannelsForTeamForUser", "team2_id", "user_id", false).Return(team2Channels, nil)
+		api.On("HasPermissionToChannel", "user_id", "readonly_id", model.PERMISSION_CREATE_POST).Return(false)
+		api.On("HasPermissionToChannel", "user_id", mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
+	}
+
+	t.Run("channels in all teams", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{DeniedShareDestinations: "denied_id"})
+		mockChannels(api)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleChannels(w, r)
+
+		var got []*destinationChannel
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal([]*destinationChannel{
+			{ID: "off_topic_id", DisplayName: "Off-Topic", TeamName: "team1", Type: model.CHANNEL_OPEN},
+			{ID: "town_square_id", DisplayName: "Town Square", TeamName: "team1", Type: model.CHANNEL_OPEN},
+			{ID: "private_id", DisplayName: "Private", TeamName: "team2", Type: model.CHANNEL_PRIVATE},
+		}, got)
+	})
+	t.Run("same team restriction", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{RestrictShareToSameTeam: true})
+		mockChannels(api)
+
+		channels, appErr := p.listDestinationChannels("user_id", "team2_id", 0, defaultDestinationsPerPage)
+
+		assert.Nil(appErr)
+		assert.Equal([]*destinationChannel{
+			{ID: "denied_id", DisplayName: "Denied", TeamName: "team2", Type: model.CHANNEL_OPEN},
+			{ID: "private_id", DisplayName: "Private", TeamName: "team2", Type: model.CHANNEL_PRIVATE},
+		}, channels)
+	})
+	t.Run("same team restriction by the team of the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{RestrictShareToSameTeam: true})
+		mockChannels(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", TeamId: "team2_id", Type: model.CHANNEL_OPEN}, nil)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels?post_id=post_id", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleChannels(w, r)
+
+		var got []*destinationChannel
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&got))
+		assert.Equal([]*destinationChannel{
+			{ID: "denied_id", DisplayName: "Denied", TeamName: "team2", Type: model.CHANNEL_OPEN},
+			{ID: "private_id", DisplayName: "Private", TeamName: "team2", Type: model.CHANNEL_PRIVATE},
+		}, got)
+	})
+	t.Run("unreadable post", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels?post_id=post_id", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleChannels(w, r)
+
+		assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+		api.AssertNotCalled(t, "GetTeamsForUser", mock.Anything)
+	})
+	t.Run("paginate", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		mockChannels(api)
+
+		channels, appErr := p.listDestinationChannels("user_id", "", 1, 2)
+
+		assert.Nil(appErr)
+		assert.Equal([]*destinationChannel{
+			{ID: "denied_id", DisplayName: "Denied", TeamName: "team2", Type: model.CHANNEL_OPEN},
+			{ID: "private_id", DisplayName: "Private", TeamName: "team2", Type: model.CHANNEL_PRIVATE},
+		}, channels)
+	})
+	t.Run("page whose offset overflows", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels?page=92233720368547759&per_page=100", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleChannels(w, r)
+
+		var got []*destinationChannel
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&got))
+		assert.Empty(t, got)
+	})
+	t.Run("invalid per_page", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels?per_page=0", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleChannels(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		api.AssertNotCalled(t, "GetTeamsForUser", mock.Anything)
+	})
+	t.Run("failed to get teams", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetTeamsForUser", "user_id").Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/channels", nil)
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		w := httptest.NewRecorder()
+		p.handleChannels(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+}
@@ -0,0 +1,100 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// collectionRootKeyPrefix is the prefix of KV store keys for collection roots.
+// IDs are hashed because two IDs and the prefix don't fit in the key length limit.
+const collectionRootKeyPrefix = "collection_root_"
+
+func makeCollectionRootKey(userID, channelID string) string {
+	sum := sha256.Sum256([]byte(userID + channelID))
+	return collectionRootKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// collectionRootRequest is the request body of handleSetCollectionRoot.
+// RootID may be the permalink of the post, and a reply means its thread.
+type collectionRootRequest struct {
+	ChannelID string `json:"channel_id"`
+	RootID    string `json:"root_id"`
+}
+
+// handleSetCollectionRoot sets the collection root of the user in the channel.
+// Posts the user shares to the channel afterwards are posted as replies to the root, unless another thread is selected.
+func (p *SharePostPlugin) handleSetCollectionRoot(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	var request collectionRootRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ChannelID == "" || request.RootID == "" {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "channel_id and root_id are required")
+		return
+	}
+	if !p.API.HasPermissionToChannel(userID, request.ChannelID, model.PERMISSION_READ_CHANNEL) {
+		p.API.LogWarn("user is not permitted to read the channel of the collection root.", "user_id", userID, "channel_id", request.ChannelID)
+		writeJSONError(w, http.StatusForbidden, errorCodeForbidden, "not permitted to read the channel")
+		return
+	}
+	T := p.getLocalizer(userID)
+	rootID, msg, err := p.findRootPostInChannel(T, request.RootID, request.ChannelID)
+	if err != nil {
+		p.API.LogWarn("failed to find the collection root", "error", err.Error())
+	}
+	if msg != nil {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, *msg)
+		return
+	}
+	if appErr := p.API.KVSet(makeCollectionRootKey(userID, request.ChannelID), []byte(rootID)); appErr != nil {
+		p.API.LogError("failed to save the collection root", "channel_id", request.ChannelID, "error", appErr.Error())
+		writeJSONError(w, http.StatusInternalServerError, errorCodeInternal, "failed to save the collection root")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(collectionRootRequest{ChannelID: request.ChannelID, RootID: rootID})
+}
+
+// handleClearCollectionRoot clears the collection root of the user in the channel given by `channel_id` query.
+func (p *SharePostPlugin) handleClearCollectionRoot(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	channelID := r.URL.Query().Get("channel_id")
+	if channelID == "" {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "channel_id is required")
+		return
+	}
+	if appErr := p.API.KVDelete(makeCollectionRootKey(userID, channelID)); appErr != nil {
+		p.API.LogError("failed to delete the collection root", "channel_id", channelID, "error", appErr.Error())
+		writeJSONError(w, http.StatusInternalServerError, errorCodeInternal, "failed to clear the collection root")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getCollectionRoot returns the collection root of the user in the channel, or an empty string if it's not set.
+// The root is cleared if it's no longer in the channel (e.g. deleted or moved), so that posts are shared to the channel as usual.
+// Failures of the KV store don't block sharing.
+func (p *SharePostPlugin) getCollectionRoot(userID, channelID string) string {
+	key := makeCollectionRootKey(userID, channelID)
+	b, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		p.API.LogWarn("failed to get the collection root", "channel_id", channelID, "error", appErr.Error())
+		return ""
+	}
+	if len(b) == 0 {
+		return ""
+	}
+	rootID := string(b)
+	post, appErr := p.API.GetPost(rootID)
+	if appErr == nil && post.DeleteAt == 0 && post.ChannelId == channelID {
+		return rootID
+	}
+	p.API.LogWarn("collection root is no longer in the channel, so it's cleared.", "post_id", rootID, "channel_id", channelID)
+	if appErr := p.API.KVDelete(key); appErr != nil {
+		p.API.LogWarn("failed to delete the collection root", "channel_id", channelID, "error", appErr.Error())
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockNoCollectionRoot mocks the KV store for users without collection roots
+func mockNoCollectionRoot(api *plugintest.API) {
+	api.On("KVGet", mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, collectionRootKeyPrefix) })).Return(nil, nil)
+}
+
+func TestHandleSetCollectionRoot(t *testing.T) {
+	serve := func(p *SharePostPlugin, body string) (int, map[string]string) {
+		r := httptest.NewRequest(http.MethodPut, "/api/v1/collection", strings.NewReader(body))
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		w := httptest.NewRecorder()
+		p.handleSetCollectionRoot(w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		var response map[string]string
+		_ = json.NewDecoder(result.Body).Decode(&response)
+		return result.StatusCode, response
+	}
+	key := makeCollectionRootKey("user_id", "channel_id")
+
+	t.Run("set the thread of the reply", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetPost", "reply_id").Return(&model.Post{Id: "reply_id", RootId: "root_id", ChannelId: "channel_id"}, nil)
+		api.On("KVSet", key, []byte("root_id")).Return(nil)
+
+		code, response := serve(p, `{"channel_id": "channel_id", "root_id": "reply_id"}`)
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal(map[string]string{"channel_id": "channel_id", "root_id": "root_id"}, response)
+	})
+	t.Run("post is not in the channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetPost", "root_id").Return(&model.Post{Id: "root_id", ChannelId: "other_channel_id"}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		code, response := serve(p, `{"channel_id": "channel_id", "root_id": "root_id"}`)
+
+		assert.Equal(http.StatusBadRequest, code)
+		assert.Equal("The thread to reply to was not found in the selected channel.", response["error"])
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+	t.Run("not permitted to read the channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		code, _ := serve(p, `{"channel_id": "channel_id", "root_id": "root_id"}`)
+
+		assert.Equal(http.StatusForbidden, code)
+		api.AssertNotCalled(t, "GetPost", mock.Anything)
+	})
+	t.Run("missing root", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		code, _ := serve(p, `{"channel_id": "channel_id"}`)
+
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestHandleClearCollectionRoot(t *testing.T) {
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	api.On("KVDelete", makeCollectionRootKey("user_id", "channel_id")).Return(nil)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/v1/collection?channel_id=channel_id", nil)
+	r.Header.Set("Mattermost-User-Id", "user_id")
+	w := httptest.NewRecorder()
+	p.handleClearCollectionRoot(w, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+}
+
+func TestGetCollectionRoot(t *testing.T) {
+	key := makeCollectionRootKey("user_id", "channel_id")
+
+	t.Run("not set", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", key).Return(nil, nil)
+
+		assert.Equal(t, "", p.getCollectionRoot("user_id", "channel_id"))
+	})
+	t.Run("root in the channel", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", key).Return([]byte("root_id"), nil)
+		api.On("GetPost", "root_id").Return(&model.Post{Id: "root_id", ChannelId: "channel_id"}, nil)
+
+		assert.Equal(t, "root_id", p.getCollectionRoot("user_id", "channel_id"))
+	})
+	t.Run("root moved to another channel", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", key).Return([]byte("root_id"), nil)
+		api.On("GetPost", "root_id").Return(&model.Post{Id: "root_id", ChannelId: "other_channel_id"}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+		api.On("KVDelete", key).Return(nil)
+
+		assert.Equal(t, "", p.getCollectionRoot("user_id", "channel_id"))
+	})
+	t.Run("root deleted", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", key).Return([]byte("root_id"), nil)
+		api.On("GetPost", "root_id").Return(nil, &model.AppError{Message: "not found", StatusCode: http.StatusNotFound})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+		api.On("KVDelete", key).Return(nil)
+
+		assert.Equal(t, "", p.getCollectionRoot("user_id", "channel_id"))
+	})
+}
+
+func TestShareToCollectionRoot(t *testing.T) {
+	assert := assert.New(t)
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+	api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+	api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+	api.On("GetChannel", "highlights_id").Return(&model.Channel{Id: "highlights_id", Name: "highlights", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+	api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+	postList := model.NewPostList()
+	postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+	postList.AddOrder("post_id")
+	api.On("GetChannelMember", "highlights_id", "user_id").Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionToChannel", "user_id", "highlights_id", model.PERMISSION_CREATE_POST).Return(true)
+	api.On("GetPostThread", "post_id").Return(postList, nil)
+	api.On("KVGet", makeCollectionRootKey("user_id", "highlights_id")).Return([]byte("root_id"), nil)
+	api.On("GetPost", "root_id").Return(&model.Post{Id: "root_id", ChannelId: "highlights_id"}, nil)
+	api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		assert.Equal("root_id", post.RootId)
+		post.Id = "new_post_id"
+		return post
+	}, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	mockAuditIndex(api)
+	api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+	api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+		Submission: map[string]interface{}{
+			toChannelKey: "highlights_id",
+			shareTypeKey: shareTypeShare,
+		},
+	}
+	msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+	assert.Nil(msg)
+	assert.Nil(response)
+	assert.Nil(err)
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+}
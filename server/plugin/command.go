@@ -0,0 +1,261 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	commandTriggerShare = "share"
+	commandTriggerMove  = "move"
+
+	// commandConfirmFlag confirms moving the post by `/move`, as the `confirm_move` element of the dialog does
+	commandConfirmFlag = "--confirm"
+
+	// lastPostSearchLimit is the number of recent posts searched for the last post to move, skipping system messages
+	lastPostSearchLimit = 20
+)
+
+func (p *SharePostPlugin) registerCommands() error {
+	// Dynamic autocomplete is not supported by the minimum server version, but the webapp suggests channels after typing `~`.
+	commands := []*model.Command{{
+		Trigger:          commandTriggerShare,
+		AutoComplete:     true,
+		AutoCompleteDesc: "Share a post to other channel",
+		AutoCompleteHint: "~channel [permalink] [additional text]",
+		DisplayName:      "Share post",
+	}, {
+		Trigger:          commandTriggerMove,
+		AutoComplete:     true,
+		AutoCompleteDesc: "Move the post you're replying to, or the last post in the channel, to other channel",
+		AutoCompleteHint: "~channel [permalink] [--confirm]",
+		DisplayName:      "Move post",
+	}}
+	for _, command := range commands {
+		if err := p.API.RegisterCommand(command); err != nil {
+			return fmt.Errorf("failed to register %s command %w", command.Trigger, err)
+		}
+	}
+	return nil
+}
+
+// ExecuteCommand executes the slash commands registered by the plugin
+func (p *SharePostPlugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	T := p.getLocalizer(args.UserId)
+	trigger, rest := nextCommandArg(args.Command)
+	switch strings.TrimPrefix(trigger, "/") {
+	case commandTriggerShare:
+		return commandResponse(p.executeShareCommand(T, args, rest)), nil
+	case commandTriggerMove:
+		return commandResponse(p.executeMoveCommand(T, args, rest)), nil
+	default:
+		return commandResponse(T("command.unknown", trigger)), nil
+	}
+}
+
+// executeShareCommand shares the post via handleSharePost so that the behavior is the same as the dialog
+func (p *SharePostPlugin) executeShareCommand(T localizer, args *model.CommandArgs, rest string) string {
+	if msg := p.checkShareTypeEnabled(T, args.UserId, args.TeamId, shareTypeShare); msg != nil {
+		return *msg
+	}
+	channelName, rest := nextCommandArg(rest)
+	if channelName == "" {
+		return T("command.share.usage")
+	}
+	toChannel, msg := p.findCommandChannel(T, args.TeamId, channelName)
+	if msg != "" {
+		return msg
+	}
+
+	postID := args.ParentId
+	if postID == "" {
+		postID = args.RootId
+	}
+	if arg, remaining := nextCommandArg(rest); arg != "" {
+		if _, id, err := parsePostLink(arg); err == nil {
+			postID = id
+			rest = remaining
+		}
+	}
+	if postID == "" {
+		return T("command.share.usage")
+	}
+
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get post", "post_id", postID, "error", appErr.Error())
+		return T("command.share.post_not_found")
+	}
+	if !p.API.HasPermissionToChannel(args.UserId, post.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		p.API.LogWarn("user doesn't have permission to read the post.", "user_id", args.UserId, "post_id", postID)
+		return T("command.share.post_not_found")
+	}
+
+	submission := map[string]interface{}{
+		toChannelKey: toChannel.Id,
+		shareTypeKey: shareTypeShare,
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		submission[additionalTextKey] = rest
+	}
+	request := &model.SubmitDialogRequest{
+		CallbackId: postID,
+		UserId:     args.UserId,
+		ChannelId:  post.ChannelId,
+		TeamId:     args.TeamId,
+		Submission: submission,
+	}
+	// The dialog submissions are limited by handleSubmitDialogRequest, which the command doesn't go through
+	if !p.allowShare(args.UserId) {
+		return T("share.rate_limited")
+	}
+	message, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+	if err != nil {
+		p.API.LogWarn("failed to share post by command", "error", err.Error())
+	}
+	if message != nil {
+		return *message
+	}
+	// Errors for the dialog elements, e.g. too long additional text, are also shown to the user
+	if response != nil {
+		for _, e := range response.Errors {
+			return e
+		}
+	}
+	// The result of sharing has already been sent as an ephemeral post
+	return ""
+}
+
+// executeMoveCommand moves the post via movePost
+func (p *SharePostPlugin) executeMoveCommand(T localizer, args *model.CommandArgs, rest string) string {
+	if msg := p.checkShareTypeEnabled(T, args.UserId, args.TeamId, shareTypeMove); msg != nil {
+		return *msg
+	}
+	channelName, rest := nextCommandArg(rest)
+	if channelName == "" {
+		return T("command.move.usage")
+	}
+	toChannel, msg := p.findCommandChannel(T, args.TeamId, channelName)
+	if msg != "" {
+		return msg
+	}
+
+	postID := args.ParentId
+	if postID == "" {
+		postID = args.RootId
+	}
+	confirmed := false
+	for arg, remaining := nextCommandArg(rest); arg != ""; arg, remaining = nextCommandArg(remaining) {
+		if arg == commandConfirmFlag {
+			confirmed = true
+			continue
+		}
+		_, id, err := parsePostLink(arg)
+		if err != nil {
+			return T("command.move.usage")
+		}
+		postID = id
+	}
+	if postID == "" {
+		lastPost, appErr := p.findLastPost(args.ChannelId)
+		if appErr != nil {
+			p.API.LogWarn("failed to get posts for channel", "channel_id", args.ChannelId, "error", appErr.Error())
+			return T("error.generic")
+		}
+		if lastPost == nil {
+			return T("command.move.no_post")
+		}
+		postID = lastPost.Id
+	}
+
+	// The command doesn't move whole thread, so posts in a thread are refused before moving
+	postList, appErr := p.API.GetPostThread(postID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get post list", "post_id", postID, "error", appErr.Error())
+		return T("error.generic")
+	}
+	if post, ok := postList.Posts[postID]; ok && isInThread(postList, post) {
+		return T("command.move.thread_not_movable")
+	}
+
+	if msg, err := p.checkDestination(T, args.UserId, args.TeamId, toChannel.Id); msg != nil {
+		if err != nil {
+			p.API.LogWarn("failed to check destination", "error", err.Error())
+		}
+		return *msg
+	}
+
+	request := &model.SubmitDialogRequest{
+		CallbackId: postID,
+		UserId:     args.UserId,
+		ChannelId:  args.ChannelId,
+		TeamId:     args.TeamId,
+		Submission: map[string]interface{}{confirmMoveKey: confirmed},
+	}
+	// Moving deletes the original post, so it's confirmed as in the dialog. The confirmation has the permalink,
+	// so that the post confirmed is moved even if other posts have been posted since then.
+	if response := p.confirmMove(T, request); response != nil {
+		return T("command.move.confirm", toChannel.Name, p.makePostLink("", postID))
+	}
+	if !p.allowShare(args.UserId) {
+		return T("share.rate_limited")
+	}
+	ctx, cancel := p.newOperationContext(context.Background())
+	defer cancel()
+	message, _, err := p.movePost(ctx, request, toChannel.Id, "", "", false, false, false, false)
+	if err != nil {
+		p.API.LogWarn("failed to move post by command", "error", err.Error())
+	}
+	if message != nil {
+		return *message
+	}
+	// The result of moving has already been sent as an ephemeral post
+	return ""
+}
+
+// findLastPost returns the latest post in the channel which is not a system message, or nil if not found
+func (p *SharePostPlugin) findLastPost(channelID string) (*model.Post, *model.AppError) {
+	postList, appErr := p.API.GetPostsForChannel(channelID, 0, lastPostSearchLimit)
+	if appErr != nil {
+		return nil, appErr
+	}
+	for _, id := range postList.Order {
+		if post, ok := postList.Posts[id]; ok && !post.IsSystemMessage() {
+			return post, nil
+		}
+	}
+	return nil, nil
+}
+
+// findCommandChannel finds the channel specified as `~channel-name` or `channel-name` in the team
+func (p *SharePostPlugin) findCommandChannel(T localizer, teamID, name string) (*model.Channel, string) {
+	name = strings.TrimPrefix(name, "~")
+	channel, appErr := p.API.GetChannelByName(teamID, name, false)
+	if appErr != nil {
+		p.API.LogWarn("failed to get channel", "channel_name", name, "error", appErr.Error())
+		return nil, T("command.channel_not_found", name)
+	}
+	return channel, ""
+}
+
+// nextCommandArg splits the first whitespace-separated argument from the rest of the command
+func nextCommandArg(s string) (string, string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func commandResponse(text string) *model.CommandResponse {
+	return &model.CommandResponse{
+		ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL,
+		Text:         text,
+	}
+}
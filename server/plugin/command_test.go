@@ -0,0 +1,271 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExecuteShareCommand(t *testing.T) {
+	t.Run("no arguments", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/share", UserId: "user_id", TeamId: "team_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, model.COMMAND_RESPONSE_TYPE_EPHEMERAL, response.ResponseType)
+		assert.Contains(t, response.Text, "Usage: `/share")
+	})
+	t.Run("share is disabled", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableShare: false, EnableMove: true})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/share ~off-topic", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "Sharing posts is disabled on this server.", response.Text)
+		api.AssertNotCalled(t, "GetChannelByName", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("channel not found", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "unknown", false).Return(nil, &model.AppError{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/share ~unknown", UserId: "user_id", TeamId: "team_id", RootId: "post_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "Channel ~unknown was not found.", response.Text)
+	})
+	t.Run("no post to share", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/share ~off-topic some note", UserId: "user_id", TeamId: "team_id"})
+
+		assert.Nil(t, appErr)
+		assert.Contains(t, response.Text, "Usage: `/share")
+	})
+	t.Run("post in unreadable channel", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "private_channel_id"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "private_channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/share ~off-topic http://localhost:8065/team/pl/post_id", UserId: "user_id", TeamId: "team_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "The post to share was not found.", response.Text)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("share post by permalink", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockNoCollectionRoot(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("to_channel_id", post.ChannelId)
+			assert.Equal("some note\n\n", post.GetProp(postPropsKeyAdditionalText))
+			post.Id = "new_post_id"
+			return post
+		}, nil)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/share ~off-topic http://localhost:8065/team/pl/post_id some note", UserId: "user_id", TeamId: "team_id", ChannelId: "other_channel_id"})
+
+		assert.Nil(appErr)
+		assert.Equal("", response.Text)
+	})
+	t.Run("rate limited", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableShare: true, ShareRateLimitPerMinute: 1})
+		p.shareRateLimiter = newRateLimiter(time.Minute)
+		p.shareRateLimiter.allow("user_id", 1, time.Now())
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/share ~off-topic http://localhost:8065/team/pl/post_id", UserId: "user_id", TeamId: "team_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "You're sharing too fast, please slow down.", response.Text)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestExecuteMoveCommand(t *testing.T) {
+	t.Run("no arguments", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move", UserId: "user_id", TeamId: "team_id"})
+
+		assert.Nil(t, appErr)
+		assert.Contains(t, response.Text, "Usage: `/move")
+	})
+	t.Run("move is disabled", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: false})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move ~off-topic", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "Moving posts is disabled on this server.", response.Text)
+		api.AssertNotCalled(t, "GetChannelByName", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("post in a thread", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "root_id", ChannelId: "channel_id", UserId: "user_id"})
+		postList.AddPost(&model.Post{Id: "reply_id", ChannelId: "channel_id", UserId: "user_id", RootId: "root_id"})
+		api.On("GetPostThread", "root_id").Return(postList, nil)
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move ~off-topic", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id", RootId: "root_id"})
+
+		assert.Nil(t, appErr)
+		assert.Contains(t, response.Text, "cannot be moved by the command")
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("confirm moving last post", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		channelPosts := model.NewPostList()
+		channelPosts.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		channelPosts.AddOrder("post_id")
+		api.On("GetPostsForChannel", "channel_id", 0, lastPostSearchLimit).Return(channelPosts, nil)
+		api.On("GetPostThread", "post_id").Return(channelPosts, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("KVGet", "skip_move_confirmation_user_id").Return(nil, nil)
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move ~off-topic", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "This will delete the original post http://localhost:8065/_redirect/pl/post_id. Run `/move ~off-topic http://localhost:8065/_redirect/pl/post_id --confirm` to move it.", response.Text)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("move post by permalink with confirmation", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		post := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		mockMovePost(api, post)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+
+		// Other posts may have been posted since the confirmation, so the last post isn't looked up
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move ~off-topic http://localhost:8065/_redirect/pl/post_id --confirm", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "", response.Text)
+		api.AssertCalled(t, "DeletePost", "post_id")
+		api.AssertNotCalled(t, "GetPostsForChannel", mock.Anything, mock.Anything, mock.Anything)
+		api.AssertNotCalled(t, "KVGet", "skip_move_confirmation_user_id")
+	})
+	t.Run("invalid argument", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move ~off-topic --force", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id"})
+
+		assert.Nil(t, appErr)
+		assert.Contains(t, response.Text, "Usage: `/move")
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("rate limited", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableMove: true, ShareRateLimitPerMinute: 1})
+		p.shareRateLimiter = newRateLimiter(time.Minute)
+		p.shareRateLimiter.allow("user_id", 1, time.Now())
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move ~off-topic --confirm", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id", RootId: "post_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "You're sharing too fast, please slow down.", response.Text)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("move last post without the confirmation by the preference", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannelByName", "team_id", "off-topic", false).Return(&model.Channel{Id: "to_channel_id", Name: "off-topic"}, nil)
+		lastPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		channelPosts := model.NewPostList()
+		channelPosts.AddPost(&model.Post{Id: "system_post_id", ChannelId: "channel_id", Type: model.POST_JOIN_CHANNEL})
+		channelPosts.AddOrder("system_post_id")
+		channelPosts.AddPost(lastPost)
+		channelPosts.AddOrder("post_id")
+		api.On("GetPostsForChannel", "channel_id", 0, lastPostSearchLimit).Return(channelPosts, nil)
+		mockMovePost(api, lastPost)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = "moved_post_id"
+			return post
+		}, nil)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		api.On("KVGet", "skip_move_confirmation_user_id").Return([]byte("true"), nil)
+
+		response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/move ~off-topic", UserId: "user_id", TeamId: "team_id", ChannelId: "channel_id"})
+
+		assert.Nil(t, appErr)
+		assert.Equal(t, "", response.Text)
+		api.AssertCalled(t, "DeletePost", "post_id")
+	})
+}
@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// redactedValue replaces the secrets in the configuration returned by handleConfig
+const redactedValue = "********"
+
+// handleConfig returns the configuration the plugin is using for system admins, keyed by the names of the settings.
+// With `team_id`, the overrides of the team are applied, so that admins can see why sharing was refused in the team.
+// Secrets, including the webhook URL which often carries a token in its path, are redacted, because the response may be pasted into issues or chats while debugging.
+func (p *SharePostPlugin) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if !p.API.HasPermissionTo(r.Header.Get("Mattermost-User-Id"), model.PERMISSION_MANAGE_SYSTEM) {
+		writeJSONError(w, http.StatusForbidden, errorCodeForbidden, "forbidden")
+		return
+	}
+
+	// Overrides of a team ID which isn't an existing team would be silently ignored, so such team IDs are refused
+	teamID := r.URL.Query().Get("team_id")
+	if teamID != "" {
+		if _, appErr := p.getTeam(teamID); appErr != nil {
+			writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "team_id is not an existing team")
+			return
+		}
+	}
+	config := p.getConfiguration().forTeam(teamID).Clone()
+	if config.EventWebhookSecret != "" {
+		config.EventWebhookSecret = redactedValue
+	}
+	if config.EventWebhookURL != "" {
+		config.EventWebhookURL = redactURL(config.EventWebhookURL)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(config); err != nil {
+		p.API.LogWarn("failed to write configuration", "error", err.Error())
+	}
+}
+
+// redactURL keeps only the scheme and the host of the URL, so that admins can still tell which endpoint is configured.
+// The credentials, the path and the query may carry tokens, so they're redacted.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return redactedValue
+	}
+	return u.Scheme + "://" + u.Host + "/" + redactedValue
+}
@@ -1,8 +1,14 @@
 package plugin
 
 import (
+	"encoding/json"
+	"net/http"
 	"reflect"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +24,91 @@ import (
 // If you add non-reference types to your configuration struct, be sure to rewrite Clone as a deep
 // copy appropriate for your types.
 type configuration struct {
+	EnableShare              bool
+	EnableMove               bool
+	EnableRedirectNote       bool
+	EnableMoveNotification   bool
+	EnableMentionsOnMove     bool
+	EnforceRetentionOnMove   bool
+	ShareMessageTemplate     string
+	PermalinkLabel           string
+	SharedPostFooter         string
+	MovedPostFooter          string
+	MaxAdditionalTextLength  int
+	RequireAdditionalText    bool
+	MaxPostSize              int
+	BroadcastMentionPolicy   string
+	UndoMoveWindowMinutes    int
+	MoveCooldownMinutes      int
+	MaxThreadMoveSize        int
+	OperationTimeoutSeconds  int
+	ShareRateLimitPerMinute  int
+	ShareDedupWindowSeconds  int
+	RestrictShareToSameTeam  bool
+	AllowedShareDestinations string
+	DeniedShareDestinations  string
+	DefaultShareChannel      string
+	CarriedOverPropKeys      string
+	AutoJoinPublicChannels   bool
+	AutoJoinOpenTeams        bool
+	TeamOverrides            string
+	EventWebhookURL          string
+	EventWebhookSecret       string
+
+	// shareMessageTemplate is parsed from ShareMessageTemplate. It's nil when the template is empty.
+	shareMessageTemplate *template.Template
+	// teamOverrides is parsed from TeamOverrides, keyed by team ID
+	teamOverrides map[string]*teamOverride
+}
+
+// teamOverride is the configuration of a team which overrides the global one. Omitted fields inherit the global configuration.
+type teamOverride struct {
+	EnableShare              *bool
+	EnableMove               *bool
+	AllowedShareDestinations *string
+	DeniedShareDestinations  *string
+}
+
+// parseTeamOverrides parses the JSON object of the configuration overrides keyed by team ID.
+// Unknown fields are refused, so that misspelled settings are noticed when saving the configuration.
+func parseTeamOverrides(value string) (map[string]*teamOverride, error) {
+	overrides := map[string]*teamOverride{}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&overrides); err != nil {
+		return nil, errors.Wrap(err, "failed to parse team overrides")
+	}
+	for teamID, override := range overrides {
+		if !model.IsValidId(teamID) {
+			return nil, errors.Errorf("team override key %q is not a valid team ID", teamID)
+		}
+		if override == nil {
+			return nil, errors.Errorf("team override of %s is empty", teamID)
+		}
+	}
+	return overrides, nil
+}
+
+// forTeam returns the configuration applied to the team, which is the global one with the overrides of the team
+func (c *configuration) forTeam(teamID string) *configuration {
+	override, ok := c.teamOverrides[teamID]
+	if !ok {
+		return c
+	}
+	config := c.Clone()
+	if override.EnableShare != nil {
+		config.EnableShare = *override.EnableShare
+	}
+	if override.EnableMove != nil {
+		config.EnableMove = *override.EnableMove
+	}
+	if override.AllowedShareDestinations != nil {
+		config.AllowedShareDestinations = *override.AllowedShareDestinations
+	}
+	if override.DeniedShareDestinations != nil {
+		config.DeniedShareDestinations = *override.DeniedShareDestinations
+	}
+	return config
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -27,10 +118,104 @@ func (c *configuration) Clone() *configuration {
 	return &clone
 }
 
+// getMaxAdditionalTextLength returns the maximum number of characters of additional text.
+// It's capped by the post size limit which is valid on all servers.
+func (c *configuration) getMaxAdditionalTextLength() int {
+	if c.MaxAdditionalTextLength <= 0 || c.MaxAdditionalTextLength > model.POST_MESSAGE_MAX_RUNES_V1 {
+		return model.POST_MESSAGE_MAX_RUNES_V1
+	}
+	return c.MaxAdditionalTextLength
+}
+
+// getMaxPostSize returns the maximum number of characters of a post message.
+// The limit of the server isn't available to plugins, so it's the limit on servers with migrated databases unless configured smaller.
+func (c *configuration) getMaxPostSize() int {
+	if c.MaxPostSize <= 0 || c.MaxPostSize > model.POST_MESSAGE_MAX_RUNES_V2 {
+		return model.POST_MESSAGE_MAX_RUNES_V2
+	}
+	return c.MaxPostSize
+}
+
+// getCarriedOverPropKeys returns the keys of the props carried over to copied/duplicated posts
+func (c *configuration) getCarriedOverPropKeys() []string {
+	keys := []string{}
+	for _, key := range strings.Split(c.CarriedOverPropKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Labels of the links to the original posts in shared posts
+const (
+	permalinkLabelOriginal       = "original"
+	permalinkLabelSharedPost     = "shared_post"
+	permalinkLabelChannelMessage = "channel_message"
+)
+
+// getPermalinkLabel returns the label of the links to the original posts, defaulting to the original format.
+func (c *configuration) getPermalinkLabel() string {
+	switch c.PermalinkLabel {
+	case permalinkLabelSharedPost, permalinkLabelChannelMessage:
+		return c.PermalinkLabel
+	}
+	return permalinkLabelOriginal
+}
+
+// Policies for @all, @channel and @here in additional text
+const (
+	broadcastMentionConfirm = "confirm"
+	broadcastMentionStrip   = "strip"
+	broadcastMentionAllow   = "allow"
+)
+
+// getBroadcastMentionPolicy returns how mentions notifying everyone in additional text are handled, defaulting to confirm.
+func (c *configuration) getBroadcastMentionPolicy() string {
+	switch c.BroadcastMentionPolicy {
+	case broadcastMentionStrip, broadcastMentionAllow:
+		return c.BroadcastMentionPolicy
+	}
+	return broadcastMentionConfirm
+}
+
+// isDestinationAllowed checks whether posts can be shared/moved to the channel from the team.
+// Entries of the allow/deny lists match either the ID of the channel or the ID of the team the channel belongs to,
+// and an empty allow list means all channels are allowed.
+func (c *configuration) isDestinationAllowed(channel *model.Channel, sourceTeamID string) bool {
+	if c.RestrictShareToSameTeam && channel.TeamId != sourceTeamID {
+		return false
+	}
+	matches := func(list string) bool {
+		for _, id := range parseChannelIDs(list) {
+			if id == channel.Id || (channel.TeamId != "" && id == channel.TeamId) {
+				return true
+			}
+		}
+		return false
+	}
+	if matches(c.DeniedShareDestinations) {
+		return false
+	}
+	return len(parseChannelIDs(c.AllowedShareDestinations)) == 0 || matches(c.AllowedShareDestinations)
+}
+
+// getMessageRetention returns the message retention period of the server's data retention policy.
+// It returns zero when the policy doesn't delete messages.
+func getMessageRetention(serverConfig *model.Config) time.Duration {
+	if serverConfig == nil {
+		return 0
+	}
+	settings := serverConfig.DataRetentionSettings
+	if settings.EnableMessageDeletion == nil || !*settings.EnableMessageDeletion || settings.MessageRetentionDays == nil || *settings.MessageRetentionDays <= 0 {
+		return 0
+	}
+	return time.Duration(*settings.MessageRetentionDays) * 24 * time.Hour
+}
+
 // getConfiguration retrieves the active configuration under lock, making it safe to use
 // concurrently. The active configuration may change underneath the client of this method, but
 // the struct returned by this API call is considered immutable.
-/*
 func (p *SharePostPlugin) getConfiguration() *configuration {
 	p.configurationLock.RLock()
 	defer p.configurationLock.RUnlock()
@@ -41,7 +226,6 @@ func (p *SharePostPlugin) getConfiguration() *configuration {
 
 	return p.configuration
 }
-*/
 
 // setConfiguration replaces the active configuration under lock.
 //
@@ -80,7 +264,50 @@ func (p *SharePostPlugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	if configuration.ShareMessageTemplate != "" {
+		tmpl, err := template.New("share_message").Parse(configuration.ShareMessageTemplate)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse share message template")
+		}
+		configuration.shareMessageTemplate = tmpl
+	}
+
+	if strings.TrimSpace(configuration.TeamOverrides) != "" {
+		overrides, err := parseTeamOverrides(configuration.TeamOverrides)
+		if err != nil {
+			return err
+		}
+		configuration.teamOverrides = overrides
+	}
+
+	if channelID := configuration.DefaultShareChannel; channelID != "" {
+		if !model.IsValidId(channelID) {
+			return errors.Errorf("default share channel %q is not a valid channel ID", channelID)
+		}
+		if err := p.validateDefaultShareChannel(channelID); err != nil {
+			return err
+		}
+	}
+
 	p.setConfiguration(configuration)
 
 	return nil
 }
+
+// validateDefaultShareChannel checks that the default destination exists, so that a wrong channel is refused when saving
+// the configuration rather than noticed when sharing. The channel can be archived after saving, so an archived channel
+// is only logged not to stop the plugin, and it's ignored when sharing until it's fixed.
+func (p *SharePostPlugin) validateDefaultShareChannel(channelID string) error {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		if appErr.StatusCode == http.StatusNotFound {
+			return errors.Errorf("default share channel %s does not exist", channelID)
+		}
+		p.API.LogError("failed to get default share channel", "channel_id", channelID, "error", appErr.Error())
+		return nil
+	}
+	if channel.DeleteAt != 0 {
+		p.API.LogError("default share channel is archived", "channel_id", channelID)
+	}
+	return nil
+}
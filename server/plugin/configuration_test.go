@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOnConfigurationChange(t *testing.T) {
+	for name, test := range map[string]struct {
+		Template      string
+		ShouldError   bool
+		ParsedPresent bool
+	}{
+		"empty template":   {Template: "", ShouldError: false, ParsedPresent: false},
+		"valid template":   {Template: "Shared from {{.Permalink}}", ShouldError: false, ParsedPresent: true},
+		"invalid template": {Template: "Shared from {{.Permalink", ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			p := &SharePostPlugin{}
+			p.SetAPI(api)
+
+			api.On("GetConfig").Return(&model.Config{})
+			api.On("LoadPluginConfiguration", mock.AnythingOfType("*plugin.configuration")).Return(func(dest interface{}) error {
+				dest.(*configuration).ShareMessageTemplate = test.Template
+				return nil
+			})
+
+			err := p.OnConfigurationChange()
+			if test.ShouldError {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(test.ParsedPresent, p.getConfiguration().shareMessageTemplate != nil)
+		})
+	}
+}
+
+func TestOnConfigurationChangeDefaultShareChannel(t *testing.T) {
+	channelID := model.NewId()
+	// Archived channels are only logged, because the plugin must start even if the channel is archived after saving
+	for name, test := range map[string]struct {
+		Channel     string
+		Found       *model.Channel
+		StatusCode  int
+		ShouldError bool
+		ShouldLog   bool
+	}{
+		"existing channel": {Channel: channelID, Found: &model.Channel{Id: channelID}},
+		"invalid ID":       {Channel: "town-square", ShouldError: true},
+		"not found":        {Channel: channelID, StatusCode: http.StatusNotFound, ShouldError: true},
+		"server error":     {Channel: channelID, StatusCode: http.StatusInternalServerError, ShouldLog: true},
+		"archived channel": {Channel: channelID, Found: &model.Channel{Id: channelID, DeleteAt: 1000}, ShouldLog: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			defer api.AssertExpectations(t)
+			p := &SharePostPlugin{}
+			p.SetAPI(api)
+			api.On("GetConfig").Return(&model.Config{})
+			api.On("LoadPluginConfiguration", mock.AnythingOfType("*plugin.configuration")).Return(func(dest interface{}) error {
+				dest.(*configuration).DefaultShareChannel = test.Channel
+				return nil
+			})
+			if test.Found != nil {
+				api.On("GetChannel", channelID).Return(test.Found, nil)
+			} else if test.StatusCode != 0 {
+				api.On("GetChannel", channelID).Return(nil, &model.AppError{Message: "failed", StatusCode: test.StatusCode})
+			}
+			if test.ShouldLog {
+				api.On("LogError", mock.AnythingOfType("string"), "channel_id", channelID).Return().Maybe()
+				api.On("LogError", mock.AnythingOfType("string"), "channel_id", channelID, "error", mock.AnythingOfType("string")).Return().Maybe()
+			}
+
+			err := p.OnConfigurationChange()
+
+			assert.Equal(t, test.ShouldError, err != nil)
+			if !test.ShouldError {
+				assert.Equal(t, channelID, p.getConfiguration().DefaultShareChannel)
+			}
+			if test.ShouldLog {
+				api.AssertNumberOfCalls(t, "LogError", 1)
+			}
+		})
+	}
+}
+
+func TestOnConfigurationChangeTeamOverrides(t *testing.T) {
+	teamID := model.NewId()
+	for name, test := range map[string]struct {
+		Overrides   string
+		ShouldError bool
+	}{
+		"empty":             {Overrides: "", ShouldError: false},
+		"valid overrides":   {Overrides: `{"` + teamID + `": {"EnableMove": false, "AllowedShareDestinations": "channel_id"}}`, ShouldError: false},
+		"invalid JSON":      {Overrides: `{"` + teamID + `": {"EnableMove": false`, ShouldError: true},
+		"not an object":     {Overrides: `["` + teamID + `"]`, ShouldError: true},
+		"invalid team ID":   {Overrides: `{"team-name": {"EnableMove": false}}`, ShouldError: true},
+		"unknown setting":   {Overrides: `{"` + teamID + `": {"EnableCopy": false}}`, ShouldError: true},
+		"wrong value type":  {Overrides: `{"` + teamID + `": {"EnableMove": "false"}}`, ShouldError: true},
+		"null team setting": {Overrides: `{"` + teamID + `": null}`, ShouldError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			p := &SharePostPlugin{}
+			p.SetAPI(api)
+			api.On("GetConfig").Return(&model.Config{})
+			api.On("LoadPluginConfiguration", mock.AnythingOfType("*plugin.configuration")).Return(func(dest interface{}) error {
+				dest.(*configuration).TeamOverrides = test.Overrides
+				return nil
+			})
+
+			err := p.OnConfigurationChange()
+
+			assert.Equal(test.ShouldError, err != nil)
+			if !test.ShouldError && test.Overrides != "" {
+				assert.Len(p.getConfiguration().teamOverrides, 1)
+			}
+		})
+	}
+}
+
+func TestForTeam(t *testing.T) {
+	disabled := false
+	allowed := "team_channel_id"
+	config := &configuration{
+		EnableShare:              true,
+		EnableMove:               true,
+		AllowedShareDestinations: "global_channel_id",
+		DeniedShareDestinations:  "denied_channel_id",
+		teamOverrides: map[string]*teamOverride{
+			"team_id": {EnableMove: &disabled, AllowedShareDestinations: &allowed},
+		},
+	}
+
+	t.Run("team with overrides", func(t *testing.T) {
+		assert := assert.New(t)
+		teamConfig := config.forTeam("team_id")
+
+		assert.True(teamConfig.EnableShare)
+		assert.False(teamConfig.EnableMove)
+		assert.Equal("team_channel_id", teamConfig.AllowedShareDestinations)
+		assert.Equal("denied_channel_id", teamConfig.DeniedShareDestinations)
+		// The global configuration is not changed
+		assert.True(config.EnableMove)
+		assert.Equal("global_channel_id", config.AllowedShareDestinations)
+	})
+	t.Run("team without overrides", func(t *testing.T) {
+		assert.Equal(t, config, config.forTeam("other_team_id"))
+	})
+}
+
+func TestIsDestinationAllowed(t *testing.T) {
+	channel := &model.Channel{Id: "channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}
+	dm := &model.Channel{Id: "dm_channel_id", Type: model.CHANNEL_DIRECT}
+
+	for name, test := range map[string]struct {
+		Configuration *configuration
+		Channel       *model.Channel
+		Expected      bool
+	}{
+		"no restriction":          {Configuration: &configuration{}, Channel: channel, Expected: true},
+		"same team":               {Configuration: &configuration{RestrictShareToSameTeam: true}, Channel: channel, Expected: true},
+		"direct message in team":  {Configuration: &configuration{RestrictShareToSameTeam: true}, Channel: dm, Expected: false},
+		"allowed by channel":      {Configuration: &configuration{AllowedShareDestinations: "other_id, channel_id"}, Channel: channel, Expected: true},
+		"allowed by team":         {Configuration: &configuration{AllowedShareDestinations: "team_id"}, Channel: channel, Expected: true},
+		"not in allow list":       {Configuration: &configuration{AllowedShareDestinations: "other_id"}, Channel: channel, Expected: false},
+		"denied by channel":       {Configuration: &configuration{DeniedShareDestinations: "channel_id"}, Channel: channel, Expected: false},
+		"denied by team":          {Configuration: &configuration{DeniedShareDestinations: "team_id"}, Channel: channel, Expected: false},
+		"deny list has priority":  {Configuration: &configuration{AllowedShareDestinations: "team_id", DeniedShareDestinations: "channel_id"}, Channel: channel, Expected: false},
+		"direct message no match": {Configuration: &configuration{DeniedShareDestinations: "team_id"}, Channel: dm, Expected: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, test.Configuration.isDestinationAllowed(test.Channel, "team_id"))
+		})
+	}
+}
+
+func TestGetMaxAdditionalTextLength(t *testing.T) {
+	assert.Equal(t, 1000, (&configuration{MaxAdditionalTextLength: 1000}).getMaxAdditionalTextLength())
+	assert.Equal(t, model.POST_MESSAGE_MAX_RUNES_V1, (&configuration{}).getMaxAdditionalTextLength())
+	assert.Equal(t, model.POST_MESSAGE_MAX_RUNES_V1, (&configuration{MaxAdditionalTextLength: 100000}).getMaxAdditionalTextLength())
+}
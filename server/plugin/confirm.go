@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	confirmMoveKey      = "confirm_move"
+	skipConfirmationKey = "skip_move_confirmation"
+
+	// skipConfirmationKeyPrefix is the prefix of KV store keys for the "don't ask again" preference.
+	// Keys are `skip_move_confirmation_<user id>`.
+	skipConfirmationKeyPrefix = "skip_move_confirmation_"
+)
+
+func makeSkipConfirmationKey(userID string) string {
+	return skipConfirmationKeyPrefix + userID
+}
+
+// confirmMove returns the dialog response asking the user to confirm moving the post, or nil if the move can proceed.
+// Interactive dialogs can't open another dialog on submission, so the confirmation is shown as an error of the
+// `confirm_move` element and the dialog is submitted again with the element checked.
+func (p *SharePostPlugin) confirmMove(T localizer, request *model.SubmitDialogRequest) *model.SubmitDialogResponse {
+	confirmed, _ := request.Submission[confirmMoveKey].(bool)
+	skip, hasSkip := request.Submission[skipConfirmationKey].(bool)
+	switch {
+	case confirmed && skip:
+		if appErr := p.API.KVSet(makeSkipConfirmationKey(request.UserId), []byte("true")); appErr != nil {
+			p.API.LogWarn("failed to save the preference for move confirmation", "user_id", request.UserId, "error", appErr.Error())
+		}
+	case hasSkip && !skip:
+		// The dialog checks "don't ask again" while the preference is saved, so unchecking it asks for the confirmation again
+		if appErr := p.API.KVDelete(makeSkipConfirmationKey(request.UserId)); appErr != nil {
+			p.API.LogWarn("failed to delete the preference for move confirmation", "user_id", request.UserId, "error", appErr.Error())
+		}
+	case !confirmed && p.skipsMoveConfirmation(request.UserId):
+		return nil
+	}
+	if confirmed {
+		return nil
+	}
+	return dialogFieldError(confirmMoveKey, T("move.confirm"))
+}
+
+// skipsMoveConfirmation returns true if the user has chosen to move posts without the confirmation
+func (p *SharePostPlugin) skipsMoveConfirmation(userID string) bool {
+	b, appErr := p.API.KVGet(makeSkipConfirmationKey(userID))
+	if appErr != nil {
+		// Asking again is safer than moving without the confirmation
+		p.API.LogWarn("failed to get the preference for move confirmation", "user_id", userID, "error", appErr.Error())
+	}
+	return b != nil
+}
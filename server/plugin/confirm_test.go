@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestConfirmMove(t *testing.T) {
+	t.Run("ask to confirm", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("KVGet", "skip_move_confirmation_user_id").Return(nil, nil)
+
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: map[string]interface{}{
+				toChannelKey: "to_channel_id",
+				shareTypeKey: shareTypeMove,
+			},
+		}
+		msg, response, err := p.handleSharePost(context.Background(), map[string]string{}, request)
+
+		assert.Nil(msg)
+		assert.Contains(response.Errors[confirmMoveKey], "This will delete the original post.")
+		assert.Nil(err)
+		api.AssertNotCalled(t, "GetPostThread", mock.Anything)
+	})
+	t.Run("confirmed", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		request := &model.SubmitDialogRequest{
+			UserId:     "user_id",
+			Submission: map[string]interface{}{confirmMoveKey: true},
+		}
+
+		assert.Nil(t, p.confirmMove(p.getLocalizer("user_id"), request))
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+	t.Run("confirmed with don't ask again", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVSet", "skip_move_confirmation_user_id", []byte("true")).Return(nil)
+
+		request := &model.SubmitDialogRequest{
+			UserId:     "user_id",
+			Submission: map[string]interface{}{confirmMoveKey: true, skipConfirmationKey: true},
+		}
+
+		assert.Nil(t, p.confirmMove(p.getLocalizer("user_id"), request))
+	})
+	t.Run("confirmation is skipped by the preference", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", "skip_move_confirmation_user_id").Return([]byte("true"), nil)
+
+		request := &model.SubmitDialogRequest{
+			UserId:     "user_id",
+			Submission: map[string]interface{}{},
+		}
+
+		assert.Nil(t, p.confirmMove(p.getLocalizer("user_id"), request))
+	})
+	t.Run("unchecking don't ask again asks for the confirmation again", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVDelete", "skip_move_confirmation_user_id").Return(nil)
+
+		request := &model.SubmitDialogRequest{
+			UserId:     "user_id",
+			Submission: map[string]interface{}{confirmMoveKey: false, skipConfirmationKey: false},
+		}
+
+		response := p.confirmMove(p.getLocalizer("user_id"), request)
+		assert.Contains(t, response.Errors[confirmMoveKey], "This will delete the original post.")
+		api.AssertNotCalled(t, "KVGet", mock.Anything)
+	})
+}
@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// createdPostsKey is the context key of the posts created while handling a request
+type createdPostsKey struct{}
+
+// createdPost is the post created by sharing/moving, which is returned to API clients so that they can chain actions on it
+type createdPost struct {
+	PostID    string `json:"post_id"`
+	ChannelID string `json:"channel_id"`
+	Permalink string `json:"permalink"`
+}
+
+// submitDialogResult is the body of the response to API clients when sharing/moving succeeds
+type submitDialogResult struct {
+	Posts []createdPost `json:"posts"`
+}
+
+// createdPosts collects the posts created while handling a request.
+// Posts are added concurrently when sharing to multiple channels.
+type createdPosts struct {
+	mu    sync.Mutex
+	posts []createdPost
+}
+
+// withCreatedPosts returns the context collecting the posts created with it
+func withCreatedPosts(ctx context.Context) (context.Context, *createdPosts) {
+	created := &createdPosts{}
+	return context.WithValue(ctx, createdPostsKey{}, created), created
+}
+
+// addCreatedPost adds the post to the collection of the context. It does nothing if the context doesn't collect posts.
+func addCreatedPost(ctx context.Context, post *model.Post, permalink string) {
+	created, ok := ctx.Value(createdPostsKey{}).(*createdPosts)
+	if !ok {
+		return
+	}
+	created.mu.Lock()
+	defer created.mu.Unlock()
+	created.posts = append(created.posts, createdPost{
+		PostID:    post.Id,
+		ChannelID: post.ChannelId,
+		Permalink: permalink,
+	})
+}
+
+// list returns the collected posts
+func (c *createdPosts) list() []createdPost {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]createdPost{}, c.posts...)
+}
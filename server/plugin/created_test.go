@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAddCreatedPost(t *testing.T) {
+	t.Run("collect posts", func(t *testing.T) {
+		ctx, created := withCreatedPosts(context.Background())
+		addCreatedPost(ctx, &model.Post{Id: "post_id1", ChannelId: "channel_id1"}, "http://localhost:8065/team/pl/post_id1")
+		addCreatedPost(ctx, &model.Post{Id: "post_id2", ChannelId: "channel_id2"}, "http://localhost:8065/team/pl/post_id2")
+
+		assert.Equal(t, []createdPost{
+			{PostID: "post_id1", ChannelID: "channel_id1", Permalink: "http://localhost:8065/team/pl/post_id1"},
+			{PostID: "post_id2", ChannelID: "channel_id2", Permalink: "http://localhost:8065/team/pl/post_id2"},
+		}, created.list())
+	})
+	t.Run("context without collection", func(t *testing.T) {
+		// Callers like the slash commands don't collect the created posts
+		addCreatedPost(context.Background(), &model.Post{Id: "post_id"}, "")
+	})
+}
+
+func TestSubmitDialogResult(t *testing.T) {
+	request := `{"user_id":"user_id","channel_id":"channel_id"}`
+	serve := func(p *SharePostPlugin, handler submitDialogHandler) *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/share", strings.NewReader(request))
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		p.handleSubmitDialogRequest(handler)(w, r)
+		return w.Result()
+	}
+
+	t.Run("return created posts", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		result := serve(p, func(ctx context.Context, _ map[string]string, _ *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+			addCreatedPost(ctx, &model.Post{Id: "new_post_id", ChannelId: "to_channel_id"}, "http://localhost:8065/team/pl/new_post_id")
+			return nil, nil, nil
+		})
+		defer result.Body.Close()
+
+		assert.Equal(http.StatusOK, result.StatusCode)
+		assert.Equal("application/json", result.Header.Get("Content-Type"))
+		var body submitDialogResult
+		assert.Nil(json.NewDecoder(result.Body).Decode(&body))
+		assert.Equal(submitDialogResult{Posts: []createdPost{
+			{PostID: "new_post_id", ChannelID: "to_channel_id", Permalink: "http://localhost:8065/team/pl/new_post_id"},
+		}}, body)
+	})
+	t.Run("keep summary as ephemeral post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		result := serve(p, func(ctx context.Context, _ map[string]string, _ *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+			addCreatedPost(ctx, &model.Post{Id: "new_post_id", ChannelId: "to_channel_id"}, "")
+			return toPtr("Shared to 1 of 2 channels."), nil, nil
+		})
+		defer result.Body.Close()
+
+		var body submitDialogResult
+		assert.Nil(json.NewDecoder(result.Body).Decode(&body))
+		assert.Len(body.Posts, 1)
+	})
+	t.Run("dialog errors take precedence", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		result := serve(p, func(ctx context.Context, _ map[string]string, _ *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+			return nil, dialogFieldError(toChannelKey, "Please select a channel."), nil
+		})
+		defer result.Body.Close()
+
+		var body model.SubmitDialogResponse
+		assert.Nil(json.NewDecoder(result.Body).Decode(&body))
+		assert.Equal(map[string]string{toChannelKey: "Please select a channel."}, body.Errors)
+	})
+	t.Run("nothing created", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		result := serve(p, func(ctx context.Context, _ map[string]string, _ *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+			return toPtr("Something went wrong. Please try again later."), nil, nil
+		})
+		defer result.Body.Close()
+
+		assert.Equal(http.StatusOK, result.StatusCode)
+		assert.Equal("", result.Header.Get("Content-Type"))
+	})
+}
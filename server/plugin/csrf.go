@@ -0,0 +1,88 @@
+package plugin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// csrfSecretKey is the KV store key of the secret to issue CSRF tokens.
+	// It's stored so that the tokens in opened dialogs and undo buttons stay valid after restarting the plugin.
+	csrfSecretKey = "csrf_secret"
+
+	// csrfContextKey is the key of the CSRF token in the context of message attachment buttons
+	csrfContextKey = "csrf_token"
+)
+
+// csrfExemptPaths are state-changing endpoints which don't need the CSRF check.
+// The "View original" button only replies the link, and the card is seen by users other than the one it's issued for.
+var csrfExemptPaths = map[string]bool{
+	"/api/v1/view_original": true,
+}
+
+// ensureCSRFSecret loads the secret to issue CSRF tokens, or generates it if not stored yet.
+// The secret is stored with compare-and-set, so that all servers in a cluster use the same secret.
+func (p *SharePostPlugin) ensureCSRFSecret() error {
+	secret, appErr := p.API.KVGet(csrfSecretKey)
+	if appErr != nil {
+		return fmt.Errorf("failed to get CSRF secret %w", appErr)
+	}
+	if secret == nil {
+		generated := []byte(model.NewId() + model.NewId())
+		ok, appErr := p.API.KVCompareAndSet(csrfSecretKey, nil, generated)
+		if appErr != nil {
+			return fmt.Errorf("failed to save CSRF secret %w", appErr)
+		}
+		if ok {
+			secret = generated
+		} else if secret, appErr = p.API.KVGet(csrfSecretKey); appErr != nil {
+			// Another server has stored the secret first
+			return fmt.Errorf("failed to get CSRF secret %w", appErr)
+		}
+	}
+	p.csrfSecret = secret
+	return nil
+}
+
+// makeCSRFToken returns the CSRF token of the user. The token is the HMAC of the user ID, so it doesn't need to be stored.
+func (p *SharePostPlugin) makeCSRFToken(userID string) string {
+	mac := hmac.New(sha256.New, p.csrfSecret)
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readCSRFToken reads the CSRF token from the state of the dialog submission, or the context of the button.
+// It returns an empty string if the body doesn't have the token.
+func readCSRFToken(body []byte) string {
+	var request struct {
+		State   string                 `json:"state"`
+		Context map[string]interface{} `json:"context"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return ""
+	}
+	if token, ok := request.Context[csrfContextKey].(string); ok {
+		return token
+	}
+	return request.State
+}
+
+// checkCSRF checks that the state-changing request is not forged by another site.
+// Requests from the webapp have the `X-Requested-With` header, which other sites can't set without CORS.
+// Dialog submissions and button clicks are sent by the server without the header, so they need the token issued
+// by the plugin in the state of the dialog or the context of the button.
+func (p *SharePostPlugin) checkCSRF(r *http.Request, userID, token string) bool {
+	if r.Header.Get(model.HEADER_REQUESTED_WITH) == model.HEADER_REQUESTED_WITH_XML {
+		return true
+	}
+	if len(p.csrfSecret) == 0 || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(p.makeCSRFToken(userID)))
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckCSRF(t *testing.T) {
+	p := setupTestPlugin(&plugintest.API{})
+	token := p.makeCSRFToken("user_id")
+
+	for name, test := range map[string]struct {
+		Header   string
+		UserID   string
+		Token    string
+		Expected bool
+	}{
+		"requested by the webapp": {Header: "XMLHttpRequest", UserID: "user_id", Expected: true},
+		"valid token":             {UserID: "user_id", Token: token, Expected: true},
+		"token of other user":     {UserID: "other_user_id", Token: token, Expected: false},
+		"invalid token":           {UserID: "user_id", Token: "invalid", Expected: false},
+		"without token":           {UserID: "user_id", Expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/share", nil)
+			if test.Header != "" {
+				r.Header.Set("X-Requested-With", test.Header)
+			}
+			assert.Equal(t, test.Expected, p.checkCSRF(r, test.UserID, test.Token))
+		})
+	}
+	t.Run("without secret", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		p.csrfSecret = nil
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/share", nil)
+		assert.False(t, p.checkCSRF(r, "user_id", p.makeCSRFToken("user_id")))
+	})
+}
+
+func TestReadCSRFToken(t *testing.T) {
+	assert.Equal(t, "token", readCSRFToken([]byte(`{"state":"token"}`)))
+	assert.Equal(t, "token", readCSRFToken([]byte(`{"context":{"csrf_token":"token"}}`)))
+	assert.Equal(t, "", readCSRFToken([]byte(`{"context":{"csrf_token":1}}`)))
+	assert.Equal(t, "", readCSRFToken([]byte(`invalid`)))
+}
+
+func TestEnsureCSRFSecret(t *testing.T) {
+	t.Run("stored secret", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", csrfSecretKey).Return([]byte("stored"), nil)
+
+		assert.Nil(t, p.ensureCSRFSecret())
+		assert.Equal(t, []byte("stored"), p.csrfSecret)
+	})
+	t.Run("generate secret", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", csrfSecretKey).Return(nil, nil)
+		api.On("KVCompareAndSet", csrfSecretKey, []byte(nil), mock.AnythingOfType("[]uint8")).Return(true, nil)
+
+		assert.Nil(t, p.ensureCSRFSecret())
+		assert.Len(t, p.csrfSecret, 52)
+	})
+	t.Run("stored by other server", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", csrfSecretKey).Return(nil, nil).Once()
+		api.On("KVCompareAndSet", csrfSecretKey, []byte(nil), mock.AnythingOfType("[]uint8")).Return(false, nil)
+		api.On("KVGet", csrfSecretKey).Return([]byte("stored"), nil).Once()
+
+		assert.Nil(t, p.ensureCSRFSecret())
+		assert.Equal(t, []byte("stored"), p.csrfSecret)
+	})
+	t.Run("failed to get secret", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		api.On("KVGet", csrfSecretKey).Return(nil, &model.AppError{})
+
+		assert.NotNil(t, p.ensureCSRFSecret())
+	})
+}
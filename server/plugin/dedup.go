@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// shareDedupKeyPrefix is the prefix of KV store keys for recent shares.
+// IDs are hashed because three IDs don't fit in the key length limit.
+const shareDedupKeyPrefix = "share_dedup_"
+
+func makeShareDedupKey(userID, postID, toChannel string) string {
+	sum := sha256.Sum256([]byte(userID + postID + toChannel))
+	return shareDedupKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// reserveShare records that the user is sharing the post to the channel, and returns false if it's already shared
+// within the dedup window, so that double-clicking or re-running a share doesn't create duplicate posts.
+// The record is set atomically only when there is none, so only one of the requests at the same moment passes,
+// and it expires after the window. Deduplication is best-effort, so failures of the KV store don't block sharing.
+func (p *SharePostPlugin) reserveShare(userID, postID, toChannel string) bool {
+	window := time.Duration(p.getConfiguration().ShareDedupWindowSeconds) * time.Second
+	if window <= 0 {
+		return true
+	}
+	value := []byte(strconv.FormatInt(model.GetMillis(), 10))
+	ok, appErr := p.API.KVSetWithOptions(makeShareDedupKey(userID, postID, toChannel), value, model.PluginKVSetOptions{
+		Atomic:          true,
+		ExpireInSeconds: int64(window / time.Second),
+	})
+	if appErr != nil {
+		p.API.LogWarn("failed to save recent share", "post_id", postID, "error", appErr.Error())
+		return true
+	}
+	return ok
+}
+
+// releaseShare removes the record of reserveShare when sharing fails, so that the user can retry at once.
+func (p *SharePostPlugin) releaseShare(userID, postID, toChannel string) {
+	if p.getConfiguration().ShareDedupWindowSeconds <= 0 {
+		return
+	}
+	if appErr := p.API.KVDelete(makeShareDedupKey(userID, postID, toChannel)); appErr != nil {
+		p.API.LogWarn("failed to delete recent share", "post_id", postID, "error", appErr.Error())
+	}
+}
@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReserveShare(t *testing.T) {
+	key := makeShareDedupKey("user_id", "post_id", "to_channel_id")
+	assert.True(t, len(key) <= model.KEY_VALUE_KEY_MAX_RUNES)
+
+	t.Run("disabled", func(t *testing.T) {
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		assert.True(t, p.reserveShare("user_id", "post_id", "to_channel_id"))
+		assert.True(t, p.reserveShare("user_id", "post_id", "to_channel_id"))
+		api.AssertNotCalled(t, "KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("first share", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{ShareDedupWindowSeconds: 30})
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true, ExpireInSeconds: 30}).Return(true, nil)
+
+		assert.True(t, p.reserveShare("user_id", "post_id", "to_channel_id"))
+	})
+	t.Run("shared within the window", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{ShareDedupWindowSeconds: 30})
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), model.PluginKVSetOptions{Atomic: true, ExpireInSeconds: 30}).Return(false, nil)
+
+		assert.False(t, p.reserveShare("user_id", "post_id", "to_channel_id"))
+	})
+	t.Run("other users and channels are not deduplicated", func(t *testing.T) {
+		assert.NotEqual(t, key, makeShareDedupKey("other_user_id", "post_id", "to_channel_id"))
+		assert.NotEqual(t, key, makeShareDedupKey("user_id", "post_id", "other_channel_id"))
+	})
+	t.Run("KV store failure doesn't block sharing", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{ShareDedupWindowSeconds: 30})
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).Return(false, &model.AppError{Message: "failed"})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		assert.True(t, p.reserveShare("user_id", "post_id", "to_channel_id"))
+	})
+}
+
+func TestSharePostDedup(t *testing.T) {
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+	}
+	// setup mocks the KV store with a map, so that recent shares can be expired by deleting them
+	setup := func(api *plugintest.API) map[string][]byte {
+		kv := map[string][]byte{}
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetUser", "author_id").Return(&model.User{Id: "author_id", Username: "author"}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return kv[key] }, nil)
+		isDedupKey := func(key string) bool { return strings.HasPrefix(key, shareDedupKeyPrefix) }
+		api.On("KVSetWithOptions", mock.MatchedBy(isDedupKey), mock.AnythingOfType("[]uint8"), mock.AnythingOfType("model.PluginKVSetOptions")).Return(func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			if _, ok := kv[key]; ok {
+				return false
+			}
+			kv[key] = value
+			return true
+		}, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		return kv
+	}
+
+	t.Run("share again within the window and after it", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		p.setConfiguration(&configuration{ShareDedupWindowSeconds: 30})
+		kv := setup(api)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = model.NewId()
+			return post
+		}, nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		msg, _, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+		assert.Nil(msg)
+		assert.Nil(err)
+
+		msg, _, err = p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+		assert.Equal("You already shared this post to ~off-topic a moment ago.", *msg)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+
+		// The record expires when the window elapses
+		delete(kv, makeShareDedupKey("user_id", "post_id", "to_channel_id"))
+		msg, _, err = p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+		assert.Nil(msg)
+		assert.Nil(err)
+		api.AssertNumberOfCalls(t, "CreatePost", 2)
+	})
+	t.Run("retry after failure is not deduplicated", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		p.setConfiguration(&configuration{ShareDedupWindowSeconds: 30})
+		kv := setup(api)
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+			delete(kv, args.String(0))
+		})
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, &model.AppError{Message: "failed", StatusCode: http.StatusBadRequest}).Once()
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = model.NewId()
+			return post
+		}, nil).Once()
+		api.On("LogWarn", GetMockArgumentsWithType("string", 13)...).Return()
+
+		msg, _, err := p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+		assert.Equal("Something went wrong. Please try again later.", *msg)
+		assert.NotNil(err)
+
+		msg, _, err = p.sharePost(context.Background(), request, "to_channel_id", "", "", renderModeQuote, textPositionAbove, false, false, false)
+		assert.Nil(msg)
+		assert.Nil(err)
+	})
+}
@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// healthStatus is the response of the self-check endpoint
+type healthStatus struct {
+	OK       bool     `json:"ok"`
+	Problems []string `json:"problems"`
+}
+
+// checkHealth checks the configuration and the state the plugin needs to work, and returns the problems found.
+// Permissions of users are checked when they share/move posts, because they depend on the channels.
+func (p *SharePostPlugin) checkHealth() []string {
+	problems := []string{}
+	if _, err := p.getSiteURL(); err != nil {
+		problems = append(problems, "Site URL is not configured, so links to posts can't be made.")
+	}
+
+	if p.botUserID == "" {
+		problems = append(problems, "The bot account is not created.")
+	} else if bot, appErr := p.API.GetBot(p.botUserID, true); appErr != nil {
+		problems = append(problems, "The bot account can't be found: "+appErr.Error())
+	} else if bot.DeleteAt != 0 {
+		problems = append(problems, "The bot account is disabled.")
+	}
+
+	// Dialogs and buttons are refused without the secret to verify CSRF tokens
+	if len(p.csrfSecret) == 0 {
+		problems = append(problems, "The secret of CSRF tokens is not loaded, so dialogs can't be submitted.")
+	}
+	if _, appErr := p.API.KVGet(csrfSecretKey); appErr != nil {
+		problems = append(problems, "The KV store is not available: "+appErr.Error())
+	}
+	return problems
+}
+
+// handleHealth reports whether the plugin can work for system admins. It responds 503 when any problem is found,
+// so that it can be used by monitoring tools.
+func (p *SharePostPlugin) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if !p.API.HasPermissionTo(r.Header.Get("Mattermost-User-Id"), model.PERMISSION_MANAGE_SYSTEM) {
+		writeJSONError(w, http.StatusForbidden, errorCodeForbidden, "forbidden")
+		return
+	}
+
+	problems := p.checkHealth()
+	status := http.StatusOK
+	if len(problems) > 0 {
+		p.API.LogWarn("health check found problems", "problems", problems)
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(healthStatus{OK: len(problems) == 0, Problems: problems}); err != nil {
+		p.API.LogWarn("failed to write health status", "error", err.Error())
+	}
+}
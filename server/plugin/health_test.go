@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleHealth(t *testing.T) {
+	serve := func(p *SharePostPlugin, userID string) (int, *healthStatus) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/healthz", nil)
+		r.Header.Set("Mattermost-User-Id", userID)
+		w := httptest.NewRecorder()
+		p.handleHealth(w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		var status healthStatus
+		_ = json.NewDecoder(result.Body).Decode(&status)
+		return result.StatusCode, &status
+	}
+
+	t.Run("not system admin", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionTo", "user_id", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+
+		code, _ := serve(p, "user_id")
+
+		assert.Equal(t, http.StatusForbidden, code)
+		api.AssertNotCalled(t, "GetBot", mock.Anything, mock.Anything)
+	})
+	t.Run("healthy", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_id"
+		api.On("HasPermissionTo", "admin_id", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		api.On("GetBot", "bot_id", true).Return(&model.Bot{UserId: "bot_id"}, nil)
+		api.On("KVGet", csrfSecretKey).Return([]byte("csrf_secret"), nil)
+
+		code, status := serve(p, "admin_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal(&healthStatus{OK: true, Problems: []string{}}, status)
+	})
+	t.Run("misconfigured", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.ServerConfig.ServiceSettings.SiteURL = nil
+		p.botUserID = "bot_id"
+		p.csrfSecret = nil
+		api.On("HasPermissionTo", "admin_id", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		api.On("GetBot", "bot_id", true).Return(&model.Bot{UserId: "bot_id", DeleteAt: 1000}, nil)
+		api.On("KVGet", csrfSecretKey).Return(nil, &model.AppError{Message: "failed"})
+		api.On("LogWarn", "health check found problems", "problems", mock.Anything).Return()
+
+		code, status := serve(p, "admin_id")
+
+		assert.Equal(http.StatusServiceUnavailable, code)
+		assert.False(status.OK)
+		assert.Len(status.Problems, 4)
+		assert.Equal("Site URL is not configured, so links to posts can't be made.", status.Problems[0])
+		assert.Equal("The bot account is disabled.", status.Problems[1])
+	})
+	t.Run("bot account not found", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_id"
+		api.On("HasPermissionTo", "admin_id", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		api.On("GetBot", "bot_id", true).Return(nil, &model.AppError{Message: "not found"})
+		api.On("KVGet", csrfSecretKey).Return([]byte("csrf_secret"), nil)
+		api.On("LogWarn", "health check found problems", "problems", mock.Anything).Return()
+
+		code, status := serve(p, "admin_id")
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		if assert.Len(t, status.Problems, 1) {
+			assert.Contains(t, status.Problems[0], "The bot account can't be found")
+		}
+	})
+}
@@ -13,16 +13,22 @@ import (
 // MessageWillBePosted expand contents of permalink of local post
 func (p *SharePostPlugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*model.Post, string) {
 	siteURL := p.API.GetConfig().ServiceSettings.SiteURL
-	channel, appErr := p.API.GetChannel(post.ChannelId)
+	// Permalinks can't be detected without Site URL
+	if siteURL == nil || *siteURL == "" {
+		addAdditionalText(post)
+		return post, ""
+	}
+	channel, appErr := p.getChannel(post.ChannelId)
 	if appErr != nil {
 		return post, appErr.Error()
 	}
 
 	if channel.Type == model.CHANNEL_DIRECT || channel.Type == model.CHANNEL_GROUP {
+		addAdditionalText(post)
 		return post, ""
 	}
 
-	team, appErr := p.API.GetTeam(channel.TeamId)
+	team, appErr := p.getTeam(channel.TeamId)
 	if appErr != nil {
 		return post, appErr.Error()
 	}
@@ -33,8 +39,13 @@ func (p *SharePostPlugin) MessageWillBePosted(c *plugin.Context, post *model.Pos
 		return post, err.Error()
 	}
 
+	// Copied and shared posts already contain the content of original post, and redirect note for moved post doesn't need the content,
+	// so the permalinks in them are not expanded.
+	// Moved and duplicated posts are not expanded either, because the expansion would replace the attachments of the original post.
 	matches := selfLinkPattern.FindAllString(post.Message, -1)
-	if len(matches) != 0 {
+	if len(matches) != 0 && post.GetProp(postPropsKeyCopiedFrom) == nil && post.GetProp(postPropsKeySharedFromPostID) == nil &&
+		post.GetProp(postPropsKeyMovedTo) == nil && post.GetProp(postPropsKeyMovedFromChannelID) == nil &&
+		post.GetProp(postPropsKeyDuplicatedFrom) == nil {
 		// Only first post matched the pattern is expanded, because can't deal with files that have more than five total attachments.
 		match := matches[0]
 
@@ -45,15 +56,18 @@ func (p *SharePostPlugin) MessageWillBePosted(c *plugin.Context, post *model.Pos
 			return post, appErr.Error()
 		}
 
-		newFileIds, appErr := p.API.CopyFileInfos(post.UserId, oldPost.FileIds)
-		if appErr != nil {
-			p.API.LogWarn("Failed to copy file ids", "error", appErr.Error())
-			return post, appErr.Error()
+		// Files of the post shared by the dialog are attached only when the user chooses to include them
+		if post.GetProp(postPropsKeyFilesHandled) == nil {
+			newFileIds, appErr := p.API.CopyFileInfos(post.UserId, oldPost.FileIds)
+			if appErr != nil {
+				p.API.LogWarn("Failed to copy file ids", "error", appErr.Error())
+				return post, appErr.Error()
+			}
+			// NOTES: if attaching over 5 files, error will occur
+			post.FileIds = append(post.FileIds, newFileIds...)
 		}
-		// NOTES: if attaching over 5 files, error will occur
-		post.FileIds = append(post.FileIds, newFileIds...)
 
-		oldchannel, appErr := p.API.GetChannel(oldPost.ChannelId)
+		oldchannel, appErr := p.getChannel(oldPost.ChannelId)
 		if appErr != nil {
 			return post, appErr.Error()
 		}
@@ -63,6 +77,7 @@ func (p *SharePostPlugin) MessageWillBePosted(c *plugin.Context, post *model.Pos
 			return post, appErr.Error()
 		}
 		oldPostCreateAt := time.Unix(oldPost.CreateAt/1000, 0)
+		T := p.getServerLocalizer()
 
 		AuthorName := postUser.GetDisplayNameWithPrefix(model.SHOW_NICKNAME_FULLNAME, "@")
 		fmtstmnt := "%s/api/v4/users/%s/image"
@@ -79,20 +94,22 @@ func (p *SharePostPlugin) MessageWillBePosted(c *plugin.Context, post *model.Pos
 				AuthorName: AuthorName,
 				AuthorIcon: AuthorIcon,
 				Text:       oldPost.Message,
-				Footer: fmt.Sprintf("Posted in ~%s %s",
-					oldchannel.Name,
-					oldPostCreateAt.Format("on Mon 2 Jan 2006 at 15:04:05 MST"),
-				),
+				Footer:     T("share.attachment_footer", oldchannel.Name, oldPostCreateAt.Format(T("share.time_layout"))),
 			},
 			nil,
 		}
 		model.ParseSlackAttachment(post, attachment)
 	}
 
-	// Add additional comment written in dialog
 	// If adding first the additional text in the message, the link in the additional text will be expanded, so additional text have to be added here
+	addAdditionalText(post)
+	return post, ""
+}
+
+// addAdditionalText adds additional comment written in dialog to the message
+func addAdditionalText(post *model.Post) {
 	if post.GetProp(postPropsKeyAdditionalText) != nil {
-		post.Message = fmt.Sprintf("%s%s", post.GetProp(postPropsKeyAdditionalText), post.Message)
+		position, _ := post.GetProp(postPropsKeyTextPosition).(string)
+		post.Message = placeAdditionalText(post.Message, fmt.Sprintf("%s", post.GetProp(postPropsKeyAdditionalText)), position)
 	}
-	return post, ""
 }
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMessageWillBePosted(t *testing.T) {
+	t.Run("moved post is not expanded", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		config := &model.Config{}
+		config.ServiceSettings.SiteURL = toPtr("http://localhost:8065")
+		api.On("GetConfig").Return(config)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+
+		attachments := []*model.SlackAttachment{{Title: "Build #1", Text: "succeeded"}}
+		post := &model.Post{ChannelId: "to_channel_id", Message: "see http://localhost:8065/team/pl/linked_post_id"}
+		post.AddProp("attachments", attachments)
+		post.AddProp(postPropsKeyMovedFromChannelID, "channel_id")
+
+		got, rejected := p.MessageWillBePosted(nil, post)
+
+		assert.Equal("", rejected)
+		assert.Equal(attachments, got.Attachments())
+		api.AssertNotCalled(t, "GetPost", mock.Anything)
+	})
+	t.Run("additional text position", func(t *testing.T) {
+		for _, test := range []struct {
+			Position string
+			Expected string
+		}{
+			{Position: textPositionAbove, Expected: "Hi\n\n> shared message"},
+			{Position: textPositionBelow, Expected: "> shared message\n\nHi"},
+		} {
+			t.Run(test.Position, func(t *testing.T) {
+				api := &plugintest.API{}
+				defer api.AssertExpectations(t)
+				p := setupTestPlugin(api)
+				config := &model.Config{}
+				config.ServiceSettings.SiteURL = toPtr("http://localhost:8065")
+				api.On("GetConfig").Return(config)
+				api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+				api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+
+				post := &model.Post{ChannelId: "to_channel_id", Message: "> shared message"}
+				post.AddProp(postPropsKeySharedFromPostID, "post_id")
+				post.AddProp(postPropsKeyAdditionalText, "Hi\n\n")
+				post.AddProp(postPropsKeyTextPosition, test.Position)
+
+				got, rejected := p.MessageWillBePosted(nil, post)
+
+				assert.Equal(t, "", rejected)
+				assert.Equal(t, test.Expected, got.Message)
+			})
+		}
+	})
+	t.Run("additional text in direct message", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		config := &model.Config{}
+		config.ServiceSettings.SiteURL = toPtr("http://localhost:8065")
+		api.On("GetConfig").Return(config)
+		api.On("GetChannel", "dm_channel_id").Return(&model.Channel{Id: "dm_channel_id", Type: model.CHANNEL_DIRECT}, nil)
+
+		post := &model.Post{ChannelId: "dm_channel_id", Message: "> shared message"}
+		post.AddProp(postPropsKeySharedFromPostID, "post_id")
+		post.AddProp(postPropsKeyAdditionalText, "Hi\n\n")
+		post.AddProp(postPropsKeyTextPosition, textPositionAbove)
+
+		got, rejected := p.MessageWillBePosted(nil, post)
+
+		assert.Equal(t, "", rejected)
+		assert.Equal(t, "Hi\n\n> shared message", got.Message)
+	})
+}
@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+const defaultLocale = "en"
+
+// i18nBundle holds the translated messages loaded from `assets/i18n/<locale>.json`.
+// Each file is a flat JSON object mapping message IDs to fmt format strings.
+type i18nBundle struct {
+	messages map[string]map[string]string
+}
+
+func loadI18nBundle(dir string) (*i18nBundle, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message files %w", err)
+	}
+
+	bundle := &i18nBundle{messages: map[string]map[string]string{}}
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message file %s %w", file, err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(b, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse message file %s %w", file, err)
+		}
+		bundle.messages[strings.TrimSuffix(filepath.Base(file), ".json")] = messages
+	}
+	if _, ok := bundle.messages[defaultLocale]; !ok {
+		return nil, fmt.Errorf("message file for default locale %s is not found in %s", defaultLocale, dir)
+	}
+	return bundle, nil
+}
+
+// localize returns the message for the locale. English message is used when the translation is missing.
+func (b *i18nBundle) localize(locale, id string, args ...interface{}) string {
+	format, ok := b.messages[locale][id]
+	if !ok {
+		// Locales like `zh-CN` fall back to the language (`zh`) before English
+		if i := strings.IndexAny(locale, "-_"); i > 0 {
+			format, ok = b.messages[locale[:i]][id]
+		}
+	}
+	if !ok {
+		format, ok = b.messages[defaultLocale][id]
+	}
+	if !ok {
+		return id
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// localizer returns the message for the message ID in the locale of a user
+type localizer func(id string, args ...interface{}) string
+
+// getLocalizer returns the localizer for the user. The locale of the user is looked up only when needed.
+func (p *SharePostPlugin) getLocalizer(userID string) localizer {
+	locale := ""
+	return func(id string, args ...interface{}) string {
+		if p.i18n == nil {
+			return id
+		}
+		if locale == "" {
+			locale = p.getUserLocale(userID)
+		}
+		return p.i18n.localize(locale, id, args...)
+	}
+}
+
+// getServerLocalizer returns the localizer for the default locale of the server.
+// Posts seen by all members of a channel, like notes and cards, have no single recipient, so they're rendered in it.
+func (p *SharePostPlugin) getServerLocalizer() localizer {
+	locale := defaultLocale
+	if p.ServerConfig != nil && p.ServerConfig.LocalizationSettings.DefaultServerLocale != nil && *p.ServerConfig.LocalizationSettings.DefaultServerLocale != "" {
+		locale = *p.ServerConfig.LocalizationSettings.DefaultServerLocale
+	}
+	return func(id string, args ...interface{}) string {
+		if p.i18n == nil {
+			return id
+		}
+		return p.i18n.localize(locale, id, args...)
+	}
+}
+
+func (p *SharePostPlugin) getUserLocale(userID string) string {
+	// No need to look up the user when there is no translation
+	if len(p.i18n.messages) <= 1 {
+		return defaultLocale
+	}
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get user for locale", "user_id", userID, "error", appErr.Error())
+		return defaultLocale
+	}
+	if user.Locale == "" {
+		return defaultLocale
+	}
+	return user.Locale
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoadI18nBundle(t *testing.T) {
+	bundle, err := loadI18nBundle(filepath.Join("..", "..", "assets", "i18n"))
+	assert.Nil(t, err)
+
+	// All translations must have the same messages as English
+	for locale, messages := range bundle.messages {
+		for id := range bundle.messages[defaultLocale] {
+			_, ok := messages[id]
+			assert.Truef(t, ok, "message %s is missing in %s", id, locale)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	bundle := &i18nBundle{messages: map[string]map[string]string{
+		"en": {"hello": "Hello, %s", "bye": "Bye"},
+		"zh": {"hello": "你好, %s"},
+		"ja": {"hello": "こんにちは, %s"},
+	}}
+
+	for name, test := range map[string]struct {
+		Locale   string
+		ID       string
+		Expected string
+	}{
+		"translated":           {Locale: "ja", ID: "hello", Expected: "こんにちは, user"},
+		"missing translation":  {Locale: "ja", ID: "bye", Expected: "Bye"},
+		"fallback to language": {Locale: "zh-CN", ID: "hello", Expected: "你好, user"},
+		"unknown locale":       {Locale: "fr", ID: "hello", Expected: "Hello, user"},
+		"unknown message":      {Locale: "en", ID: "unknown", Expected: "unknown"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if test.ID == "hello" {
+				assert.Equal(t, test.Expected, bundle.localize(test.Locale, test.ID, "user"))
+			} else {
+				assert.Equal(t, test.Expected, bundle.localize(test.Locale, test.ID))
+			}
+		})
+	}
+}
+
+func TestGetLocalizer(t *testing.T) {
+	assert := assert.New(t)
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	p.i18n = &i18nBundle{messages: map[string]map[string]string{
+		"en": {"hello": "Hello"},
+		"ja": {"hello": "こんにちは"},
+	}}
+
+	api.On("GetUser", "user_id").Return(&model.User{Id: "user_id", Locale: "ja"}, nil).Once()
+
+	T := p.getLocalizer("user_id")
+	assert.Equal("こんにちは", T("hello"))
+	assert.Equal("こんにちは", T("hello"), "locale is looked up only once")
+}
+
+func TestGetServerLocalizer(t *testing.T) {
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	p.i18n = &i18nBundle{messages: map[string]map[string]string{
+		"en": {"hello": "Hello"},
+		"ja": {"hello": "こんにちは"},
+	}}
+
+	assert.Equal(t, "Hello", p.getServerLocalizer()("hello"), "English without the default locale")
+	p.ServerConfig.LocalizationSettings.DefaultServerLocale = model.NewString("ja")
+	assert.Equal(t, "こんにちは", p.getServerLocalizer()("hello"))
+	api.AssertNotCalled(t, "GetUser", mock.Anything)
+}
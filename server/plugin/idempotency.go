@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// idempotencyKeyHeader is the header of API requests whose results are replayed when they're retried
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyReplayedHeader is set on the responses replaying the recorded results
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength is the maximum length of idempotency keys, which is enough for UUIDs and hashes
+	maxIdempotencyKeyLength = 255
+	// idempotencyKeyTTL is how long the results of requests are kept for retries
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyPendingTimeout is how long the claim of a request in flight is kept. The claims left by requests
+	// that never finished, e.g. by restarting the plugin, expire after this, so that the key can be retried.
+	idempotencyPendingTimeout = 10 * time.Minute
+
+	// idempotencyKeyPrefix is the prefix of KV store keys for the results of requests.
+	// Keys are hashed because the user ID, the path and the key given by clients don't fit in the key length limit.
+	idempotencyKeyPrefix = "idempotency_"
+)
+
+func makeIdempotencyKey(userID, path, key string) string {
+	sum := sha256.Sum256([]byte(userID + "\n" + path + "\n" + key))
+	return idempotencyKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// idempotentResult is the recorded result of a request. Body is the response body, which is empty when nothing is returned.
+// Pending is set while the first request with the key is processed.
+type idempotentResult struct {
+	Body    []byte `json:"body"`
+	Pending bool   `json:"pending,omitempty"`
+}
+
+// claimIdempotencyKey atomically records that the request with the key by the user is in flight, so that retries arriving
+// while it's processed aren't processed again. It returns nil when the request is to be processed, or the recorded result
+// of the request with the same key otherwise, which is pending while that request is still processed.
+// Keys are scoped by the path, so that the same key sent to different endpoints doesn't return the other result.
+// Claims of requests that never finished expire after idempotencyPendingTimeout.
+// Failures of the KV store are treated as no record, so that requests aren't blocked.
+func (p *SharePostPlugin) claimIdempotencyKey(userID, path, key string) *idempotentResult {
+	kvKey := makeIdempotencyKey(userID, path, key)
+	pending, err := json.Marshal(idempotentResult{Pending: true})
+	if err != nil {
+		p.API.LogWarn("failed to marshal the claim of idempotent request", "user_id", userID, "error", err.Error())
+		return nil
+	}
+	options := model.PluginKVSetOptions{Atomic: true, ExpireInSeconds: int64(idempotencyPendingTimeout / time.Second)}
+	ok, appErr := p.API.KVSetWithOptions(kvKey, pending, options)
+	if appErr != nil {
+		p.API.LogWarn("failed to claim idempotent request", "user_id", userID, "error", appErr.Error())
+		return nil
+	}
+	if ok {
+		return nil
+	}
+
+	b, appErr := p.API.KVGet(kvKey)
+	if appErr != nil {
+		p.API.LogWarn("failed to get the result of idempotent request", "user_id", userID, "error", appErr.Error())
+		return nil
+	}
+	if b != nil {
+		var result idempotentResult
+		if err := json.Unmarshal(b, &result); err != nil {
+			p.API.LogWarn("failed to unmarshal the result of idempotent request", "user_id", userID, "error", err.Error())
+			return nil
+		}
+		return &result
+	}
+	// The record is gone when the request with the key has failed in the meantime, and it's claimed again in that case.
+	// Only one of the requests claiming it at the same moment succeeds, and the others wait for its result.
+	ok, appErr = p.API.KVSetWithOptions(kvKey, pending, options)
+	if appErr != nil {
+		p.API.LogWarn("failed to claim idempotent request", "user_id", userID, "error", appErr.Error())
+		return nil
+	}
+	if !ok {
+		return &idempotentResult{Pending: true}
+	}
+	return nil
+}
+
+// saveIdempotentResult records the result of the request in place of its claim, which expires after idempotencyKeyTTL.
+func (p *SharePostPlugin) saveIdempotentResult(userID, path, key string, body []byte) {
+	b, err := json.Marshal(idempotentResult{Body: body})
+	if err != nil {
+		p.API.LogWarn("failed to marshal the result of idempotent request", "user_id", userID, "error", err.Error())
+		p.releaseIdempotencyKey(userID, path, key)
+		return
+	}
+	if appErr := p.API.KVSetWithExpiry(makeIdempotencyKey(userID, path, key), b, int64(idempotencyKeyTTL/time.Second)); appErr != nil {
+		p.API.LogWarn("failed to save the result of idempotent request", "user_id", userID, "error", appErr.Error())
+	}
+}
+
+// releaseIdempotencyKey removes the claim of the request whose result isn't recorded, so that retrying it processes it again.
+func (p *SharePostPlugin) releaseIdempotencyKey(userID, path, key string) {
+	if appErr := p.API.KVDelete(makeIdempotencyKey(userID, path, key)); appErr != nil {
+		p.API.LogWarn("failed to release the claim of idempotent request", "user_id", userID, "error", appErr.Error())
+	}
+}
+
+// writeIdempotentResult writes the recorded result as the response to the retried request
+func (p *SharePostPlugin) writeIdempotentResult(w http.ResponseWriter, result *idempotentResult) {
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	if len(result.Body) == 0 {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(result.Body); err != nil {
+		p.API.LogWarn("failed to write the result of idempotent request", "error", err.Error())
+	}
+}
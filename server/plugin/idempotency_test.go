@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotentSubmitDialogRequest(t *testing.T) {
+	serve := func(p *SharePostPlugin, idempotencyKey string, handler submitDialogHandler) (*http.Response, string) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/share", strings.NewReader(`{"user_id":"user_id","channel_id":"channel_id"}`))
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		r.Header.Set(idempotencyKeyHeader, idempotencyKey)
+		p.handleSubmitDialogRequest(handler)(w, r)
+		result := w.Result()
+		defer result.Body.Close()
+		body, _ := ioutil.ReadAll(result.Body)
+		return result, string(body)
+	}
+	share := func(calls *int) submitDialogHandler {
+		return func(ctx context.Context, _ map[string]string, _ *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+			*calls++
+			addCreatedPost(ctx, &model.Post{Id: "new_post_id", ChannelId: "to_channel_id"}, "http://localhost:8065/team/pl/new_post_id")
+			return nil, nil, nil
+		}
+	}
+	key := makeIdempotencyKey("user_id", "/api/v1/share", "retry-key")
+	claim := model.PluginKVSetOptions{Atomic: true, ExpireInSeconds: 600}
+
+	t.Run("replay the result of the retried request", func(t *testing.T) {
+		assert := assert.New(t)
+		calls := 0
+
+		// The first request is processed and its result is recorded
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		var saved []byte
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(true, nil)
+		api.On("KVSetWithExpiry", key, mock.Anything, int64(86400)).Return(nil).Run(func(args mock.Arguments) {
+			saved = args.Get(1).([]byte)
+		})
+		first, firstBody := serve(p, "retry-key", share(&calls))
+		api.AssertExpectations(t)
+		assert.Equal(1, calls)
+		assert.Contains(firstBody, "new_post_id")
+		assert.Equal("", first.Header.Get(idempotencyReplayedHeader))
+
+		// The retry returns the same result without posting again
+		api = &plugintest.API{}
+		p = setupTestPlugin(api)
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(false, nil)
+		api.On("KVGet", key).Return(saved, nil)
+		retried, retriedBody := serve(p, "retry-key", share(&calls))
+		api.AssertExpectations(t)
+		assert.Equal(1, calls)
+		assert.Equal(firstBody, retriedBody)
+		assert.Equal("true", retried.Header.Get(idempotencyReplayedHeader))
+		assert.Equal("application/json", retried.Header.Get("Content-Type"))
+		api.AssertNotCalled(t, "KVSetWithExpiry", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("retry while the first request is in flight", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		calls := 0
+		pending, _ := json.Marshal(idempotentResult{Pending: true})
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(false, nil)
+		api.On("KVGet", key).Return(pending, nil)
+
+		result, _ := serve(p, "retry-key", share(&calls))
+
+		assert.Equal(http.StatusConflict, result.StatusCode)
+		assert.Equal(0, calls)
+	})
+	t.Run("claim again after the first request failed in the meantime", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		calls := 0
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(false, nil).Once()
+		api.On("KVGet", key).Return(nil, nil)
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(true, nil).Once()
+		api.On("KVSetWithExpiry", key, mock.Anything, int64(86400)).Return(nil)
+
+		result, _ := serve(p, "retry-key", share(&calls))
+
+		assert.Equal(http.StatusOK, result.StatusCode)
+		assert.Equal(1, calls)
+	})
+	t.Run("without idempotency key", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		calls := 0
+
+		serve(p, "", share(&calls))
+		serve(p, "", share(&calls))
+
+		assert.Equal(t, 2, calls)
+		api.AssertNotCalled(t, "KVSetWithOptions", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("errors of dialog elements are not recorded", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(true, nil)
+		api.On("KVDelete", key).Return(nil)
+
+		serve(p, "retry-key", func(context.Context, map[string]string, *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+			return nil, dialogFieldError(toChannelKey, "Please select a channel."), nil
+		})
+
+		api.AssertNotCalled(t, "KVSetWithExpiry", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("retry after a failure is processed again", func(t *testing.T) {
+		assert := assert.New(t)
+		calls := 0
+
+		// The first request fails without creating any post, so its claim is released
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(true, nil)
+		api.On("KVDelete", key).Return(nil)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		serve(p, "retry-key", func(context.Context, map[string]string, *model.SubmitDialogRequest) (*string, *model.SubmitDialogResponse, error) {
+			calls++
+			return toPtr("Something went wrong. Please try again later."), nil, errors.New("failed to create post")
+		})
+		api.AssertExpectations(t)
+		api.AssertNotCalled(t, "KVSetWithExpiry", mock.Anything, mock.Anything, mock.Anything)
+
+		// The retry with the same key is processed, and its result is recorded
+		api = &plugintest.API{}
+		p = setupTestPlugin(api)
+		api.On("KVSetWithOptions", key, mock.AnythingOfType("[]uint8"), claim).Return(true, nil)
+		api.On("KVSetWithExpiry", key, mock.Anything, int64(86400)).Return(nil)
+		_, body := serve(p, "retry-key", share(&calls))
+		api.AssertExpectations(t)
+		assert.Equal(2, calls)
+		assert.Contains(body, "new_post_id")
+	})
+	t.Run("too long idempotency key", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		calls := 0
+
+		result, _ := serve(p, strings.Repeat("k", maxIdempotencyKeyLength+1), share(&calls))
+
+		assert.Equal(t, http.StatusBadRequest, result.StatusCode)
+		assert.Equal(t, 0, calls)
+	})
+}
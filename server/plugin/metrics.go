@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	metricSharesTotal = "sharepost_shares_total"
+	metricMovesTotal  = "sharepost_moves_total"
+	metricErrorsTotal = "sharepost_errors_total"
+
+	metricResultSuccess = "success"
+	// metricResultRejected is the result of actions refused for the user, e.g. lacking permissions
+	metricResultRejected = "rejected"
+	metricResultFailure  = "failure"
+
+	metricErrorInvalidRequest = "invalid_request"
+	metricErrorUnauthorized   = "unauthorized"
+	metricErrorSubmitDialog   = "submit_dialog"
+)
+
+var metricHelps = map[string]string{
+	metricSharesTotal: "Number of posts shared or copied by the plugin.",
+	metricMovesTotal:  "Number of posts moved by the plugin.",
+	metricErrorsTotal: "Number of errors in handling requests to the plugin.",
+}
+
+// metrics holds the counters exposed in the Prometheus text format.
+// Plugins can't register metrics to the metrics server of Mattermost, so the counters are served by the plugin itself
+// and they are counted per server process.
+type metrics struct {
+	lock sync.Mutex
+	// counters maps the metric name to the values by formatted labels, e.g. `type="share",result="success"`
+	counters map[string]map[string]int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{counters: map[string]map[string]int64{}}
+}
+
+func (m *metrics) inc(name, labels string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.counters[name]; !ok {
+		m.counters[name] = map[string]int64{}
+	}
+	m.counters[name][labels]++
+}
+
+// observeAction counts the result of sharing, copying or moving a post from the return values of the action
+func (m *metrics) observeAction(action string, msg *string, err error) {
+	result := metricResultSuccess
+	if err != nil {
+		result = metricResultFailure
+	} else if msg != nil {
+		result = metricResultRejected
+	}
+
+	name := metricSharesTotal
+	if action == shareTypeMove {
+		name = metricMovesTotal
+	}
+	m.inc(name, fmt.Sprintf(`type=%q,result=%q`, action, result))
+}
+
+func (m *metrics) observeError(errorType string) {
+	m.inc(metricErrorsTotal, fmt.Sprintf(`type=%q`, errorType))
+}
+
+// write writes the counters in the Prometheus text format, sorted so that the output is stable
+func (m *metrics) write(b *strings.Builder) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	names := make([]string, 0, len(metricHelps))
+	for name := range metricHelps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "# HELP %s %s\n", name, metricHelps[name])
+		fmt.Fprintf(b, "# TYPE %s counter\n", name)
+		labels := make([]string, 0, len(m.counters[name]))
+		for l := range m.counters[name] {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			fmt.Fprintf(b, "%s{%s} %d\n", name, l, m.counters[name][l])
+		}
+	}
+}
+
+// handleMetrics serves the metrics for Prometheus. Only system admins, or their access tokens, can get the metrics.
+func (p *SharePostPlugin) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	if userID == "" || !p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM) {
+		writeJSONError(w, http.StatusUnauthorized, errorCodeUnauthorized, "not authorized")
+		return
+	}
+
+	var b strings.Builder
+	p.metrics.write(&b)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := w.Write([]byte(b.String())); err != nil {
+		p.API.LogWarn("failed to write metrics", "error", err.Error())
+	}
+}
@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("count actions and errors", func(t *testing.T) {
+		m := newMetrics()
+		m.observeAction(shareTypeShare, nil, nil)
+		m.observeAction(shareTypeShare, nil, nil)
+		m.observeAction(shareTypeCopy, toPtr("no permission"), nil)
+		m.observeAction(shareTypeMove, toPtr("error"), errors.New("failed"))
+		m.observeError(metricErrorUnauthorized)
+
+		var b strings.Builder
+		m.write(&b)
+
+		assert.Equal(t, strings.Join([]string{
+			"# HELP sharepost_errors_total Number of errors in handling requests to the plugin.",
+			"# TYPE sharepost_errors_total counter",
+			`sharepost_errors_total{type="unauthorized"} 1`,
+			"# HELP sharepost_moves_total Number of posts moved by the plugin.",
+			"# TYPE sharepost_moves_total counter",
+			`sharepost_moves_total{type="move",result="failure"} 1`,
+			"# HELP sharepost_shares_total Number of posts shared or copied by the plugin.",
+			"# TYPE sharepost_shares_total counter",
+			`sharepost_shares_total{type="copy",result="rejected"} 1`,
+			`sharepost_shares_total{type="share",result="success"} 2`,
+			"",
+		}, "\n"), b.String())
+	})
+}
+
+func TestHandleMetrics(t *testing.T) {
+	t.Run("not system admin", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionTo", "user_id", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		w := httptest.NewRecorder()
+		p.handleMetrics(w, r)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
+	})
+	t.Run("system admin", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("HasPermissionTo", "admin_id", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		p.metrics.observeAction(shareTypeShare, nil, nil)
+
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		r.Header.Set("Mattermost-User-Id", "admin_id")
+		w := httptest.NewRecorder()
+		p.handleMetrics(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Contains(t, w.Body.String(), `sharepost_shares_total{type="share",result="success"} 1`)
+	})
+}
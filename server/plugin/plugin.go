@@ -3,6 +3,7 @@ package plugin
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"sync"
 
 	"github.com/blang/semver/v4"
@@ -11,7 +12,16 @@ import (
 	"github.com/mattermost/mattermost-server/v5/plugin"
 )
 
-const minimumServerVersion = "5.18.0"
+const (
+	minimumServerVersion = "5.20.0"
+
+	botUsername    = "sharepost"
+	botDisplayName = "Share Post"
+	botDescription = "Created by the Share Post plugin."
+)
+
+// buildHash is the git commit hash the plugin is built from. It's set by ldflags at build time.
+var buildHash = "unknown"
 
 // SharePostPlugin implements the interface expected by the Mattermost server to communicate between the server and plugin processes.
 type SharePostPlugin struct {
@@ -26,6 +36,24 @@ type SharePostPlugin struct {
 	configuration *configuration
 
 	ServerConfig *model.Config
+
+	// botUserID is the user ID of the bot posting messages on behalf of the plugin
+	botUserID string
+
+	// shareRateLimiter limits the number of shares per user
+	shareRateLimiter *rateLimiter
+
+	// metrics counts shares and moves
+	metrics *metrics
+
+	// lookupCache caches teams and channels
+	lookupCache *lookupCache
+
+	// csrfSecret is the secret to issue CSRF tokens
+	csrfSecret []byte
+
+	// i18n holds the translated messages
+	i18n *i18nBundle
 }
 
 // OnActivate initialize the plugin
@@ -39,6 +67,32 @@ func (p *SharePostPlugin) OnActivate() error {
 		return errors.New("siteURL is not set. Please set a siteURL and restart the plugin")
 	}
 
+	bundlePath, err := p.API.GetBundlePath()
+	if err != nil {
+		return fmt.Errorf("failed to get bundle path %w", err)
+	}
+	if p.i18n, err = loadI18nBundle(filepath.Join(bundlePath, "assets", "i18n")); err != nil {
+		return err
+	}
+
+	botUserID, err := p.Helpers.EnsureBot(&model.Bot{
+		Username:    botUsername,
+		DisplayName: botDisplayName,
+		Description: botDescription,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ensure bot user %w", err)
+	}
+	p.botUserID = botUserID
+
+	if err := p.ensureCSRFSecret(); err != nil {
+		return err
+	}
+
+	if err := p.registerCommands(); err != nil {
+		return err
+	}
+
 	p.router = p.InitAPI()
 	return nil
 }
@@ -56,12 +110,23 @@ func (p *SharePostPlugin) checkServerVersion() error {
 	return nil
 }
 
-// SendEphemeralPost send ephemeral post
-func (p *SharePostPlugin) SendEphemeralPost(channelID, userID, message string) {
+// SendEphemeralPost send ephemeral post from the bot, so that the message isn't shown as posted by the user.
+// It reports whether the post is sent.
+func (p *SharePostPlugin) SendEphemeralPost(channelID, userID, message string) bool {
 	ephemeralPost := &model.Post{
 		ChannelId: channelID,
-		UserId:    userID,
+		UserId:    p.botUserID,
 		Message:   message,
 	}
-	_ = p.API.SendEphemeralPost(userID, ephemeralPost)
+	return p.sendEphemeralPost(userID, ephemeralPost)
+}
+
+// sendEphemeralPost sends the ephemeral post to the user. The API returns no post when sending fails, and the failure
+// is logged because the user sees no feedback of the action otherwise.
+func (p *SharePostPlugin) sendEphemeralPost(userID string, post *model.Post) bool {
+	if sent := p.API.SendEphemeralPost(userID, post); sent == nil {
+		p.API.LogWarn("failed to send ephemeral post", "user_id", userID, "channel_id", post.ChannelId)
+		return false
+	}
+	return true
 }
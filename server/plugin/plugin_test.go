@@ -1,11 +1,15 @@
 package plugin
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -39,6 +43,193 @@ func TestServeHTTP(t *testing.T) {
 	assert.Equal("Installed SharePostPlugin v0.1.0", bodyString)
 }
 
+func TestServeHTTPInfoJSON(t *testing.T) {
+	assert := assert.New(t)
+	p := SharePostPlugin{}
+
+	api := &plugintest.API{}
+	api.On("LogDebug", GetMockArgumentsWithType("string", 7)...).Return()
+	defer api.AssertExpectations(t)
+	p.SetAPI(api)
+
+	p.router = p.InitAPI()
+	p.setConfiguration(&configuration{})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/json")
+
+	p.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	defer result.Body.Close()
+
+	assert.Equal("application/json", result.Header.Get("Content-Type"))
+	var info pluginInfo
+	assert.Nil(json.NewDecoder(result.Body).Decode(&info))
+	assert.Equal(pluginInfo{PluginID: "com.github.kaakaa.sharepost", Version: "0.1.0", BuildHash: "unknown"}, info)
+}
+
+func TestServeHTTPSettings(t *testing.T) {
+	assert := assert.New(t)
+	p := SharePostPlugin{}
+
+	api := &plugintest.API{}
+	api.On("LogDebug", GetMockArgumentsWithType("string", 7)...).Return()
+	api.On("KVGet", "skip_move_confirmation_user_id").Return([]byte("true"), nil)
+	defer api.AssertExpectations(t)
+	p.SetAPI(api)
+
+	p.router = p.InitAPI()
+	p.setConfiguration(&configuration{EnableShare: true, EnableMove: false})
+	p.csrfSecret = []byte("csrf_secret")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/settings", nil)
+	r.Header.Set("Mattermost-User-ID", "user_id")
+
+	p.ServeHTTP(nil, w, r)
+
+	result := w.Result()
+	defer result.Body.Close()
+
+	assert.Equal(http.StatusOK, result.StatusCode)
+	var settings clientSettings
+	assert.Nil(json.NewDecoder(result.Body).Decode(&settings))
+	assert.Equal(clientSettings{EnableShare: true, EnableMove: false, CSRFToken: p.makeCSRFToken("user_id"), SkipMoveConfirmation: true}, settings)
+}
+
+func TestHandleSettingsForPost(t *testing.T) {
+	disabled := false
+	setup := func(api *plugintest.API) *SharePostPlugin {
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: true, teamOverrides: map[string]*teamOverride{
+			"team_id": {EnableMove: &disabled},
+		}})
+		p.csrfSecret = []byte("csrf_secret")
+		return p
+	}
+	serve := func(p *SharePostPlugin, query string) *http.Response {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/settings?"+query, nil)
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		w := httptest.NewRecorder()
+		p.handleSettings(w, r)
+		return w.Result()
+	}
+
+	t.Run("overrides of the team of the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setup(api)
+		mockSourcePost(api)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("KVGet", "skip_move_confirmation_user_id").Return(nil, nil)
+
+		// The team sent by the client is ignored for posts in a team
+		result := serve(p, "post_id=post_id&team_id=other_team_id")
+		defer result.Body.Close()
+
+		var settings clientSettings
+		assert.Equal(http.StatusOK, result.StatusCode)
+		assert.Nil(json.NewDecoder(result.Body).Decode(&settings))
+		assert.Equal(clientSettings{EnableShare: true, EnableMove: false, CSRFToken: p.makeCSRFToken("user_id")}, settings)
+	})
+	t.Run("unreadable post", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setup(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+
+		result := serve(p, "post_id=post_id")
+		defer result.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, result.StatusCode)
+	})
+}
+
+func TestServeHTTPErrors(t *testing.T) {
+	setup := func() *SharePostPlugin {
+		p := &SharePostPlugin{}
+		api := &plugintest.API{}
+		api.On("LogDebug", GetMockArgumentsWithType("string", 7)...).Return()
+		api.On("LogWarn", "Failed to decode SubmitDialogRequest").Return()
+		api.On("LogWarn", "invalid CSRF token", "user_id", "user_id", "path", mock.AnythingOfType("string")).Return()
+		api.On("LogWarn", "failed to get moved post id from the context", "context", mock.Anything).Return()
+		p.SetAPI(api)
+		p.router = p.InitAPI()
+		p.setConfiguration(&configuration{})
+		p.csrfSecret = []byte("csrf_secret")
+		return p
+	}
+
+	t.Run("not authorized", func(t *testing.T) {
+		assert := assert.New(t)
+		p := setup()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/share", strings.NewReader("{}"))
+		p.ServeHTTP(nil, w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		assert.Equal(http.StatusUnauthorized, result.StatusCode)
+		assert.Equal("application/json", result.Header.Get("Content-Type"))
+		var body errorResponse
+		assert.Nil(json.NewDecoder(result.Body).Decode(&body))
+		assert.Equal(errorResponse{Error: "not authorized", Code: errorCodeUnauthorized}, body)
+	})
+	t.Run("invalid request", func(t *testing.T) {
+		assert := assert.New(t)
+		p := setup()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/move", strings.NewReader("invalid"))
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		r.Header.Set("X-Requested-With", "XMLHttpRequest")
+		p.ServeHTTP(nil, w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		assert.Equal(http.StatusBadRequest, result.StatusCode)
+		var body errorResponse
+		assert.Nil(json.NewDecoder(result.Body).Decode(&body))
+		assert.Equal(errorResponse{Error: "invalid request", Code: errorCodeInvalidRequest}, body)
+	})
+	t.Run("without CSRF token", func(t *testing.T) {
+		assert := assert.New(t)
+		p := setup()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/move", strings.NewReader(`{"user_id":"user_id","state":"invalid"}`))
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		p.ServeHTTP(nil, w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		assert.Equal(http.StatusUnauthorized, result.StatusCode)
+		var body errorResponse
+		assert.Nil(json.NewDecoder(result.Body).Decode(&body))
+		assert.Equal(errorResponse{Error: "not authorized", Code: errorCodeUnauthorized}, body)
+	})
+	t.Run("with CSRF token in the context", func(t *testing.T) {
+		assert := assert.New(t)
+		p := setup()
+
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"user_id":"user_id","context":{"csrf_token":"%s"}}`, p.makeCSRFToken("user_id"))
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/undo", strings.NewReader(body))
+		r.Header.Set("Mattermost-User-ID", "user_id")
+		p.ServeHTTP(nil, w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		// The request reaches the handler, which refuses the context without the moved post
+		assert.Equal(http.StatusBadRequest, result.StatusCode)
+	})
+}
+
 func GetMockArgumentsWithType(typeString string, num int) []interface{} {
 	ret := make([]interface{}, num)
 	for i := 0; i < len(ret); i++ {
@@ -46,3 +237,28 @@ func GetMockArgumentsWithType(typeString string, num int) []interface{} {
 	}
 	return ret
 }
+
+func TestSendEphemeralPost(t *testing.T) {
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	p.botUserID = "bot_user_id"
+	api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{}).Run(func(args mock.Arguments) {
+		post := args.Get(1).(*model.Post)
+		assert.Equal(t, "bot_user_id", post.UserId)
+		assert.Equal(t, "channel_id", post.ChannelId)
+		assert.Equal(t, "message", post.Message)
+	})
+
+	assert.True(t, p.SendEphemeralPost("channel_id", "user_id", "message"))
+}
+
+func TestSendEphemeralPostFailure(t *testing.T) {
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(nil)
+	api.On("LogWarn", "failed to send ephemeral post", "user_id", "user_id", "channel_id", "channel_id").Return()
+
+	assert.False(t, p.SendEphemeralPost("channel_id", "user_id", "message"))
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// previewResponse is the response of handlePreview. Error is the message for the user when the post can't be shared.
+type previewResponse struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handlePreview returns the message to be posted by sharing, without posting it.
+// It takes the same submission as `/api/v1/share`.
+func (p *SharePostPlugin) handlePreview(w http.ResponseWriter, r *http.Request) {
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil {
+		p.API.LogWarn("Failed to decode SubmitDialogRequest")
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid request")
+		return
+	}
+	if request.UserId != r.Header.Get("Mattermost-User-Id") {
+		p.API.LogWarn("invalid user")
+		writeJSONError(w, http.StatusUnauthorized, errorCodeUnauthorized, "not authorized")
+		return
+	}
+
+	status := http.StatusOK
+	response := &previewResponse{}
+	message, msg, err := p.previewSharePost(request)
+	switch {
+	case err != nil:
+		p.API.LogWarn("failed to preview shared post", "error", err.Error())
+		status = http.StatusInternalServerError
+		response.Error = *msg
+	case msg != nil:
+		status = http.StatusBadRequest
+		response.Error = *msg
+	default:
+		response.Message = message
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		p.API.LogWarn("failed to write preview", "error", err.Error())
+	}
+}
+
+// previewSharePost composes the message in the same way as sharePost, but doesn't create any post.
+// The message for the user is returned when the post can't be previewed.
+func (p *SharePostPlugin) previewSharePost(request *model.SubmitDialogRequest) (string, *string, error) {
+	postID := resolvePostID(request.CallbackId)
+	userID := request.UserId
+	T := p.getLocalizer(userID)
+	if _, err := p.getSiteURL(); err != nil {
+		return "", toPtr(T("error.site_url_not_set")), err
+	}
+
+	toChannels := parseChannelIDs(request.Submission[toChannelKey])
+	if len(toChannels) == 0 {
+		return "", toPtr(T("dialog.select_channel")), nil
+	}
+	toChannel, response := p.resolveChannelID(T, toChannels[0])
+	if response != nil {
+		return "", toPtr(response.Errors[toChannelKey]), nil
+	}
+	if shareType, _ := request.Submission[shareTypeKey].(string); shareType != shareTypeShare {
+		return "", toPtr(T("preview.share_only")), nil
+	}
+	if msg, err := p.checkSourceTeam(T, postID, request.TeamId); msg != nil {
+		return "", msg, err
+	}
+	if msg := p.checkShareTypeEnabled(T, userID, request.TeamId, shareTypeShare); msg != nil {
+		return "", msg, nil
+	}
+	additionalText, response := p.parseAdditionalText(T, request.Submission)
+	if response != nil {
+		return "", toPtr(response.Errors[additionalTextKey]), nil
+	}
+	renderMode, response := parseRenderMode(T, request.Submission)
+	if response != nil {
+		return "", toPtr(response.Errors[renderModeKey]), nil
+	}
+	textPosition, response := parseTextPosition(T, request.Submission)
+	if response != nil {
+		return "", toPtr(response.Errors[textPositionKey]), nil
+	}
+	shareThread, _ := request.Submission[shareThreadKey].(bool)
+	includeReactions, _ := request.Submission[includeReactionsKey].(bool)
+
+	// The message is the same for all destinations except the team name in permalinks, so the first one is used
+	newChannel, msg, err := p.getDestinationChannel(T, toChannel)
+	if msg != nil {
+		return "", msg, err
+	}
+
+	postList, appErr := p.API.GetPostThread(postID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get post list", "post_id", postID, "error", appErr.Error())
+		return "", toPtr(T("command.share.post_not_found")), nil
+	}
+	original, ok := postList.Posts[postID]
+	if !ok {
+		return "", toPtr(T("error.generic")), fmt.Errorf("failed to find post %s in the thread", postID)
+	}
+	// The preview shows the content of the post, so it must not be shown to users who can't read it
+	if !p.API.HasPermissionToChannel(userID, original.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		p.API.LogWarn("user doesn't have permission to read the post.", "user_id", userID, "post_id", postID)
+		return "", toPtr(T("command.share.post_not_found")), nil
+	}
+	if !isShareablePost(original) {
+		return "", toPtr(T("share.system_message")), nil
+	}
+
+	channel, appErr := p.getChannel(original.ChannelId)
+	if appErr != nil {
+		return "", toPtr(T("error.generic")), fmt.Errorf("failed to get channel %w", appErr)
+	}
+	// The permalinks are made with the team of the post, and posts in DM/GM channels fall back to the team of the request
+	var team *model.Team
+	if !newChannel.IsGroupOrDirect() {
+		teamID := channel.TeamId
+		if teamID == "" {
+			teamID = request.TeamId
+		}
+		team, appErr = p.getTeam(teamID)
+		if appErr != nil {
+			return "", toPtr(T("error.generic")), fmt.Errorf("failed to get team %w", appErr)
+		}
+	}
+
+	message, additionalText := p.buildShareMessage(postList, original, channel, team, additionalText, renderMode, shareThread, includeReactions)
+	// Additional text is placed by MessageWillBePosted when the post is created
+	return placeAdditionalText(message, additionalText, textPosition), nil, nil
+}
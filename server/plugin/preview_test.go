@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandlePreview(t *testing.T) {
+	newRequest := func(submission map[string]interface{}) *http.Request {
+		request := &model.SubmitDialogRequest{
+			CallbackId: "post_id",
+			UserId:     "user_id",
+			ChannelId:  "channel_id",
+			TeamId:     "team_id",
+			Submission: submission,
+		}
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/preview", strings.NewReader(string(request.ToJson())))
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		return r
+	}
+
+	t.Run("preview shared post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id"}, nil)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		w := httptest.NewRecorder()
+		p.handlePreview(w, newRequest(map[string]interface{}{
+			toChannelKey:      "to_channel_id",
+			shareTypeKey:      shareTypeShare,
+			additionalTextKey: "Look at this",
+		}))
+
+		var response previewResponse
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal("Look at this\n\n> Shared from ~town-square. ([original post](http://localhost:8065/team/pl/post_id))", response.Message)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+		api.AssertNotCalled(t, "SendEphemeralPost", mock.Anything, mock.Anything)
+	})
+	t.Run("preview post in DM channel with team of the request", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "dm_id"}, nil)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "dm_id").Return(&model.Channel{Id: "dm_id", Name: "user_id__author_id", Type: model.CHANNEL_DIRECT}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "dm_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("HasPermissionToChannel", "user_id", "dm_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		w := httptest.NewRecorder()
+		p.handlePreview(w, newRequest(map[string]interface{}{
+			toChannelKey: "to_channel_id",
+			shareTypeKey: shareTypeShare,
+		}))
+
+		var response previewResponse
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&response))
+		assert.Contains(response.Message, "([original post](http://localhost:8065/team/pl/post_id))")
+	})
+	t.Run("preview shared post with footer", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EnableShare: true, SharedPostFooter: "Posted via SharePost"})
+		mockSourcePost(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+
+		w := httptest.NewRecorder()
+		p.handlePreview(w, newRequest(map[string]interface{}{
+			toChannelKey:      "to_channel_id",
+			shareTypeKey:      shareTypeShare,
+			additionalTextKey: "Look at this",
+			textPositionKey:   textPositionBelow,
+		}))
+
+		var response previewResponse
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&response))
+		assert.True(strings.HasSuffix(response.Message, "\n\n---\nPosted via SharePost\n\nLook at this"))
+	})
+	t.Run("preview shared post with reactions", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetTeam", "team_id").Return(&model.Team{Id: "team_id", Name: "team"}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{
+			{UserId: "user1", PostId: "post_id", EmojiName: "+1"},
+			{UserId: "user2", PostId: "post_id", EmojiName: "+1"},
+			{UserId: "user1", PostId: "post_id", EmojiName: "smile"},
+		}, nil)
+
+		w := httptest.NewRecorder()
+		p.handlePreview(w, newRequest(map[string]interface{}{
+			toChannelKey:        "to_channel_id",
+			shareTypeKey:        shareTypeShare,
+			includeReactionsKey: true,
+		}))
+
+		var response previewResponse
+		assert.Equal(http.StatusOK, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&response))
+		assert.True(strings.HasSuffix(response.Message, "\n\n:+1: 2 :smile: 1"))
+	})
+	t.Run("post in unreadable channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockSourcePost(api)
+
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "post_id", UserId: "author_id", ChannelId: "private_channel_id", Message: "secret"})
+		postList.AddOrder("post_id")
+		api.On("GetPostThread", "post_id").Return(postList, nil)
+		api.On("HasPermissionToChannel", "user_id", "private_channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+		api.On("LogWarn", GetMockArgumentsWithType("string", 5)...).Return()
+
+		w := httptest.NewRecorder()
+		p.handlePreview(w, newRequest(map[string]interface{}{
+			toChannelKey: "to_channel_id",
+			shareTypeKey: shareTypeShare,
+		}))
+
+		var response previewResponse
+		assert.Equal(http.StatusBadRequest, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal("", response.Message)
+		assert.Equal("The post to share was not found.", response.Error)
+	})
+	t.Run("move can't be previewed", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		w := httptest.NewRecorder()
+		p.handlePreview(w, newRequest(map[string]interface{}{
+			toChannelKey: "to_channel_id",
+			shareTypeKey: shareTypeMove,
+		}))
+
+		var response previewResponse
+		assert.Equal(http.StatusBadRequest, w.Result().StatusCode)
+		assert.Nil(json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal("Only sharing can be previewed.", response.Error)
+	})
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the number of requests per user in a fixed time window
+type rateLimiter struct {
+	window time.Duration
+
+	lock    sync.Mutex
+	counter map[string]*rateLimitCounter
+}
+
+type rateLimitCounter struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		window:  window,
+		counter: map[string]*rateLimitCounter{},
+	}
+}
+
+// allow counts the request of the user, and reports whether the request is within the limit.
+// Zero or negative limit means unlimited.
+func (l *rateLimiter) allow(userID string, limit int, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	c, ok := l.counter[userID]
+	if !ok || now.Sub(c.start) >= l.window {
+		l.removeExpired(now)
+		c = &rateLimitCounter{start: now}
+		l.counter[userID] = c
+	}
+	if c.count >= limit {
+		return false
+	}
+	c.count++
+	return true
+}
+
+// removeExpired removes the counters of finished windows so that the map doesn't keep growing
+func (l *rateLimiter) removeExpired(now time.Time) {
+	for userID, c := range l.counter {
+		if now.Sub(c.start) >= l.window {
+			delete(l.counter, userID)
+		}
+	}
+}
+
+// allowShare counts the operation of the user, and reports whether it's within the rate limit of sharing.
+// Moving, copying and duplicating posts create posts as sharing does, so they're counted together.
+func (p *SharePostPlugin) allowShare(userID string) bool {
+	if p.shareRateLimiter.allow(userID, p.getConfiguration().ShareRateLimitPerMinute, time.Now()) {
+		return true
+	}
+	p.API.LogWarn("user exceeded the rate limit of sharing.", "user_id", userID)
+	return false
+}
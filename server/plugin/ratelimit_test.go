@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+	l := newRateLimiter(time.Minute)
+	now := time.Now()
+
+	assert.True(l.allow("user1", 2, now))
+	assert.True(l.allow("user1", 2, now.Add(time.Second)))
+	assert.False(l.allow("user1", 2, now.Add(2*time.Second)))
+	assert.True(l.allow("user2", 2, now.Add(2*time.Second)), "other users are not limited")
+	assert.True(l.allow("user1", 2, now.Add(time.Minute)), "new window is started")
+	assert.True(l.allow("user1", 0, now.Add(time.Minute)), "zero means unlimited")
+}
+
+func TestShareRateLimit(t *testing.T) {
+	for _, path := range []string{"/api/v1/share", "/api/v1/move"} {
+		t.Run(path, func(t *testing.T) {
+			assert := assert.New(t)
+			api := &plugintest.API{}
+			defer api.AssertExpectations(t)
+			p := setupTestPlugin(api)
+			p.setConfiguration(&configuration{ShareRateLimitPerMinute: 1})
+			p.router = p.InitAPI()
+			p.shareRateLimiter.allow("user_id", 1, time.Now())
+
+			api.On("LogDebug", GetMockArgumentsWithType("string", 7)...).Return()
+			api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+			api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"user_id":"user_id","channel_id":"channel_id","callback_id":"post_id"}`))
+			r.Header.Set("X-Requested-With", "XMLHttpRequest")
+			r.Header.Set("Mattermost-User-Id", "user_id")
+			p.ServeHTTP(nil, w, r)
+
+			assert.Equal(http.StatusTooManyRequests, w.Result().StatusCode)
+			api.AssertNotCalled(t, "GetPost", mock.Anything)
+		})
+	}
+}
+
+func TestBulkMoveRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	api := &plugintest.API{}
+	defer api.AssertExpectations(t)
+	p := setupTestPlugin(api)
+	p.setConfiguration(&configuration{EnableMove: true, ShareRateLimitPerMinute: 1})
+	p.shareRateLimiter = newRateLimiter(time.Minute)
+	p.shareRateLimiter.allow("user_id", 1, time.Now())
+	api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return()
+
+	result := p.bulkMovePost("user_id", "team_id", "post_id", "to_channel_id")
+
+	assert.False(result.Moved)
+	assert.Equal("You're sharing too fast, please slow down.", result.Error)
+	api.AssertNotCalled(t, "GetPost", mock.Anything)
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// shareTypesResponse is the response of handleShareTypes, in the format of options of dialog elements
+type shareTypesResponse struct {
+	Items []*model.PostActionOptions `json:"items"`
+}
+
+// handleShareTypes returns the share types the user can perform on the post given by `post_id`, so that the dialog
+// offers only them. The overrides of the configuration of the team of the post apply, and `team_id`, the team where
+// the dialog is opened, is used only for posts in DM/GM channels. The same checks are done again on submitting the dialog.
+func (p *SharePostPlugin) handleShareTypes(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	query := r.URL.Query()
+	postID := resolvePostID(query.Get("post_id"))
+	if postID == "" {
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "post_id is required")
+		return
+	}
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		p.API.LogWarn("failed to get post", "post_id", postID, "error", appErr.Error())
+		// Posts of channels the user can't read are reported as not found, not to reveal their existence
+		writeJSONError(w, http.StatusNotFound, errorCodeInvalidRequest, "post not found")
+		return
+	}
+	if !p.canReadPost(userID, post) {
+		writeJSONError(w, http.StatusNotFound, errorCodeInvalidRequest, "post not found")
+		return
+	}
+
+	teamID, appErr := p.getPostTeamID(post, query.Get("team_id"))
+	if appErr != nil {
+		p.API.LogWarn("failed to get team of the post", "post_id", postID, "error", appErr.Error())
+		writeJSONError(w, http.StatusInternalServerError, errorCodeInternal, "failed to get share types")
+		return
+	}
+
+	T := p.getLocalizer(userID)
+	items := []*model.PostActionOptions{}
+	for _, shareType := range p.availableShareTypes(userID, teamID, post) {
+		items = append(items, &model.PostActionOptions{Text: T("share_type." + shareType), Value: shareType})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(shareTypesResponse{Items: items}); err != nil {
+		p.API.LogWarn("failed to write share types", "error", err.Error())
+	}
+}
+
+// availableShareTypes returns the share types the user can perform on the readable post, in the order of the dialog.
+// Moving requires being a member of the channel and having permission to delete the post, as movePost checks.
+// Moving a reply with its thread starts from the root post, so the root post must also be deletable for replies.
+func (p *SharePostPlugin) availableShareTypes(userID, teamID string, post *model.Post) []string {
+	if !isShareablePost(post) {
+		return []string{}
+	}
+	config := p.getConfiguration().forTeam(teamID)
+	shareTypes := []string{}
+	if config.EnableShare {
+		shareTypes = append(shareTypes, shareTypeShare)
+	}
+	shareTypes = append(shareTypes, shareTypeCopy, shareTypeDuplicate)
+	if config.EnableMove && p.canMovePost(userID, post) {
+		shareTypes = append(shareTypes, shareTypeMove)
+	}
+	return append(shareTypes, shareTypePermalink)
+}
+
+// canMovePost checks whether the user is a member of the channel of the post and can delete it and its root post
+func (p *SharePostPlugin) canMovePost(userID string, post *model.Post) bool {
+	if _, appErr := p.API.GetChannelMember(post.ChannelId, userID); appErr != nil || !p.canDeletePost(userID, post) {
+		return false
+	}
+	if post.RootId == "" {
+		return true
+	}
+	root, appErr := p.API.GetPost(post.RootId)
+	if appErr != nil {
+		p.API.LogWarn("failed to get root post", "post_id", post.Id, "error", appErr.Error())
+		return false
+	}
+	return p.canDeletePost(userID, root)
+}
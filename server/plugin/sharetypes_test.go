@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleShareTypes(t *testing.T) {
+	serve := func(p *SharePostPlugin, query string) (int, []string) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/share_types?"+query, nil)
+		r.Header.Set("Mattermost-User-Id", "user_id")
+		w := httptest.NewRecorder()
+		p.handleShareTypes(w, r)
+
+		result := w.Result()
+		defer result.Body.Close()
+		var response shareTypesResponse
+		_ = json.NewDecoder(result.Body).Decode(&response)
+		values := []string{}
+		for _, item := range response.Items {
+			values = append(values, item.Value)
+		}
+		return result.StatusCode, values
+	}
+	post := &model.Post{Id: "post_id", UserId: "user_id", ChannelId: "channel_id", Message: "message"}
+
+	t.Run("all share types", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(post, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_POST).Return(true)
+
+		code, shareTypes := serve(p, "post_id=post_id&team_id=team_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal([]string{shareTypeShare, shareTypeCopy, shareTypeDuplicate, shareTypeMove, shareTypePermalink}, shareTypes)
+	})
+	t.Run("no permission to delete the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		othersPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message"}
+		api.On("GetPost", "post_id").Return(othersPost, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_OTHERS_POSTS).Return(false)
+
+		code, shareTypes := serve(p, "post_id=post_id&team_id=team_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal([]string{shareTypeShare, shareTypeCopy, shareTypeDuplicate, shareTypePermalink}, shareTypes)
+	})
+	t.Run("no permission to delete the root post of the reply", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		reply := &model.Post{Id: "reply_id", UserId: "user_id", ChannelId: "channel_id", RootId: "root_id", Message: "reply"}
+		api.On("GetPost", "reply_id").Return(reply, nil)
+		api.On("GetPost", "root_id").Return(&model.Post{Id: "root_id", UserId: "author_id", ChannelId: "channel_id", Message: "root"}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_POST).Return(true)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_DELETE_OTHERS_POSTS).Return(false)
+
+		code, shareTypes := serve(p, "post_id=reply_id&team_id=team_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal([]string{shareTypeShare, shareTypeCopy, shareTypeDuplicate, shareTypePermalink}, shareTypes)
+	})
+	t.Run("disabled for the team", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		disabled := false
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: true, teamOverrides: map[string]*teamOverride{
+			"team_id": {EnableShare: &disabled, EnableMove: &disabled},
+		}})
+		api.On("GetPost", "post_id").Return(post, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+
+		code, shareTypes := serve(p, "post_id=post_id&team_id=team_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal([]string{shareTypeCopy, shareTypeDuplicate, shareTypePermalink}, shareTypes)
+	})
+	t.Run("overrides of the team of the post", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		disabled := false
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: true, teamOverrides: map[string]*teamOverride{
+			"team_id": {EnableShare: &disabled, EnableMove: &disabled},
+		}})
+		api.On("GetPost", "post_id").Return(post, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+
+		// The team sent by the client is ignored for posts in a team
+		code, shareTypes := serve(p, "post_id=post_id&team_id=other_team_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal([]string{shareTypeCopy, shareTypeDuplicate, shareTypePermalink}, shareTypes)
+	})
+	t.Run("post in DM channel", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		disabled := false
+		p.setConfiguration(&configuration{EnableShare: true, EnableMove: true, teamOverrides: map[string]*teamOverride{
+			"team_id": {EnableShare: &disabled, EnableMove: &disabled},
+		}})
+		dmPost := &model.Post{Id: "post_id", UserId: "user_id", ChannelId: "dm_channel_id", Message: "message"}
+		api.On("GetPost", "post_id").Return(dmPost, nil)
+		api.On("HasPermissionToChannel", "user_id", "dm_channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "dm_channel_id").Return(&model.Channel{Id: "dm_channel_id", Name: "user_id__other_id", Type: model.CHANNEL_DIRECT}, nil)
+
+		code, shareTypes := serve(p, "post_id=post_id&team_id=team_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Equal([]string{shareTypeCopy, shareTypeDuplicate, shareTypePermalink}, shareTypes)
+	})
+	t.Run("system message", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(&model.Post{Id: "post_id", ChannelId: "channel_id", Type: model.POST_JOIN_CHANNEL}, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(true)
+		api.On("GetChannel", "channel_id").Return(&model.Channel{Id: "channel_id", Name: "town-square", TeamId: "team_id", Type: model.CHANNEL_OPEN}, nil)
+
+		code, shareTypes := serve(p, "post_id=post_id")
+
+		assert.Equal(http.StatusOK, code)
+		assert.Empty(shareTypes)
+	})
+	t.Run("no permission to read the post", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetPost", "post_id").Return(post, nil)
+		api.On("HasPermissionToChannel", "user_id", "channel_id", model.PERMISSION_READ_CHANNEL).Return(false)
+
+		code, _ := serve(p, "post_id=post_id")
+
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+	t.Run("missing post id", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		code, _ := serve(p, "")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
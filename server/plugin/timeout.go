@@ -0,0 +1,43 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// newOperationContext returns the context derived from parent with the configured deadline of sharing/moving a post.
+// Plugin API calls can't be canceled once started, so the context is checked between the calls.
+func (p *SharePostPlugin) newOperationContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(p.getConfiguration().OperationTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// checkTimeout returns the message for the user and the error when the deadline of the operation is exceeded.
+func checkTimeout(T localizer, ctx context.Context) (*string, error) {
+	if err := ctx.Err(); err != nil {
+		return toPtr(T("error.timed_out")), fmt.Errorf("operation timed out %w", err)
+	}
+	return nil, nil
+}
+
+// isTimeout reports whether the error is caused by the deadline of the operation.
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// sleepContext waits for the duration, or returns the error when the context is done before that.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewOperationContext(t *testing.T) {
+	t.Run("with timeout", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		p.setConfiguration(&configuration{OperationTimeoutSeconds: 10})
+		ctx, cancel := p.newOperationContext(context.Background())
+		defer cancel()
+
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		remaining := time.Until(deadline)
+		assert.True(t, remaining > 9*time.Second && remaining <= 10*time.Second)
+	})
+	t.Run("without timeout", func(t *testing.T) {
+		p := setupTestPlugin(&plugintest.API{})
+		ctx, cancel := p.newOperationContext(context.Background())
+		defer cancel()
+
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+	})
+}
+
+func TestOperationTimeout(t *testing.T) {
+	request := &model.SubmitDialogRequest{
+		CallbackId: "post_id",
+		UserId:     "user_id",
+		ChannelId:  "channel_id",
+		TeamId:     "team_id",
+	}
+
+	t.Run("share after the deadline", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("GetChannel", "to_channel_id").Return(&model.Channel{Id: "to_channel_id", Name: "off-topic", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", "to_channel_id", "user_id").Return(&model.ChannelMember{}, nil)
+		api.On("HasPermissionToChannel", "user_id", "to_channel_id", model.PERMISSION_CREATE_POST).Return(true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		msg, _, err := p.sharePost(ctx, request, "to_channel_id", "", "", renderModePlain, textPositionAbove, false, false, false)
+
+		assert.Equal("The operation timed out. Please try again later.", *msg)
+		assert.True(isTimeout(err))
+		api.AssertNotCalled(t, "GetPostThread", mock.Anything)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("moving thread is rolled back at the deadline", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		p := setupTestPlugin(api)
+
+		rootPost := &model.Post{Id: "post_id", UserId: "author_id", ChannelId: "channel_id", Message: "message", CreateAt: 1}
+		replyPost := &model.Post{Id: "reply_id", UserId: "author_id", ChannelId: "channel_id", RootId: "post_id", Message: "reply", CreateAt: 2}
+		// Registered before mockMovePost so that the original posts are never deleted
+		api.On("DeletePost", "moved_post_id").Return(nil).Once()
+		mockMovePost(api, rootPost, replyPost)
+		api.On("GetReactions", "post_id").Return([]*model.Reaction{}, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// The deadline is exceeded while creating the root post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			cancel()
+			post.Id = "moved_post_id"
+			return post
+		}, nil).Once()
+
+		msg, _, err := p.movePost(ctx, request, "to_channel_id", "", "", true, false, false, false)
+
+		assert.Equal("The operation timed out. Please try again later.", *msg)
+		assert.True(isTimeout(err))
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertCalled(t, "DeletePost", "moved_post_id")
+		api.AssertNotCalled(t, "DeletePost", "post_id")
+		api.AssertNotCalled(t, "DeletePost", "reply_id")
+	})
+	t.Run("stop retrying at the deadline", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, &model.AppError{StatusCode: http.StatusServiceUnavailable})
+		api.On("LogWarn", "failed to create post, retrying", "attempt", 1, "error", mock.AnythingOfType("string")).Return()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, appErr := p.createPostWithRetry(ctx, &model.Post{})
+
+		assert.NotNil(appErr)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+}
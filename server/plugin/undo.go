@@ -0,0 +1,273 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	// undoKeyPrefix is the prefix of KV store keys for undo records. Keys are `undo_<moved post id>`.
+	undoKeyPrefix = "undo_"
+
+	undoContextKeyMovedPostID = "moved_post_id"
+)
+
+// undoRecord is the state required to undo moving a post
+type undoRecord struct {
+	UserID            string `json:"user_id"`
+	OriginalChannelID string `json:"original_channel_id"`
+	OriginalRootID    string `json:"original_root_id"`
+	OriginalMessage   string `json:"original_message"`
+	MovedPostID       string `json:"moved_post_id"`
+	RedirectNoteID    string `json:"redirect_note_id"`
+	// UnpinOnUndo is true when the post was pinned by moving it, so that it's restored unpinned
+	UnpinOnUndo bool  `json:"unpin_on_undo,omitempty"`
+	ExpireAt    int64 `json:"expire_at"`
+}
+
+func makeUndoKey(movedPostID string) string {
+	return undoKeyPrefix + movedPostID
+}
+
+// saveUndoRecord stores the undo record, which expires after the undo window, in the KV store.
+// It returns false when undoing is disabled or the record can't be stored.
+func (p *SharePostPlugin) saveUndoRecord(record *undoRecord) bool {
+	window := time.Duration(p.getConfiguration().UndoMoveWindowMinutes) * time.Minute
+	if window <= 0 {
+		return false
+	}
+	record.ExpireAt = model.GetMillis() + int64(window/time.Millisecond)
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		p.API.LogWarn("failed to marshal undo record", "post_id", record.MovedPostID, "error", err.Error())
+		return false
+	}
+	if appErr := p.API.KVSetWithExpiry(makeUndoKey(record.MovedPostID), b, int64(window/time.Second)); appErr != nil {
+		p.API.LogWarn("failed to save undo record", "post_id", record.MovedPostID, "error", appErr.Error())
+		return false
+	}
+	return true
+}
+
+// sendMoveConfirmation sends the ephemeral post notifying the result of moving, with the button to undo the move if undoable.
+// The original post is gone after moving, so the confirmation is sent as a direct message from the bot if the ephemeral post fails.
+func (p *SharePostPlugin) sendMoveConfirmation(channelID, userID, message, movedPostID string, undoable bool) {
+	T := p.getLocalizer(userID)
+	post := &model.Post{
+		ChannelId: channelID,
+		UserId:    p.botUserID,
+		Message:   message,
+	}
+	if undoable {
+		model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+			Text: T("undo.hint", p.getConfiguration().UndoMoveWindowMinutes),
+			Actions: []*model.PostAction{{
+				Name: T("undo.button"),
+				Integration: &model.PostActionIntegration{
+					URL: fmt.Sprintf("/plugins/%s/api/v1/undo", manifest.Id),
+					Context: map[string]interface{}{
+						undoContextKeyMovedPostID: movedPostID,
+						csrfContextKey:            p.makeCSRFToken(userID),
+					},
+				},
+			}},
+		}})
+	}
+	if p.sendEphemeralPost(userID, post) {
+		return
+	}
+	channel, appErr := p.API.GetDirectChannel(p.botUserID, userID)
+	if appErr != nil {
+		p.API.LogError("failed to get direct channel to send the move confirmation.", "user_id", userID, "error", appErr.Error())
+		return
+	}
+	post.ChannelId = channel.Id
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogError("failed to send the move confirmation.", "user_id", userID, "error", appErr.Error())
+	}
+}
+
+func (p *SharePostPlugin) handleUndoMove(w http.ResponseWriter, r *http.Request) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		p.API.LogWarn("Failed to decode PostActionIntegrationRequest")
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid request")
+		return
+	}
+	if request.UserId != r.Header.Get("Mattermost-User-Id") {
+		p.API.LogWarn("invalid user")
+		writeJSONError(w, http.StatusUnauthorized, errorCodeUnauthorized, "not authorized")
+		return
+	}
+	movedPostID, ok := request.Context[undoContextKeyMovedPostID].(string)
+	if !ok {
+		p.API.LogWarn("failed to get moved post id from the context", "context", request.Context)
+		writeJSONError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid request")
+		return
+	}
+
+	msg, err := p.undoMove(request.UserId, movedPostID)
+	if err != nil {
+		p.API.LogWarn("failed to undo moving post", "error", err.Error())
+	}
+
+	response := &model.PostActionIntegrationResponse{EphemeralText: msg}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(response.ToJson()); err != nil {
+		p.API.LogWarn("failed to write PostActionIntegrationResponse", "error", err.Error())
+	}
+}
+
+// clearMoveProvenance removes the props stamped by movePost from the post moved back to the original channel.
+// Posts moved as the bot are restored to their original authors.
+func clearMoveProvenance(post *model.Post) {
+	post.DelProp(postPropsKeyMovedFromChannelID)
+	post.DelProp(postPropsKeyMovedByUserID)
+	post.DelProp(postPropsKeyMovedAt)
+	removeBotAttribution(post)
+}
+
+// undoMove moves the moved post (and its thread) back to the original channel, and deletes the moved post
+func (p *SharePostPlugin) undoMove(userID, movedPostID string) (string, error) {
+	T := p.getLocalizer(userID)
+	key := makeUndoKey(movedPostID)
+	b, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return T("error.generic"), fmt.Errorf("failed to get undo record %w", appErr)
+	}
+	if b == nil {
+		return T("undo.expired"), nil
+	}
+	var record undoRecord
+	if err := json.Unmarshal(b, &record); err != nil {
+		return T("error.generic"), fmt.Errorf("failed to unmarshal undo record %w", err)
+	}
+	if record.UserID != userID {
+		return T("undo.not_mover"), nil
+	}
+	if model.GetMillis() > record.ExpireAt {
+		return T("undo.expired"), nil
+	}
+	// The record is claimed by deleting it before restoring the posts, so that clicking Undo twice doesn't restore them twice
+	claimed, appErr := p.API.KVCompareAndDelete(key, b)
+	if appErr != nil {
+		return T("error.generic"), fmt.Errorf("failed to claim undo record %w", appErr)
+	}
+	if !claimed {
+		return T("undo.expired"), nil
+	}
+
+	postList, appErr := p.API.GetPostThread(movedPostID)
+	if appErr != nil {
+		p.restoreUndoRecord(key, b, record.ExpireAt)
+		return T("error.generic"), fmt.Errorf("failed to get post list %w", appErr)
+	}
+	movedPost, appErr := p.API.GetPost(movedPostID)
+	if appErr != nil {
+		p.restoreUndoRecord(key, b, record.ExpireAt)
+		return T("error.generic"), fmt.Errorf("failed to get post %w", appErr)
+	}
+	// The original message is restored in the root post, so the replies continuing the oversized message are deleted
+	// instead of being moved back
+	postList, continuationIds := splitContinuations(postList, movedPostID)
+
+	newPost, err := p.clonePost(movedPost, userID)
+	if err != nil {
+		p.restoreUndoRecord(key, b, record.ExpireAt)
+		return T("error.generic"), fmt.Errorf("failed to clone post %w", err)
+	}
+	clearMoveProvenance(newPost)
+	// The moved post contains the additional text, so the original message is restored
+	newPost.ChannelId = record.OriginalChannelID
+	// A reply moved alone is restored in its original thread
+	newPost.RootId = record.OriginalRootID
+	newPost.ParentId = record.OriginalRootID
+	newPost.Message = record.OriginalMessage
+	p.suppressMentions(newPost)
+	newPost.DelProp(postPropsKeyAdditionalText)
+	if record.UnpinOnUndo {
+		newPost.IsPinned = false
+	}
+	restoredPost, appErr := p.API.CreatePost(newPost)
+	if appErr != nil {
+		p.restoreUndoRecord(key, b, record.ExpireAt)
+		return T("error.generic"), fmt.Errorf("failed to create post %w", appErr)
+	}
+	p.copyReactions(movedPostID, restoredPost.Id)
+
+	createdPostIds := []string{restoredPost.Id}
+	ctx, cancel := p.newOperationContext(context.Background())
+	defer cancel()
+	// The post moved into a thread of the destination channel doesn't have its own replies
+	willDeletePostIds := []string{}
+	if movedPost.RootId == "" {
+		var createdChildIds []string
+		willDeletePostIds, createdChildIds, err = p.moveChildren(ctx, postList, movedPostID, restoredPost, userID, clearMoveProvenance)
+		createdPostIds = append(createdPostIds, createdChildIds...)
+		if err != nil {
+			msg, _, err := p.rollbackThread(T, createdPostIds, err)
+			p.restoreUndoRecord(key, b, record.ExpireAt)
+			return *msg, err
+		}
+	}
+
+	// Delete the root post at last, because deleting root post also deletes the posts in the thread
+	willDeletePostIds = append(willDeletePostIds, continuationIds...)
+	willDeletePostIds = append(willDeletePostIds, movedPostID)
+	if record.RedirectNoteID != "" {
+		willDeletePostIds = append(willDeletePostIds, record.RedirectNoteID)
+	}
+	for _, id := range willDeletePostIds {
+		if appErr := p.API.DeletePost(id); appErr != nil {
+			p.API.LogWarn("failed to delete post", "post_id", id)
+		}
+	}
+
+	p.recordAudit(&auditEntry{
+		Action:               auditActionUndo,
+		UserID:               userID,
+		PostID:               movedPostID,
+		NewPostID:            restoredPost.Id,
+		SourceChannelID:      movedPost.ChannelId,
+		DestinationChannelID: record.OriginalChannelID,
+	})
+	return T("undo.done"), nil
+}
+
+// restoreUndoRecord puts back the undo record claimed by undoMove when undoing fails before changing any post,
+// so that the user can retry within the rest of the undo window.
+func (p *SharePostPlugin) restoreUndoRecord(key string, b []byte, expireAt int64) {
+	ttl := (expireAt - model.GetMillis()) / int64(time.Second/time.Millisecond)
+	if ttl <= 0 {
+		return
+	}
+	if appErr := p.API.KVSetWithExpiry(key, b, ttl); appErr != nil {
+		p.API.LogWarn("failed to restore undo record", "key", key, "error", appErr.Error())
+	}
+}
+
+// splitContinuations removes the replies continuing the message of the moved post from the post list.
+// It returns the post list without them and their IDs.
+func splitContinuations(postList *model.PostList, movedPostID string) (*model.PostList, []string) {
+	rest := model.NewPostList()
+	continuationIds := []string{}
+	for _, id := range postList.Order {
+		post, ok := postList.Posts[id]
+		if !ok {
+			continue
+		}
+		if continuationOf, _ := post.GetProp(postPropsKeyContinuationOf).(string); continuationOf == movedPostID {
+			continuationIds = append(continuationIds, id)
+			continue
+		}
+		rest.AddPost(post)
+		rest.AddOrder(id)
+	}
+	return rest, continuationIds
+}
@@ -0,0 +1,357 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSaveUndoRecord(t *testing.T) {
+	t.Run("undo is disabled", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+
+		assert.False(t, p.saveUndoRecord(&undoRecord{MovedPostID: "moved_post_id"}))
+	})
+	t.Run("save record with expiry", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{UndoMoveWindowMinutes: 5})
+
+		api.On("KVSetWithExpiry", "undo_moved_post_id", mock.Anything, int64(300)).Return(nil)
+
+		assert.True(p.saveUndoRecord(&undoRecord{UserID: "user_id", MovedPostID: "moved_post_id"}))
+	})
+}
+
+func TestSendMoveConfirmation(t *testing.T) {
+	t.Run("ephemeral post", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+
+		p.sendMoveConfirmation("channel_id", "user_id", "moved", "moved_post_id", false)
+
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+	t.Run("direct message when ephemeral post fails", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_user_id"
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(nil)
+		api.On("LogWarn", "failed to send ephemeral post", "user_id", "user_id", "channel_id", "channel_id").Return()
+		api.On("GetDirectChannel", "bot_user_id", "user_id").Return(&model.Channel{Id: "dm_channel_id"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("dm_channel_id", post.ChannelId)
+			assert.Equal("bot_user_id", post.UserId)
+			assert.Equal("moved", post.Message)
+			return post
+		}, nil)
+
+		p.sendMoveConfirmation("channel_id", "user_id", "moved", "moved_post_id", false)
+	})
+	t.Run("direct message fails", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.botUserID = "bot_user_id"
+		api.On("SendEphemeralPost", "user_id", mock.AnythingOfType("*model.Post")).Return(nil)
+		api.On("LogWarn", "failed to send ephemeral post", "user_id", "user_id", "channel_id", "channel_id").Return()
+		api.On("GetDirectChannel", "bot_user_id", "user_id").Return(&model.Channel{Id: "dm_channel_id"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, &model.AppError{Message: "failed"})
+		api.On("LogError", GetMockArgumentsWithType("string", 5)...).Return()
+
+		p.sendMoveConfirmation("channel_id", "user_id", "moved", "moved_post_id", false)
+	})
+}
+
+func TestClearMoveProvenance(t *testing.T) {
+	t.Run("moved by the author", func(t *testing.T) {
+		post := &model.Post{UserId: "author_id", Message: "message"}
+		post.AddProp(postPropsKeyMovedFromChannelID, "channel_id")
+
+		clearMoveProvenance(post)
+
+		assert.Equal(t, "author_id", post.UserId)
+		assert.Equal(t, "message", post.Message)
+		assert.Nil(t, post.GetProp(postPropsKeyMovedFromChannelID))
+	})
+	t.Run("moved as bot", func(t *testing.T) {
+		post := &model.Post{UserId: "bot_id", Message: "Originally by @author\n\nmessage\n\nsecond paragraph"}
+		post.AddProp(postPropsKeyOriginalUserID, "author_id")
+
+		clearMoveProvenance(post)
+
+		assert.Equal(t, "author_id", post.UserId)
+		assert.Equal(t, "message\n\nsecond paragraph", post.Message)
+		assert.Nil(t, post.GetProp(postPropsKeyOriginalUserID))
+	})
+	t.Run("moved as bot without message", func(t *testing.T) {
+		post := &model.Post{UserId: "bot_id", Message: "Originally by @author"}
+		post.AddProp(postPropsKeyOriginalUserID, "author_id")
+
+		clearMoveProvenance(post)
+
+		assert.Equal(t, "author_id", post.UserId)
+		assert.Equal(t, "", post.Message)
+	})
+}
+
+func TestUndoMove(t *testing.T) {
+	mockUndoRecord := func(api *plugintest.API, record undoRecord) {
+		b, _ := json.Marshal(record)
+		api.On("KVGet", "undo_moved_post_id").Return(b, nil)
+	}
+
+	t.Run("record not found", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		api.On("KVGet", "undo_moved_post_id").Return(nil, nil)
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "This move can no longer be undone.", msg)
+	})
+	t.Run("other user", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{UserID: "other_user_id", MovedPostID: "moved_post_id", ExpireAt: model.GetMillis() + 60000})
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Only the user who moved the post can undo the move.", msg)
+	})
+	t.Run("expired", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{UserID: "user_id", MovedPostID: "moved_post_id", ExpireAt: model.GetMillis() - 1})
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "This move can no longer be undone.", msg)
+	})
+	t.Run("already undone", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{UserID: "user_id", MovedPostID: "moved_post_id", ExpireAt: model.GetMillis() + 60000})
+		// The other click has claimed the record in the meantime
+		api.On("KVCompareAndDelete", "undo_moved_post_id", mock.AnythingOfType("[]uint8")).Return(false, nil)
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "This move can no longer be undone.", msg)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		api.AssertNotCalled(t, "DeletePost", mock.Anything)
+	})
+	t.Run("restore the record when undoing fails", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{UserID: "user_id", MovedPostID: "moved_post_id", ExpireAt: model.GetMillis() + 60000})
+		api.On("KVCompareAndDelete", "undo_moved_post_id", mock.AnythingOfType("[]uint8")).Return(true, nil)
+		api.On("GetPostThread", "moved_post_id").Return(nil, &model.AppError{Message: "failed"})
+		api.On("KVSetWithExpiry", "undo_moved_post_id", mock.AnythingOfType("[]uint8"), mock.AnythingOfType("int64")).Return(nil)
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "Something went wrong. Please try again later.", msg)
+	})
+	t.Run("undo", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{
+			UserID:            "user_id",
+			OriginalChannelID: "channel_id",
+			OriginalMessage:   "message",
+			MovedPostID:       "moved_post_id",
+			RedirectNoteID:    "note_id",
+			ExpireAt:          model.GetMillis() + 60000,
+		})
+
+		movedPost := &model.Post{Id: "moved_post_id", UserId: "author_id", ChannelId: "to_channel_id", Message: "additional\n\nmessage"}
+		movedPost.AddProp(postPropsKeyAdditionalText, "additional\n\n")
+		postList := model.NewPostList()
+		postList.AddPost(movedPost)
+		postList.AddOrder(movedPost.Id)
+		api.On("GetPostThread", "moved_post_id").Return(postList, nil)
+		api.On("GetPost", "moved_post_id").Return(movedPost, nil)
+		api.On("CopyFileInfos", "user_id", mock.Anything).Return([]string{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("channel_id", post.ChannelId)
+			assert.Equal("message", post.Message)
+			assert.Nil(post.GetProp(postPropsKeyAdditionalText))
+			post.Id = "restored_post_id"
+			return post
+		}, nil)
+		api.On("GetReactions", "moved_post_id").Return([]*model.Reaction{}, nil)
+		api.On("DeletePost", "moved_post_id").Return(nil)
+		api.On("DeletePost", "note_id").Return(nil)
+		api.On("KVCompareAndDelete", "undo_moved_post_id", mock.AnythingOfType("[]uint8")).Return(true, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(err)
+		assert.Equal("The move was undone.", msg)
+	})
+	t.Run("unpin the post pinned by moving", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{
+			UserID:            "user_id",
+			OriginalChannelID: "channel_id",
+			OriginalMessage:   "message",
+			MovedPostID:       "moved_post_id",
+			UnpinOnUndo:       true,
+			ExpireAt:          model.GetMillis() + 60000,
+		})
+
+		movedPost := &model.Post{Id: "moved_post_id", UserId: "author_id", ChannelId: "to_channel_id", Message: "message", IsPinned: true}
+		postList := model.NewPostList()
+		postList.AddPost(movedPost)
+		postList.AddOrder(movedPost.Id)
+		api.On("GetPostThread", "moved_post_id").Return(postList, nil)
+		api.On("GetPost", "moved_post_id").Return(movedPost, nil)
+		api.On("CopyFileInfos", "user_id", mock.Anything).Return([]string{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("channel_id", post.ChannelId)
+			assert.False(post.IsPinned)
+			post.Id = "restored_post_id"
+			return post
+		}, nil)
+		api.On("GetReactions", "moved_post_id").Return([]*model.Reaction{}, nil)
+		api.On("DeletePost", "moved_post_id").Return(nil)
+		api.On("KVCompareAndDelete", "undo_moved_post_id", mock.AnythingOfType("[]uint8")).Return(true, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(err)
+		assert.Equal("The move was undone.", msg)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+	t.Run("undo moving a reply into a thread", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{
+			UserID:            "user_id",
+			OriginalChannelID: "channel_id",
+			OriginalRootID:    "root_id",
+			OriginalMessage:   "reply",
+			MovedPostID:       "moved_post_id",
+			ExpireAt:          model.GetMillis() + 60000,
+		})
+
+		// The thread of the destination channel is left as it is
+		movedPost := &model.Post{Id: "moved_post_id", UserId: "author_id", ChannelId: "to_channel_id", RootId: "to_root_id", ParentId: "to_root_id", Message: "reply"}
+		postList := model.NewPostList()
+		postList.AddPost(&model.Post{Id: "to_root_id", UserId: "author_id", ChannelId: "to_channel_id", Message: "root"})
+		postList.AddPost(movedPost)
+		postList.AddOrder("to_root_id")
+		postList.AddOrder(movedPost.Id)
+		api.On("GetPostThread", "moved_post_id").Return(postList, nil)
+		api.On("GetPost", "moved_post_id").Return(movedPost, nil)
+		api.On("CopyFileInfos", "user_id", mock.Anything).Return([]string{}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal("channel_id", post.ChannelId)
+			assert.Equal("root_id", post.RootId)
+			assert.Equal("root_id", post.ParentId)
+			post.Id = "restored_post_id"
+			return post
+		}, nil)
+		api.On("GetReactions", "moved_post_id").Return([]*model.Reaction{}, nil)
+		api.On("DeletePost", "moved_post_id").Return(nil)
+		api.On("KVCompareAndDelete", "undo_moved_post_id", mock.AnythingOfType("[]uint8")).Return(true, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(err)
+		assert.Equal("The move was undone.", msg)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertNotCalled(t, "DeletePost", "to_root_id")
+	})
+	t.Run("undo moving an oversized message", func(t *testing.T) {
+		assert := assert.New(t)
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		mockUndoRecord(api, undoRecord{
+			UserID:            "user_id",
+			OriginalChannelID: "channel_id",
+			OriginalMessage:   "first half second half",
+			MovedPostID:       "moved_post_id",
+			ExpireAt:          model.GetMillis() + 60000,
+		})
+
+		// The message was split into the moved post and its continuation
+		movedPost := &model.Post{Id: "moved_post_id", UserId: "author_id", ChannelId: "to_channel_id", Message: "first half", CreateAt: 1}
+		continuation := &model.Post{Id: "continuation_id", UserId: "author_id", ChannelId: "to_channel_id", RootId: "moved_post_id", Message: "second half", CreateAt: 2}
+		continuation.AddProp(postPropsKeyContinuationOf, "moved_post_id")
+		reply := &model.Post{Id: "reply_id", UserId: "replier_id", ChannelId: "to_channel_id", RootId: "moved_post_id", Message: "reply", CreateAt: 3}
+		postList := model.NewPostList()
+		for _, post := range []*model.Post{movedPost, continuation, reply} {
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+		api.On("GetPostThread", "moved_post_id").Return(postList, nil)
+		api.On("GetPost", "moved_post_id").Return(movedPost, nil)
+		api.On("GetPost", "reply_id").Return(reply, nil)
+		api.On("CopyFileInfos", "user_id", mock.Anything).Return([]string{}, nil)
+		var messages []string
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			messages = append(messages, post.Message)
+			post.Id = "restored_" + post.Message
+			return post
+		}, nil)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+		api.On("LogDebug", GetMockArgumentsWithType("string", 3)...).Return()
+		api.On("DeletePost", "reply_id").Return(nil)
+		api.On("DeletePost", "continuation_id").Return(nil)
+		api.On("DeletePost", "moved_post_id").Return(nil)
+		api.On("KVCompareAndDelete", "undo_moved_post_id", mock.AnythingOfType("[]uint8")).Return(true, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		msg, err := p.undoMove("user_id", "moved_post_id")
+
+		assert.Nil(err)
+		assert.Equal("The move was undone.", msg)
+		// The continuation isn't restored as a reply, because the restored post has the whole message
+		assert.Equal([]string{"first half second half", "reply"}, messages)
+		api.AssertCalled(t, "DeletePost", "continuation_id")
+		api.AssertNotCalled(t, "GetPost", "continuation_id")
+	})
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const webhookSignatureHeader = "X-Sharepost-Signature"
+
+// webhookTimeout is the timeout of requests to the event webhook. It's a variable so that tests can shorten it.
+var webhookTimeout = 10 * time.Second
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 of the payload with the prefix `sha256=`.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyWebhook sends the payload to the event webhook in the background if the webhook is configured.
+// The user action has already succeeded, so failures are only logged.
+func (p *SharePostPlugin) notifyWebhook(payload []byte) {
+	config := p.getConfiguration()
+	if config.EventWebhookURL == "" {
+		return
+	}
+	go func() {
+		if err := p.sendWebhook(config.EventWebhookURL, config.EventWebhookSecret, payload); err != nil {
+			p.API.LogWarn("failed to send event webhook", "error", err.Error())
+		}
+	}()
+}
+
+// sendWebhook POSTs the JSON payload to the URL, with the signature header if the secret is set.
+func (p *SharePostPlugin) sendWebhook(url, secret string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(secret, payload))
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
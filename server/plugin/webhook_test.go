@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSendWebhook(t *testing.T) {
+	t.Run("signed payload", func(t *testing.T) {
+		assert := assert.New(t)
+		var body []byte
+		var signature, contentType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = ioutil.ReadAll(r.Body)
+			signature = r.Header.Get(webhookSignatureHeader)
+			contentType = r.Header.Get("Content-Type")
+		}))
+		defer server.Close()
+		p := setupTestPlugin(&plugintest.API{})
+
+		err := p.sendWebhook(server.URL, "secret", []byte(`{"action":"share"}`))
+
+		assert.Nil(err)
+		assert.Equal(`{"action":"share"}`, string(body))
+		assert.Equal("application/json", contentType)
+		assert.Equal(signWebhookPayload("secret", body), signature)
+	})
+	t.Run("unsigned payload without secret", func(t *testing.T) {
+		var signature string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature = r.Header.Get(webhookSignatureHeader)
+		}))
+		defer server.Close()
+		p := setupTestPlugin(&plugintest.API{})
+
+		assert.Nil(t, p.sendWebhook(server.URL, "", []byte(`{}`)))
+		assert.Equal(t, "", signature)
+	})
+	t.Run("error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		p := setupTestPlugin(&plugintest.API{})
+
+		assert.NotNil(t, p.sendWebhook(server.URL, "", []byte(`{}`)))
+	})
+	t.Run("timeout", func(t *testing.T) {
+		defer func(d time.Duration) { webhookTimeout = d }(webhookTimeout)
+		webhookTimeout = 10 * time.Millisecond
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+		p := setupTestPlugin(&plugintest.API{})
+
+		assert.NotNil(t, p.sendWebhook(server.URL, "", []byte(`{}`)))
+	})
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13", signWebhookPayload("secret", []byte(`{}`)))
+}
+
+func TestRecordAuditWebhook(t *testing.T) {
+	t.Run("send audit entry", func(t *testing.T) {
+		assert := assert.New(t)
+		received := make(chan auditEntry, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var entry auditEntry
+			assert.Nil(json.NewDecoder(r.Body).Decode(&entry))
+			received <- entry
+		}))
+		defer server.Close()
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EventWebhookURL: server.URL})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+
+		entry := auditEntry{
+			Action:               auditActionShare,
+			UserID:               "user_id",
+			PostID:               "post_id",
+			NewPostID:            "new_post_id",
+			SourceChannelID:      "channel_id",
+			DestinationChannelID: "to_channel_id",
+			Timestamp:            1234567890123,
+		}
+		p.recordAudit(&entry)
+
+		select {
+		case got := <-received:
+			assert.Equal(entry, got)
+		case <-time.After(5 * time.Second):
+			t.Error("webhook is not sent")
+		}
+	})
+	t.Run("failure is only logged", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+		p := setupTestPlugin(api)
+		p.setConfiguration(&configuration{EventWebhookURL: server.URL})
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+		mockAuditIndex(api)
+		api.On("PublishWebSocketEvent", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return()
+		logged := make(chan struct{})
+		api.On("LogWarn", GetMockArgumentsWithType("string", 3)...).Return().Run(func(mock.Arguments) {
+			close(logged)
+		})
+
+		p.recordAudit(&auditEntry{Action: auditActionMove, PostID: "post_id"})
+
+		select {
+		case <-logged:
+		case <-time.After(5 * time.Second):
+			t.Error("failure is not logged")
+		}
+	})
+}
@@ -1,5 +1,6 @@
 import {getConfig} from 'mattermost-redux/selectors/entities/general';
 import {getCurrentChannel} from 'mattermost-redux/selectors/entities/channels';
+import {getCurrentTeamId} from 'mattermost-redux/selectors/entities/teams';
 import {isOpenChannel} from 'mattermost-redux/utils/channel_utils';
 
 import {id as pluginId} from './manifest';
@@ -7,9 +8,23 @@ import {id as pluginId} from './manifest';
 export default class Plugin {
     // eslint-disable-next-line no-unused-vars
     initialize(registry, store) {
+        this.settings = defaultSettings;
+        fetchSettings(store.getState()).then((settings) => {
+            this.settings = settings;
+        });
+
         registry.registerPostDropdownMenuAction(
             'Share post',
-            (postId) => {
+            async (postId) => {
+                // The settings can be changed after initializing the plugin, so they're fetched again
+                // with the overrides of the team of the post
+                this.settings = await fetchSettings(store.getState(), postId);
+                // Only the share types the user can perform on the post are offered. Those enabled by the settings
+                // are offered if they can't be fetched, because the server refuses the others anyway.
+                let shareTypeOptions = await fetchShareTypes(store.getState(), postId);
+                if (shareTypeOptions.length === 0) {
+                    shareTypeOptions = settingsShareTypeOptions(this.settings);
+                }
                 const extraElements = [];
                 if (!isOpenChannel(getCurrentChannel(store.getState()))) {
                     extraElements.push({
@@ -23,6 +38,8 @@ export default class Plugin {
                     url: getPluginServerRoute(store.getState()) + '/api/v1/share',
                     dialog: {
                         callback_id: postId,
+                        // Dialog submissions don't have the X-Requested-With header, so the token is sent as the state
+                        state: this.settings.csrf_token,
                         title: 'Share post',
                         elements: [{
                             display_name: 'Share to...',
@@ -30,35 +47,207 @@ export default class Plugin {
                             type: 'select',
                             data_source: 'channels',
                             placeholder: 'Find a channel to share',
+                            // The server asks for a channel unless sharing to the default channel or sending the permalink
+                            optional: true,
                         }, ...extraElements,
                         {
                             display_name: 'Share type',
                             help_text: 'NOTE: "Move" has the risk to disable integration features for this post\nNOTE: "Move" can take a very long time if a thread has a large number of posts.',
                             name: 'share_type',
                             type: 'radio',
-                            default: 'share',
+                            default: shareTypeOptions[0].value,
+                            options: shareTypeOptions,
+                        }, {
+                            display_name: 'Render mode',
+                            help_text: 'How the shared post is rendered. Only for "Share".',
+                            name: 'render_mode',
+                            type: 'radio',
+                            optional: true,
+                            default: 'plain',
                             options: [{
-                                text: 'Share',
-                                value: 'share',
+                                text: 'Plain',
+                                value: 'plain',
+                            }, {
+                                text: 'Quote',
+                                value: 'quote',
                             }, {
-                                text: 'Move',
-                                value: 'move',
+                                text: 'Card',
+                                value: 'card',
                             }],
+                        }, {
+                            display_name: 'Share thread',
+                            name: 'share_thread',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Include the root post of the thread when sharing a reply.',
+                        }, {
+                            display_name: 'Include files',
+                            name: 'include_files',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Attach the files of the original post to the shared post.',
+                        }, {
+                            display_name: 'Include reactions',
+                            help_text: 'Only for "Share".',
+                            name: 'include_reactions',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Append a summary of the reactions on the original post, like ":+1: 5 :heart: 3".',
+                        }, {
+                            display_name: 'Delete original post',
+                            name: 'delete_source',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Delete the original post after sharing it.',
+                        }, {
+                            display_name: 'Move thread',
+                            name: 'move_thread',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Move all posts in the thread when moving.',
+                        }, {
+                            display_name: 'Detach replies',
+                            name: 'detach_replies',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Move only the root post and leave its replies under a note linking to it.',
+                        }, {
+                            display_name: 'Post as bot',
+                            name: 'post_as_bot',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Create the moved posts by the bot instead of the original author.',
+                        }, {
+                            display_name: 'Pin in destination',
+                            name: 'pin_in_destination',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Pin the moved post in the selected channel.',
+                        }, {
+                            display_name: 'Confirm move',
+                            name: 'confirm_move',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Moving deletes the original post.',
+                        }, {
+                            display_name: 'Don\'t ask again',
+                            name: 'skip_move_confirmation',
+                            type: 'bool',
+                            optional: true,
+                            // Checked while the preference is saved, so that unchecking it turns the confirmation back on
+                            default: String(Boolean(this.settings.skip_move_confirmation)),
+                            placeholder: 'Move posts without the confirmation from now on. Uncheck to be asked again.',
                         }, {
                             display_name: 'Additional Text',
                             name: 'additional_text',
                             type: 'textarea',
                             optional: true,
                             placeholder: 'Write an additional text (optional)',
+                        }, {
+                            display_name: 'Notify everyone',
+                            name: 'confirm_broadcast_mention',
+                            type: 'bool',
+                            optional: true,
+                            placeholder: 'Post @all, @channel or @here in the additional text.',
+                        }, {
+                            display_name: 'Additional text position',
+                            help_text: 'Where the additional text is placed. Only for "Share" and "Copy".',
+                            name: 'additional_text_position',
+                            type: 'radio',
+                            optional: true,
+                            default: 'above',
+                            options: [{
+                                text: 'Above the shared content',
+                                value: 'above',
+                            }, {
+                                text: 'Below the shared content',
+                                value: 'below',
+                            }],
+                        }, {
+                            display_name: 'Reply to thread',
+                            name: 'to_root_id',
+                            type: 'text',
+                            optional: true,
+                            placeholder: 'Permalink or ID of a post in the selected channel (optional)',
                         }],
                         submit_label: 'Share',
                     },
                 });
-            }
+            },
+            () => this.settings.enable_share || this.settings.enable_move,
         );
     }
 }
 
+const defaultSettings = {enable_share: true, enable_move: true};
+
+// fetchSettings gets the settings of the plugin for building the dialog.
+// With the post ID, the overrides of the team of the post are applied by the server.
+// All features are offered if the settings can't be fetched, because the server refuses disabled ones.
+const fetchSettings = async (state, postId) => {
+    try {
+        const query = postId ? '?' + new URLSearchParams({post_id: postId, team_id: getCurrentTeamId(state)}) : '';
+        const response = await fetch(getPluginServerRoute(state) + '/api/v1/settings' + query, {
+            credentials: 'same-origin',
+            headers: {'X-Requested-With': 'XMLHttpRequest'},
+        });
+        if (response.ok) {
+            return await response.json();
+        }
+    } catch (e) {
+        // fall through to the default settings
+    }
+    return defaultSettings;
+};
+
+// settingsShareTypeOptions returns the share types enabled by the settings of the plugin
+const settingsShareTypeOptions = (settings) => {
+    const options = [];
+    if (settings.enable_share) {
+        options.push({
+            text: 'Share',
+            value: 'share',
+        });
+    }
+    options.push({
+        text: 'Copy',
+        value: 'copy',
+    }, {
+        text: 'Duplicate',
+        value: 'duplicate',
+    });
+    if (settings.enable_move) {
+        options.push({
+            text: 'Move',
+            value: 'move',
+        });
+    }
+    options.push({
+        text: 'Permalink to me',
+        value: 'permalink',
+    });
+    return options;
+};
+
+// fetchShareTypes gets the share types the user can perform on the post as options of the dialog element.
+// An empty list is returned if they can't be fetched.
+const fetchShareTypes = async (state, postId) => {
+    try {
+        const query = new URLSearchParams({post_id: postId, team_id: getCurrentTeamId(state)});
+        const response = await fetch(getPluginServerRoute(state) + '/api/v1/share_types?' + query, {
+            credentials: 'same-origin',
+            headers: {'X-Requested-With': 'XMLHttpRequest'},
+        });
+        if (response.ok) {
+            const body = await response.json();
+            return body.items || [];
+        }
+    } catch (e) {
+        // fall through to the empty list
+    }
+    return [];
+};
+
 const getPluginServerRoute = (state) => {
     const config = getConfig(state);
 
@@ -6,7 +6,7 @@ const manifest = JSON.parse(`
     "name": "Share Post Plugin",
     "description": "This plugin shares/moves the post to other channels",
     "version": "0.1.0",
-    "min_server_version": "5.18.0",
+    "min_server_version": "5.20.0",
     "server": {
         "executables": {
             "linux-amd64": "server/dist/plugin-linux-amd64",
@@ -21,7 +21,277 @@ const manifest = JSON.parse(`
     "settings_schema": {
         "header": "",
         "footer": "",
-        "settings": []
+        "settings": [
+            {
+                "key": "EnableShare",
+                "display_name": "Enable sharing posts",
+                "type": "bool",
+                "help_text": "When false, users can't share posts. The post menu item is not shown when both sharing and moving are disabled.",
+                "placeholder": "",
+                "default": true
+            },
+            {
+                "key": "EnableMove",
+                "display_name": "Enable moving posts",
+                "type": "bool",
+                "help_text": "When false, users can't move posts. The post menu item is not shown when both sharing and moving are disabled.",
+                "placeholder": "",
+                "default": true
+            },
+            {
+                "key": "EnableRedirectNote",
+                "display_name": "Leave a note after moving posts",
+                "type": "bool",
+                "help_text": "When true, the plugin bot posts a note with the link to the moved post in the original channel.",
+                "placeholder": "",
+                "default": true
+            },
+            {
+                "key": "EnableMoveNotification",
+                "display_name": "Notify authors of moved posts",
+                "type": "bool",
+                "help_text": "When true, the plugin bot sends a direct message to the author of the post moved by other users.",
+                "placeholder": "",
+                "default": true
+            },
+            {
+                "key": "EnableMentionsOnMove",
+                "display_name": "Notify mentions in moved posts",
+                "type": "bool",
+                "help_text": "When true, mentions in moved posts notify users again. When false, mentions of users, channel-wide mentions (@here, @channel, @all) and group mentions are not highlighted and don't notify, because moved posts are old content.",
+                "placeholder": "",
+                "default": false
+            },
+            {
+                "key": "EnforceRetentionOnMove",
+                "display_name": "Enforce data retention on moves",
+                "type": "bool",
+                "help_text": "When true, posts older than the message retention period of the server's data retention policy can't be moved, because they are pending deletion. Moved posts always keep the creation time of the original posts, so moving doesn't reset their age.",
+                "placeholder": "",
+                "default": false
+            },
+            {
+                "key": "ShareMessageTemplate",
+                "display_name": "Share message template",
+                "type": "longtext",
+                "help_text": "Go text/template used for the message of shared posts. Available variables are {{.Permalink}}, {{.AdditionalText}}, {{.Author}}, {{.Channel}}, {{.Message}} and {{.PermalinkLabel}}. Additional text is only included where {{.AdditionalText}} is used. Leave empty to use the default format.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "PermalinkLabel",
+                "display_name": "Permalink label",
+                "type": "dropdown",
+                "help_text": "Label of the links to original posts in shared posts. \"Channel and message\" shows the channel and the beginning of the message, which is useful when the shared post is read out of context.",
+                "placeholder": "",
+                "default": "original",
+                "options": [
+                    {
+                        "display_name": "original post",
+                        "value": "original"
+                    },
+                    {
+                        "display_name": "Shared post",
+                        "value": "shared_post"
+                    },
+                    {
+                        "display_name": "Channel and message",
+                        "value": "channel_message"
+                    }
+                ]
+            },
+            {
+                "key": "SharedPostFooter",
+                "display_name": "Shared post footer",
+                "type": "text",
+                "help_text": "Text appended to every shared post after a horizontal rule. Leave empty to add no footer.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "MovedPostFooter",
+                "display_name": "Moved post footer",
+                "type": "text",
+                "help_text": "Text appended to every moved post after a horizontal rule. Leave empty to add no footer.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "MaxAdditionalTextLength",
+                "display_name": "Maximum length of additional text",
+                "type": "number",
+                "help_text": "Maximum number of characters of the additional text for shared/moved posts. Set 0 to allow up to 4000 characters, which is the limit on all servers.",
+                "placeholder": "",
+                "default": 1000
+            },
+            {
+                "key": "RequireAdditionalText",
+                "display_name": "Require additional text",
+                "type": "bool",
+                "help_text": "When true, sharing, copying and duplicating posts require the additional text as a note explaining why the post is shared. Moving posts doesn't require it.",
+                "placeholder": "",
+                "default": false
+            },
+            {
+                "key": "MaxPostSize",
+                "display_name": "Maximum post size",
+                "type": "number",
+                "help_text": "Maximum number of characters of a post on the server. Moved messages longer than this are split, and the rest is posted as replies. Set 0 to use 16383, or set 4000 if the database of the server has not been migrated to allow longer posts.",
+                "placeholder": "",
+                "default": 0
+            },
+            {
+                "key": "BroadcastMentionPolicy",
+                "display_name": "Mentions of everyone in additional text",
+                "type": "dropdown",
+                "help_text": "How @all, @channel and @here in the additional text are handled. \"Ask for confirmation\" refuses the text until the user confirms notifying everyone in the destination channel.",
+                "placeholder": "",
+                "default": "confirm",
+                "options": [
+                    {
+                        "display_name": "Ask for confirmation",
+                        "value": "confirm"
+                    },
+                    {
+                        "display_name": "Remove the mentions",
+                        "value": "strip"
+                    },
+                    {
+                        "display_name": "Allow",
+                        "value": "allow"
+                    }
+                ]
+            },
+            {
+                "key": "UndoMoveWindowMinutes",
+                "display_name": "Undo window for moving posts (minutes)",
+                "type": "number",
+                "help_text": "Minutes during which the user who moved a post can undo the move. Set 0 to disable undoing.",
+                "placeholder": "",
+                "default": 5
+            },
+            {
+                "key": "MoveCooldownMinutes",
+                "display_name": "Cooldown before moving a moved post again (minutes)",
+                "type": "number",
+                "help_text": "Minutes after a post is moved during which it cannot be moved again, to prevent accidental rapid relocations. Set 0 to disable.",
+                "placeholder": "",
+                "default": 0
+            },
+            {
+                "key": "MaxThreadMoveSize",
+                "display_name": "Maximum thread size to move",
+                "type": "number",
+                "help_text": "Maximum number of posts in a thread which can be moved at once. Set 0 to disable the limit.",
+                "placeholder": "",
+                "default": 100
+            },
+            {
+                "key": "OperationTimeoutSeconds",
+                "display_name": "Timeout of sharing/moving (seconds)",
+                "type": "number",
+                "help_text": "Sharing or moving a post is aborted with a message when it takes longer than this. Moving a thread is rolled back when aborted. Set 0 to disable the timeout.",
+                "placeholder": "",
+                "default": 30
+            },
+            {
+                "key": "ShareRateLimitPerMinute",
+                "display_name": "Rate limit of sharing (per minute)",
+                "type": "number",
+                "help_text": "Maximum number of posts a user can share, move, copy or duplicate in a minute. Each post of a bulk move is counted. Set 0 to disable the rate limit.",
+                "placeholder": "",
+                "default": 10
+            },
+            {
+                "key": "ShareDedupWindowSeconds",
+                "display_name": "Duplicate share window (seconds)",
+                "type": "number",
+                "help_text": "Sharing, copying or duplicating the same post to the same channel again within this period is refused, to prevent duplicate posts by double-clicking or re-running a share. Set 0 to allow duplicate shares.",
+                "placeholder": "",
+                "default": 30
+            },
+            {
+                "key": "RestrictShareToSameTeam",
+                "display_name": "Restrict destinations to the same team",
+                "type": "bool",
+                "help_text": "When true, posts can be shared/moved only to channels in the same team. Sharing to direct messages is also disallowed.",
+                "placeholder": "",
+                "default": false
+            },
+            {
+                "key": "AllowedShareDestinations",
+                "display_name": "Allowed destinations",
+                "type": "text",
+                "help_text": "Comma-separated IDs of channels or teams where posts can be shared/moved. Leave empty to allow all channels.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "DeniedShareDestinations",
+                "display_name": "Denied destinations",
+                "type": "text",
+                "help_text": "Comma-separated IDs of channels or teams where posts can't be shared/moved.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "TeamOverrides",
+                "display_name": "Per-team overrides",
+                "type": "longtext",
+                "help_text": "JSON object keyed by team ID, overriding EnableShare, EnableMove, AllowedShareDestinations and DeniedShareDestinations for the team, e.g. {\"\u003cteam id\u003e\": {\"EnableMove\": false}}. Omitted settings follow the settings above.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "DefaultShareChannel",
+                "display_name": "Default destination channel",
+                "type": "text",
+                "help_text": "ID of the channel posts are shared to when no channel is selected in the dialog (e.g. a highlights channel). Leave empty to require selecting a channel.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "CarriedOverPropKeys",
+                "display_name": "Props carried over to copies",
+                "type": "text",
+                "help_text": "Comma-separated list of custom post prop keys kept on copied and duplicated posts (e.g. props set by other integrations). Other custom props are dropped.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "AutoJoinPublicChannels",
+                "display_name": "Join public channels when sharing",
+                "type": "bool",
+                "help_text": "When true, users sharing posts to a public channel they're not a member of are added to the channel if they're allowed to join it. Private channels still require being a member.",
+                "placeholder": "",
+                "default": false
+            },
+            {
+                "key": "AutoJoinOpenTeams",
+                "display_name": "Join open teams when sharing",
+                "type": "bool",
+                "help_text": "When true together with \"Join public channels when sharing\", users sharing posts to a public channel of an open team they're not in are added to the team first if they're allowed to join open teams. Invite-only teams still require being a member.",
+                "placeholder": "",
+                "default": false
+            },
+            {
+                "key": "EventWebhookURL",
+                "display_name": "Event webhook URL",
+                "type": "text",
+                "help_text": "URL to notify with a JSON payload when a post is shared/copied/moved. Leave empty to disable.",
+                "placeholder": "",
+                "default": ""
+            },
+            {
+                "key": "EventWebhookSecret",
+                "display_name": "Event webhook secret",
+                "type": "generated",
+                "help_text": "Shared secret to sign the webhook payload. The HMAC-SHA256 signature of the payload is sent in the X-Sharepost-Signature header. The payload is sent unsigned until the secret is generated.",
+                "regenerate_help_text": "Generates a new secret. Webhook receivers must be updated with it.",
+                "placeholder": "",
+                "default": ""
+            }
+        ]
     }
 }
 `);